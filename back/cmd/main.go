@@ -9,6 +9,7 @@ import (
 	"back/config"
 	_ "back/docs"
 	"log"
+	"os"
 
 	"github.com/joho/godotenv"
 )
@@ -19,6 +20,22 @@ func main() {
 		log.Println("Warning: .env file not found, using system environment variables")
 	}
 
+	// 命令行子命令：back staff create-admin ...
+	if len(os.Args) > 1 && os.Args[1] == "staff" {
+		if err := config.RunStaffCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// back audit verify|keygen ...
+	if len(os.Args) > 1 && os.Args[1] == "audit" {
+		if err := config.RunAuditCommand(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := config.Init(); err != nil {
 		log.Fatal("Failed to start server:", err)
 	}
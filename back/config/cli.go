@@ -0,0 +1,209 @@
+package config
+
+import (
+	"back/internal/audit"
+	"back/internal/staff"
+	"back/pkg/crypto"
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// RunStaffCommand 员工账号命令行，用于初始化第一个管理员和启用/停用员工
+//
+//	back staff create-admin -username ops [-password xxx] [-role admin]
+//	back staff disable -username ops
+//	back staff enable -username ops
+//
+// 未指定 -password 时依次读取 STAFF_PASSWORD 环境变量和标准输入；停用后该员工已签发的 token 立即失效
+func RunStaffCommand(args []string) error {
+	usage := fmt.Errorf("用法: staff create-admin -username <name> [-password <pwd>] [-role admin|operator|auditor] | staff disable|enable -username <name>")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "create-admin":
+	case "disable", "enable":
+		return runStaffStatus(args[0], args[1:])
+	default:
+		return usage
+	}
+
+	fs := flag.NewFlagSet("create-admin", flag.ContinueOnError)
+	username := fs.String("username", "", "员工用户名")
+	password := fs.String("password", "", "初始密码（不建议在命令行传入）")
+	role := fs.String("role", staff.RoleAdmin, "员工角色")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("-username 不能为空")
+	}
+
+	plain := *password
+	if plain == "" {
+		plain = os.Getenv("STAFF_PASSWORD")
+	}
+	if plain == "" {
+		fmt.Print("请输入密码: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("读取密码失败: %w", err)
+		}
+		plain = strings.TrimSpace(line)
+	}
+
+	if err := InitDatabase(); err != nil {
+		return err
+	}
+	cryptoService, err := crypto.NewCrypto(getEnv("CRYPTO_KEY", "1234567890123456"), getEnv("HASH_PEPPER", ""))
+	if err != nil {
+		return fmt.Errorf("初始化加密服务失败: %w", err)
+	}
+
+	service := staff.NewService(staff.NewRepository(DB), cryptoService)
+	st, err := service.Create(context.Background(), *username, plain, *role)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("员工账号已创建: username=%s role=%s id=%s\n", st.Username, st.Role, st.ID)
+	return nil
+}
+
+// runStaffStatus 启用/停用员工账号
+func runStaffStatus(action string, args []string) error {
+	fs := flag.NewFlagSet(action, flag.ContinueOnError)
+	username := fs.String("username", "", "员工用户名")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return fmt.Errorf("-username 不能为空")
+	}
+	status := "active"
+	if action == "disable" {
+		status = "disabled"
+	}
+
+	if err := InitDatabase(); err != nil {
+		return err
+	}
+	cryptoService, err := crypto.NewCrypto(getEnv("CRYPTO_KEY", "1234567890123456"), getEnv("HASH_PEPPER", ""))
+	if err != nil {
+		return fmt.Errorf("初始化加密服务失败: %w", err)
+	}
+
+	service := staff.NewService(staff.NewRepository(DB), cryptoService)
+	st, err := service.SetStatus(context.Background(), *username, status)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("员工账号状态已更新: username=%s status=%s\n", st.Username, st.Status)
+	return nil
+}
+
+// RunAuditCommand 审计日志命令行
+//
+//	back audit verify [-from 2025-01-01] [-to 2025-02-01]
+//	back audit keygen
+//	back audit partition
+//
+// verify 校验时间范围内的哈希链，输出 JSON 结果，链断裂时返回错误（退出码非 0）；
+// keygen 生成检查点签名密钥对；
+// partition 把 audit_logs 转换为分区表，转换期间整表锁定，应在维护窗口执行
+func RunAuditCommand(args []string) error {
+	usage := fmt.Errorf("用法: audit verify [-from <time>] [-to <time>] | audit keygen | audit partition")
+	if len(args) == 0 {
+		return usage
+	}
+	switch args[0] {
+	case "partition":
+		return runAuditPartition()
+	case "keygen":
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("AUDIT_CHECKPOINT_KEY=%s\n", base64.StdEncoding.EncodeToString(priv.Seed()))
+		fmt.Printf("# 公钥（可交给第三方独立校验检查点）: %s\n", base64.StdEncoding.EncodeToString(pub))
+		fmt.Printf("# 公钥指纹: %s\n", audit.KeyID(pub))
+		return nil
+	case "verify":
+	default:
+		return usage
+	}
+
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	fromFlag := fs.String("from", "", "时间起（RFC3339 或 YYYY-MM-DD）")
+	toFlag := fs.String("to", "", "时间止（不含）")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+	var from, to *time.Time
+	for name, v := range map[string]string{"from": *fromFlag, "to": *toFlag} {
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			t, err = time.ParseInLocation("2006-01-02", v, time.Local)
+		}
+		if err != nil {
+			return fmt.Errorf("-%s 时间格式无效: %s", name, v)
+		}
+		if name == "from" {
+			from = &t
+		} else {
+			to = &t
+		}
+	}
+
+	cfg := audit.Config{}
+	if err := loadCheckpointKeys(&cfg); err != nil {
+		return err
+	}
+	if err := InitDatabase(); err != nil {
+		return err
+	}
+	// 只用于校验，不启动写入协程
+	service := audit.NewService(audit.NewRepository(DB), cfg)
+	result, err := service.Verify(context.Background(), from, to)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(result); err != nil {
+		return err
+	}
+	if !result.OK {
+		b := result.BrokenLink
+		return fmt.Errorf("审计日志哈希链断裂: seq=%d reason=%s", b.Seq, b.Reason)
+	}
+	return nil
+}
+
+// runAuditPartition 把 audit_logs 转换为分区表并创建月分区，已是分区表时只补建分区
+func runAuditPartition() error {
+	cfg, err := loadAuditPartitionConfig()
+	if err != nil {
+		return err
+	}
+	if err := InitDatabase(); err != nil {
+		return err
+	}
+	if err := audit.NewPartitionManager(DB, cfg).Convert(context.Background()); err != nil {
+		return err
+	}
+	fmt.Println("audit_logs 已是分区表")
+	return nil
+}
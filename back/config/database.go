@@ -1,9 +1,17 @@
 package config
 
 import (
+	"back/internal/apikey"
 	"back/internal/audit"
+	"back/internal/auth"
+	"back/internal/inquiry"
+	"back/internal/notify"
+	"back/internal/staff"
+	"back/internal/stream"
 	"back/internal/supplier"
+	"back/internal/tracker"
 	"back/internal/user"
+	"back/internal/webhook"
 	"fmt"
 
 	"gorm.io/driver/postgres"
@@ -47,8 +55,28 @@ func InitDatabase() error {
 	// 自动迁移数据库表
 	if err := DB.AutoMigrate(
 		&user.User{},
+		&user.WechatIdentity{},
 		&supplier.SupplierProfile{},
+		&supplier.SupplierOrg{},
+		&supplier.SupplierMember{},
+		&supplier.SupplierInvite{},
+		&auth.QRTicket{},
+		&staff.Staff{},
+		&apikey.APIKey{},
+		&apikey.Nonce{},
+		&webhook.Event{},
+		&tracker.QuoteSnapshot{},
+		&tracker.QuoteEvent{},
+		&notify.Preference{},
+		&notify.TemplateSubscription{},
+		&notify.Delivery{},
+		&inquiry.Mark{},
+		&stream.Event{},
+		&stream.Connection{},
 		&audit.AuditLog{},
+		&audit.Checkpoint{},
+		&audit.ChainLink{},
+		&audit.Archive{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
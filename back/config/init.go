@@ -26,6 +26,9 @@ func Init() error {
 		Addr:    ":8080",
 		Handler: router,
 	}
+	for _, hook := range shutdownHooks {
+		srv.RegisterOnShutdown(hook)
+	}
 
 	go func() {
 		fmt.Println("Server is running on http://localhost:8080")
@@ -1,29 +1,60 @@
 package config
 
 import (
+	"back/internal/apikey"
 	"back/internal/audit"
+	"back/internal/audit/auditapi"
 	"back/internal/auth"
+	"back/internal/inquiry"
+	"back/internal/notify"
 	"back/internal/proxy"
+	"back/internal/reminder"
+	"back/internal/staff"
+	"back/internal/stream"
 	"back/internal/supplier"
+	"back/internal/tracker"
 	"back/internal/user"
+	"back/internal/webhook"
 	"back/pkg/crypto"
 	"back/pkg/internal_token"
 	"back/pkg/jwt"
 	"back/pkg/middleware"
+	"back/pkg/sms"
+	"back/pkg/wechat"
+	"context"
+	"fmt"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	ginSwagger "github.com/swaggo/gin-swagger"
 	swaggerFiles "github.com/swaggo/files"
+	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
+// shutdownHooks HTTP 服务开始优雅关闭时执行，用于先断开 SSE 等长连接，否则 Shutdown 会一直等到超时
+var shutdownHooks []func()
+
 // SetupRouter 设置路由，返回 router 和清理函数
 func SetupRouter() (*gin.Engine, func()) {
 	// 创建 Gin 实例
 	router := gin.New()
+	// 默认只信任本机反向代理传来的 X-Forwarded-For，防止同网段主机伪造来源IP绕过白名单；
+	// 反向代理在其他容器或主机上时须在 TRUSTED_PROXIES 中显式列出其地址
+	trustedProxies, err := middleware.ParseCIDRs(getEnv("TRUSTED_PROXIES", "127.0.0.0/8,::1"))
+	if err != nil {
+		log.Fatal("TRUSTED_PROXIES 配置错误: ", err)
+	}
+	proxyCIDRs := make([]string, 0, len(trustedProxies))
+	for _, n := range trustedProxies {
+		proxyCIDRs = append(proxyCIDRs, n.String())
+	}
+	if err := router.SetTrustedProxies(proxyCIDRs); err != nil {
+		log.Fatal("设置受信代理失败: ", err)
+	}
 	router.Use(gin.Recovery())
 	router.Use(middleware.RequestContextMiddleware()) // global request context
 
@@ -53,12 +84,49 @@ func SetupRouter() (*gin.Engine, func()) {
 
 	// 初始化审计服务
 	auditRepo := audit.NewRepository(DB)
-	auditService := audit.NewService(auditRepo)
+	auditConfig := audit.Config{
+		BufferSize:         getEnvInt("AUDIT_BUFFER_SIZE", 4096),
+		BatchSize:          getEnvInt("AUDIT_BATCH_SIZE", 200),
+		FlushInterval:      time.Duration(getEnvInt("AUDIT_FLUSH_INTERVAL_MS", 1000)) * time.Millisecond,
+		MaxRetries:         getEnvInt("AUDIT_MAX_RETRIES", 3),
+		JournalPath:        getEnv("AUDIT_JOURNAL_FILE", "./data/audit-journal.ndjson"),
+		JournalMaxBytes:    int64(getEnvInt("AUDIT_JOURNAL_MAX_MB", 512)) << 20,
+		CheckpointInterval: time.Duration(getEnvInt("AUDIT_CHECKPOINT_INTERVAL_MINUTES", 60)) * time.Minute,
+	}
+	if err := loadCheckpointKeys(&auditConfig); err != nil {
+		log.Fatal("加载审计检查点密钥失败: ", err)
+	}
+	if err := loadAuditSinks(&auditConfig); err != nil {
+		log.Fatal("初始化审计输出目标失败: ", err)
+	}
+	partitionConfig, err := loadAuditPartitionConfig()
+	if err != nil {
+		log.Fatal("审计保留策略配置错误: ", err)
+	}
+	auditConfig.Retention = partitionConfig.Retention
+
+	// 审计日志分区与归档：在开始写入之前创建月分区；表的转换由 back audit partition 执行
+	auditPartitions := audit.NewPartitionManager(DB, partitionConfig)
+	if err := auditPartitions.Setup(context.Background()); err != nil {
+		log.Fatal("初始化审计日志分区失败: ", err)
+	}
+	auditPartitions.Start()
+
+	auditService := audit.NewService(auditRepo, auditConfig)
 	auditService.Start()
 
 	// 初始化用户服务（供 auth 使用）
 	userRepo := user.NewRepository(DB)
 	userService := user.NewService(userRepo, cryptoService)
+	userService.RegisterMergeHook(func(tx *gorm.DB, source, target *user.User) error {
+		return audit.ReassignUsername(tx, source.Username, target.Username)
+	})
+	userService.RegisterMergeHook(func(tx *gorm.DB, source, target *user.User) error {
+		return supplier.MergeProfiles(tx, source.ID, target.ID)
+	})
+	userService.RegisterMergeHook(func(tx *gorm.DB, source, target *user.User) error {
+		return supplier.MergeMembers(tx, source.ID, target.ID)
+	})
 
 	// 初始化供应商服务
 	supplierRepo := supplier.NewRepository(DB)
@@ -73,31 +141,231 @@ func SetupRouter() (*gin.Engine, func()) {
 		getEnv("INTERNAL_PASSWORD", ""),
 		time.Duration(internalTokenLifetimeHours)*time.Hour,
 	)
+	// 后台任务（报价跟踪轮询等）调用内部系统的限流，前端触发的代理请求不受限
+	erpBackgroundRPS := getEnvInt("ERP_BACKGROUND_RPS", 2)
+	tokenManager.SetRateLimit(float64(erpBackgroundRPS), erpBackgroundRPS)
 	tokenManager.Start()
 
-	authHandler := auth.NewHandler(jwtService, userService, tokenManager.GetToken, getEnv("INTERNAL_API_URL", ""))
-	supplierHandler := supplier.NewHandler(supplierService, userService)
-	internalProxy := proxy.NewInternalProxy(tokenManager, getEnv("INTERNAL_API_URL", ""), jwtService)
+	// 初始化微信客户端（WECHAT_FAKE=true 时使用进程内假服务，便于本地离线开发；
+	// 假服务仅编译进 -tags wechatfake 的构建，且只能在 debug 模式下启动）
+	wechatAppID := getEnv("WECHAT_APPID", "")
+	wechatSecret := getEnv("WECHAT_SECRET", "")
+	wechatBaseURL := getEnv("WECHAT_API_BASE_URL", wechat.DefaultBaseURL)
+	wechatFake := getEnv("WECHAT_FAKE", "") == "true"
+	var stopFakeWechat, stopFakeWechatOA func()
+	if wechatFake {
+		wechatBaseURL, stopFakeWechat = startWechatFake(wechatAppID, wechatSecret)
+		log.Printf("[WECHAT] 使用假服务: %s", wechatBaseURL)
+	}
+	wechatClient := wechat.NewClient(wechat.Config{
+		BaseURL: wechatBaseURL,
+		AppID:   wechatAppID,
+		Secret:  wechatSecret,
+		Timeout: time.Duration(getEnvInt("WECHAT_TIMEOUT_SECONDS", 5)) * time.Second,
+	})
+
+	// 公众号网页授权客户端
+	oaAppID := getEnv("WECHAT_OA_APPID", "")
+	oaSecret := getEnv("WECHAT_OA_SECRET", "")
+	oaBaseURL := getEnv("WECHAT_API_BASE_URL", wechat.DefaultBaseURL)
+	oaOpenBaseURL := getEnv("WECHAT_OPEN_BASE_URL", wechat.DefaultOpenBaseURL)
+	if wechatFake {
+		oaBaseURL, stopFakeWechatOA = startWechatFake(oaAppID, oaSecret)
+		oaOpenBaseURL = oaBaseURL
+	}
+	oaClient := wechat.NewClient(wechat.Config{
+		BaseURL:     oaBaseURL,
+		OpenBaseURL: oaOpenBaseURL,
+		AppID:       oaAppID,
+		Secret:      oaSecret,
+		Timeout:     time.Duration(getEnvInt("WECHAT_TIMEOUT_SECONDS", 5)) * time.Second,
+	})
+
+	authHandler := auth.NewHandler(jwtService, userService, tokenManager.GetToken, getEnv("INTERNAL_API_URL", ""), wechatClient, auth.OfficialAccountConfig{
+		Client:      oaClient,
+		CallbackURL: getEnv("WECHAT_OA_CALLBACK_URL", ""),
+		FrontURL:    getEnv("FRONT_URL", ""),
+	}, auth.QRLoginConfig{
+		Page:       getEnv("WECHAT_QR_LOGIN_PAGE", "pages/qr-login/index"),
+		EnvVersion: getEnv("WECHAT_QR_LOGIN_ENV", "release"),
+		MaxTickets: getEnvInt("QR_LOGIN_MAX_TICKETS", 10000),
+		Store:      auth.NewQRTicketRepository(DB),
+	})
+	inviteURL := ""
+	if frontURL := getEnv("FRONT_URL", ""); frontURL != "" {
+		inviteURL = strings.TrimRight(frontURL, "/") + "/supplier/invite"
+	}
+	supplierHandler := supplier.NewHandler(supplierService, userService, jwtService, inviteURL)
+	userHandler := user.NewHandler(userService, jwtService, time.Duration(getEnvInt("IMPERSONATION_TOKEN_MINUTES", 15))*time.Minute)
+	accountHandler := user.NewAccountHandler(auditService)
+	auditHandler := auditapi.NewHandler(auditService, auditPartitions)
+	inquiryService := inquiry.NewService(inquiry.NewRepository(DB))
+	inquiryHandler := inquiry.NewHandler(inquiryService, userService, supplierService)
+	// 绑定短信验证：依赖 BC_Customer_GetByCode（待 ERP 团队确认），默认关闭，绑定由内部系统校验手机号
+	bindSMSVerify := getEnv("BIND_SMS_VERIFY", "false") == "true"
+	var bindSMSSender sms.Sender
+	if bindSMSVerify {
+		if gatewayURL := getEnv("SMS_GATEWAY_URL", ""); gatewayURL != "" {
+			sender, err := sms.NewHTTPSender(gatewayURL, getEnv("SMS_GATEWAY_TOKEN", ""), time.Duration(getEnvInt("SMS_GATEWAY_TIMEOUT_SECONDS", 10))*time.Second)
+			if err != nil {
+				log.Fatalf("短信网关配置错误: %v", err)
+			}
+			bindSMSSender = sender
+		} else if gin.Mode() == gin.DebugMode {
+			log.Println("[SMS] 未配置 SMS_GATEWAY_URL，验证码只打印到控制台（仅限 debug 模式）")
+			bindSMSSender = sms.NewConsoleSender()
+		} else {
+			log.Fatal("BIND_SMS_VERIFY=true 时必须配置 SMS_GATEWAY_URL")
+		}
+	}
+	internalProxy := proxy.NewInternalProxy(tokenManager, getEnv("INTERNAL_API_URL", ""), jwtService, userService, supplierService, inquiryService, bindSMSSender)
+
+	// 员工账号（管理后台）
+	staffService := staff.NewService(staff.NewRepository(DB), cryptoService)
+	staffHandler := staff.NewHandler(staffService, jwtService, time.Duration(getEnvInt("STAFF_TOKEN_HOURS", 8))*time.Hour)
+
+	// 机器调用方 API key
+	apiKeyService := apikey.NewService(apikey.NewRepository(DB), cryptoService)
+	apiKeyHandler := apikey.NewHandler(apiKeyService)
+	apiKeyTicker := time.NewTicker(time.Minute)
+	go func() {
+		for range apiKeyTicker.C {
+			apiKeyService.CleanupNonces()
+		}
+	}()
+
+	// ERP 事件推送：落库后异步分发给各消费者（通知等模块通过 Subscribe 接入）
+	webhookService := webhook.NewService(webhook.NewRepository(DB))
+	webhookService.Subscribe("audit", webhook.AuditConsumer(auditService))
+
+	// 报价结果跟踪：定时轮询已绑定供应商的已报价记录，对比快照生成事件；ERP 推送报价/询价单事件时立即重新拉取
+	trackerService := tracker.NewService(tracker.NewRepository(DB), internalProxy, supplierService,
+		time.Duration(getEnvInt("QUOTE_TRACKER_INTERVAL_MINUTES", 10))*time.Minute)
+	trackerHandler := tracker.NewHandler(trackerService, userService, supplierService)
+
+	// 通知：小程序订阅消息（NOTIFY_CHANNEL=log 时写日志/文件，便于本地开发）
+	var notifyChannel notify.Channel = notify.NewWeChatChannel(wechatClient, getEnv("WECHAT_SUBSCRIBE_STATE", "formal"))
+	if getEnv("NOTIFY_CHANNEL", "wechat") == "log" {
+		notifyChannel = notify.NewLogChannel(getEnv("NOTIFY_LOG_FILE", ""))
+	}
+	notifyTemplates, err := notify.LoadTemplates(getEnv("NOTIFY_TEMPLATES_FILE", ""))
+	if err != nil {
+		log.Fatal("NOTIFY_TEMPLATES_FILE 配置错误: ", err)
+	}
+	if len(notifyTemplates) == 0 {
+		log.Println("Warning: NOTIFY_TEMPLATES_FILE 未设置，不发送通知")
+	}
+	notifyService := notify.NewService(notify.NewRepository(DB), notifyChannel, notifyTemplates, userService, supplierService, wechatAppID)
+	notifyHandler := notify.NewHandler(notifyService, userService)
+	trackerService.Subscribe("notify", notify.TrackerListener(notifyService))
+	notifyService.Start()
+
+	// 截止提醒：截止前各时间点提醒尚未提交报价的供应商
+	reminderOffsets, err := reminder.ParseOffsets(getEnv("DEADLINE_REMINDER_OFFSETS", "24h,2h"))
+	if err != nil {
+		log.Fatal("DEADLINE_REMINDER_OFFSETS 配置错误: ", err)
+	}
+	reminderScheduler := reminder.NewScheduler(internalProxy, supplierService, notifyService, inquiryService, reminder.Config{
+		Offsets:  reminderOffsets,
+		Interval: time.Duration(getEnvInt("DEADLINE_REMINDER_INTERVAL_MINUTES", 15)) * time.Minute,
+		AllOpen:  getEnv("DEADLINE_REMINDER_ALL_OPEN", "true") == "true",
+	})
+	reminderScheduler.Start()
+
+	// 实时推送：事件写入 Postgres 事件日志，各实例轮询日志推送给本机的 SSE 连接
+	streamService := stream.NewService(stream.NewRepository(DB), supplierService, internalProxy, stream.Config{
+		PollInterval:    time.Duration(getEnvInt("STREAM_POLL_INTERVAL_MS", 1000)) * time.Millisecond,
+		Retention:       time.Duration(getEnvInt("STREAM_RETENTION_HOURS", 24)) * time.Hour,
+		MaxConnections:  getEnvInt("STREAM_MAX_CONNECTIONS_PER_USER", 5),
+		InquiryInterval: time.Duration(getEnvInt("STREAM_INQUIRY_INTERVAL_MINUTES", 5)) * time.Minute,
+	})
+	streamHandler := stream.NewHandler(streamService, userService, supplierService,
+		time.Duration(getEnvInt("STREAM_HEARTBEAT_SECONDS", 25))*time.Second,
+		time.Duration(getEnvInt("STREAM_MAX_LIFETIME_MINUTES", 60))*time.Minute)
+	trackerService.Subscribe("stream", stream.TrackerListener(streamService))
+	userService.RegisterSessionHook(stream.SessionHook(streamService))
+	streamService.Start()
+	shutdownHooks = append(shutdownHooks, streamService.Stop)
+
+	webhookService.Subscribe("quote-tracker", tracker.WebhookConsumer(trackerService),
+		webhook.EventQuoteAwarded, webhook.EventQuoteRejected, webhook.EventInquiryClosed)
+	webhookService.Subscribe("stream-inquiries", stream.WebhookConsumer(streamService),
+		webhook.EventInquiryPublished, webhook.EventInquiryClosed)
+	webhookService.Subscribe("notify", notify.WebhookConsumer(notifyService), webhook.EventInquiryPublished)
+	trackerService.Start()
+	webhookService.Start()
+	webhookHandler := webhook.NewHandler(webhookService, getEnv("ERP_WEBHOOK_SECRET", ""))
+	webhookAllowlist, err := middleware.ParseCIDRs(getEnv("ERP_WEBHOOK_ALLOWED_CIDRS", ""))
+	if err != nil {
+		log.Fatal("ERP_WEBHOOK_ALLOWED_CIDRS 配置错误: ", err)
+	}
 
 	v1 := router.Group("/api/v1")
 
+	qrTicketRateLimit := middleware.RateLimit(getEnvInt("QR_LOGIN_RATE_LIMIT_PER_MINUTE", 10), time.Minute)
+	qrImageRateLimit := middleware.RateLimit(getEnvInt("QR_LOGIN_IMAGE_RATE_LIMIT_PER_MINUTE", 30), time.Minute)
+
 	// ========== PUBLIC：无需 JWT ==========
 	authGroup := v1.Group("/auth")
 	authGroup.Use(middleware.Audit(auditService))
 	{
-		authGroup.POST("/wechat-login", authHandler.WechatLogin) // 微信登录，返回外部JWT
-		authGroup.POST("/refresh", authHandler.RefreshToken)     // 刷新外部JWT
+		authGroup.POST("/wechat-login", authHandler.WechatLogin)                   // 微信登录，返回外部JWT
+		authGroup.POST("/refresh", authHandler.RefreshToken)                       // 刷新外部JWT
+		authGroup.GET("/wechat-oa/authorize", authHandler.WechatOAAuthorize)       // 公众号网页授权入口
+		authGroup.GET("/wechat-oa/callback", authHandler.WechatOACallback)         // 公众号网页授权回调
+		authGroup.POST("/qr/ticket", qrTicketRateLimit, authHandler.QRLoginTicket) // 网页申请扫码登录票据
+		authGroup.GET("/qr/image", qrImageRateLimit, authHandler.QRLoginImage)     // 扫码登录小程序码
+		authGroup.GET("/qr/poll", authHandler.QRLoginPoll)                         // 网页长轮询扫码结果
 	}
 
 	// ========== EXTERNAL JWT：前端携带外部JWT ==========
 	protected := v1.Group("")
-	protected.Use(middleware.JWTAuth(jwtService))
+	protected.Use(middleware.JWTAuth(jwtService, userService.CheckSession))
 	protected.Use(middleware.Audit(auditService))
+	// 员工代查看 token 只读，只放行下列只读 POST 接口（代理层另按存储过程校验）
+	protected.Use(middleware.ImpersonationReadOnly(
+		"/api/v1/proxy/inquiry-query",
+		"/api/v1/proxy/inquiry-detail",
+		"/api/v1/proxy/inquiry-quoted",
+	))
 	{
+		// 微信数据解密（依赖登录时保存的 session_key）
+		protected.POST("/auth/wechat-phone", authHandler.WechatPhone)
+		protected.POST("/auth/merge-account", authHandler.MergeAccount) // 合并其他入口产生的账号
+		protected.POST("/auth/qr/scan", authHandler.QRLoginScan)        // 小程序扫码
+		protected.POST("/auth/qr/confirm", authHandler.QRLoginConfirm)  // 小程序确认网页登录
+
+		protected.GET("/account/staff-access", accountHandler.StaffAccess) // 客服访问本账号的记录
+		protected.GET("/account/activity", accountHandler.Activity)        // 本账号的操作记录
+
+		// 询价单本地标记（关注 / 已读 / 私人备注）
+		inquiryGroup := protected.Group("/inquiries")
+		{
+			inquiryGroup.GET("/marks", inquiryHandler.ListMarks)
+			inquiryGroup.PUT("/:inquiryId/watch", inquiryHandler.Watch)
+			inquiryGroup.DELETE("/:inquiryId/watch", inquiryHandler.Unwatch)
+			inquiryGroup.PUT("/:inquiryId/read", inquiryHandler.MarkRead)
+			inquiryGroup.DELETE("/:inquiryId/read", inquiryHandler.MarkUnread)
+			inquiryGroup.PUT("/:inquiryId/note", inquiryHandler.SaveNote)
+		}
+
+		// 通知
+		notifyGroup := protected.Group("/notifications")
+		{
+			notifyGroup.GET("/templates", notifyHandler.Templates)                // 可订阅的消息模板
+			notifyGroup.POST("/subscriptions", notifyHandler.RecordSubscriptions) // 上报订阅结果
+			notifyGroup.GET("/preferences", notifyHandler.GetPreferences)
+			notifyGroup.PUT("/preferences", notifyHandler.UpdatePreferences)
+			notifyGroup.GET("/deliveries", notifyHandler.ListDeliveries) // 最近的通知记录
+		}
+
 		// 代理转发到内部系统（后端用内部token，前端无感知）
 		proxyGroup := protected.Group("/proxy")
 		{
-			proxyGroup.POST("/bind-wechat", internalProxy.BindWeChatHandler())
+			if bindSMSVerify {
+				proxyGroup.POST("/bind-wechat/send-code", internalProxy.BindWeChatSendCodeHandler()) // 绑定第一步：发送验证码
+			}
+			proxyGroup.POST("/bind-wechat", internalProxy.BindWeChatHandler()) // 启用短信验证时为第二步：确认验证码
 			proxyGroup.POST("/get-by-wechat", internalProxy.GetByWeChatHandler())
 			proxyGroup.POST("/inquiry-query", internalProxy.InquiryQueryHandler())
 			proxyGroup.POST("/inquiry-detail", internalProxy.InquiryDetailHandler())
@@ -109,19 +377,128 @@ func SetupRouter() (*gin.Engine, func()) {
 		// 供应商管理
 		supplierGroup := protected.Group("/supplier")
 		{
-			supplierGroup.POST("/profile", supplierHandler.CreateOrUpdateProfile)     // 创建/更新供应商档案
-			supplierGroup.GET("/profile", supplierHandler.GetProfile)                 // 获取供应商档案
-			supplierGroup.GET("/full-info", supplierHandler.GetFullInfo)             // 获取供应商完整信息
-			supplierGroup.POST("/capabilities", supplierHandler.UpdateCapabilities)   // 更新机器能力
+			supplierGroup.POST("/profile", supplierHandler.CreateOrUpdateProfile)   // 创建/更新供应商档案
+			supplierGroup.GET("/profile", supplierHandler.GetProfile)               // 获取供应商档案
+			supplierGroup.GET("/full-info", supplierHandler.GetFullInfo)            // 获取供应商完整信息
+			supplierGroup.POST("/capabilities", supplierHandler.UpdateCapabilities) // 更新机器能力
+			supplierGroup.GET("/quote-events", trackerHandler.ListEvents)           // 报价变化事件流
+
+			// 成员与角色
+			supplierGroup.GET("/mine", supplierHandler.ListMySuppliers)             // 我所属的供应商
+			supplierGroup.POST("/switch", supplierHandler.SwitchSupplier)           // 切换当前供应商
+			supplierGroup.GET("/members", supplierHandler.ListMembers)              // 成员列表
+			supplierGroup.PUT("/members/:userId", supplierHandler.UpdateMemberRole) // 调整角色（负责人）
+			supplierGroup.DELETE("/members/:userId", supplierHandler.RemoveMember)  // 移除成员（负责人）
+			supplierGroup.POST("/invites", supplierHandler.CreateInvite)            // 生成邀请链接（负责人）
+			supplierGroup.POST("/invites/accept", supplierHandler.AcceptInvite)     // 接受邀请
+		}
+	}
+
+	// ========== EVENTS：SSE 长连接，EventSource 不能设置请求头，允许用 access_token 参数传 token ==========
+	eventsGroup := v1.Group("/events")
+	eventsGroup.Use(middleware.TokenFromQuery("access_token"))
+	eventsGroup.Use(middleware.JWTAuth(jwtService, userService.CheckSession))
+	eventsGroup.Use(middleware.Audit(auditService))
+	{
+		eventsGroup.GET("/stream", streamHandler.Stream)
+	}
+
+	// 管理接口来源限制：IP 白名单 + 按 IP 限流（员工登录同样适用）
+	// 未配置白名单时不挂载 /staff 与 /admin，确需不限来源须显式设置 ADMIN_ALLOW_ANY_IP=true
+	adminAllowlist, err := middleware.ParseCIDRs(getEnv("ADMIN_ALLOWED_CIDRS", ""))
+	if err != nil {
+		log.Fatal("ADMIN_ALLOWED_CIDRS 配置错误: ", err)
+	}
+	adminAllowAnyIP := getEnv("ADMIN_ALLOW_ANY_IP", "false") == "true"
+	adminEnabled := len(adminAllowlist) > 0 || adminAllowAnyIP
+	switch {
+	case !adminEnabled:
+		log.Println("Warning: ADMIN_ALLOWED_CIDRS 未设置，管理接口（/api/v1/staff、/api/v1/admin）未挂载")
+	case len(adminAllowlist) == 0:
+		log.Println("Warning: ADMIN_ALLOW_ANY_IP=true，管理接口不限制来源IP")
+	}
+	adminRateLimit := middleware.RateLimit(getEnvInt("ADMIN_RATE_LIMIT_PER_MINUTE", 30), time.Minute)
+
+	if adminEnabled {
+		// ========== STAFF：员工登录，token 为 admin audience ==========
+		staffGroup := v1.Group("/staff")
+		staffGroup.Use(middleware.Audit(auditService))
+		staffGroup.Use(middleware.IPAllowlist(adminAllowlist), adminRateLimit)
+		{
+			staffGroup.POST("/login", staffHandler.Login)
+
+			staffAuthed := staffGroup.Group("")
+			staffAuthed.Use(middleware.StaffAuth(jwtService, staffService.CheckSession))
+			{
+				staffAuthed.GET("/me", staffHandler.Me)
+				staffAuthed.POST("/password", staffHandler.ChangePassword)
+				staffAuthed.POST("/totp/setup", staffHandler.TOTPSetup)     // 生成 TOTP 密钥
+				staffAuthed.POST("/totp/enable", staffHandler.TOTPEnable)   // 校验验证码后开启
+				staffAuthed.POST("/totp/disable", staffHandler.TOTPDisable) // 校验验证码后关闭
+			}
 		}
+
+		// ========== ADMIN：管理接口，需员工 token + 对应权限，所有管理接口挂在此分组下 ==========
+		adminGroup := v1.Group("/admin")
+		adminGroup.Use(middleware.Audit(auditService))
+		adminGroup.Use(middleware.IPAllowlist(adminAllowlist), adminRateLimit)
+		adminGroup.Use(middleware.StaffAuth(jwtService, staffService.CheckSession))
+		{
+			adminGroup.POST("/internal/login", staff.RequirePermission(staffService, staff.PermERPLogin), authHandler.InternalLogin)                            // 内部系统登录代理
+			adminGroup.POST("/proxy/refresh-token", staff.RequirePermission(staffService, staff.PermERPTokenRefresh), internalProxy.ForceRefreshTokenHandler()) // 强制刷新内部token
+
+			// 用户管理
+			usersRead := staff.RequirePermission(staffService, staff.PermUsersRead)
+			usersWrite := staff.RequirePermission(staffService, staff.PermUsersWrite)
+			adminGroup.GET("/users", usersRead, userHandler.ListUsers)                 // 用户列表（筛选 + 游标分页）
+			adminGroup.POST("/users", usersWrite, userHandler.CreateUser)              // 创建用户
+			adminGroup.GET("/users/:id", usersRead, userHandler.GetUser)               // 用户详情
+			adminGroup.PUT("/users/:id", usersWrite, userHandler.UpdateUser)           // 更新用户
+			adminGroup.DELETE("/users/:id", usersWrite, userHandler.DeleteUser)        // 删除用户（会话失效）
+			adminGroup.POST("/users/:id/disable", usersWrite, userHandler.DisableUser) // 停用用户（会话失效）
+			adminGroup.POST("/users/:id/enable", usersWrite, userHandler.EnableUser)   // 启用用户
+			adminGroup.POST("/users/:id/logout", usersWrite, userHandler.ForceLogout)  // 强制下线（会话失效）
+			adminGroup.POST("/users/:id/merge", usersWrite, userHandler.MergeUser)     // 合并到 targetId 指定的账号（会话失效）
+
+			usersImpersonate := staff.RequirePermission(staffService, staff.PermUsersImpersonate)
+			adminGroup.POST("/users/:id/impersonate", usersImpersonate, userHandler.Impersonate) // 签发只读代查看token
+
+			// API key 管理
+			apiKeysManage := staff.RequirePermission(staffService, staff.PermAPIKeysManage)
+			adminGroup.GET("/api-keys", apiKeysManage, apiKeyHandler.List)
+			adminGroup.POST("/api-keys", apiKeysManage, apiKeyHandler.Create)
+			adminGroup.DELETE("/api-keys/:id", apiKeysManage, apiKeyHandler.Revoke) // 吊销，立即生效
+
+			// 审计日志
+			auditRead := staff.RequirePermission(staffService, staff.PermAuditRead)
+			adminGroup.GET("/audit-logs", auditRead, auditHandler.List)
+			adminGroup.GET("/audit-logs/export", auditRead, auditHandler.Export)   // CSV / NDJSON 流式导出
+			adminGroup.GET("/audit-logs/metrics", auditRead, auditHandler.Stats)   // 写入管道指标
+			adminGroup.GET("/audit-logs/verify", auditRead, auditHandler.Verify)   // 哈希链校验
+			adminGroup.GET("/audit-logs/storage", auditRead, auditHandler.Storage) // 各分区存储占用
+
+			// 系统公告（实时推送给在线用户）
+			adminGroup.POST("/announcements", staff.RequirePermission(staffService, staff.PermAnnouncements), streamHandler.Announce)
+		}
+	}
+
+	// ========== WEBHOOK：ERP 推送，HMAC 签名 ==========
+	webhookGroup := v1.Group("/webhooks")
+	webhookGroup.Use(middleware.Audit(auditService))
+	webhookGroup.Use(middleware.IPAllowlist(webhookAllowlist))
+	{
+		webhookGroup.POST("/erp", webhookHandler.Receive)
 	}
 
-	// ========== ADMIN：管理接口，不走外部JWT ==========
-	adminGroup := v1.Group("")
-	adminGroup.Use(middleware.Audit(auditService))
+	// ========== SERVICE：机器调用方，API key + HMAC 签名 ==========
+	// 限定了供应商的 key 会写入 rc.CustomerCode，这里的每个接口都必须按它过滤数据（见 apikey.customerScoped）
+	serviceGroup := v1.Group("/service")
+	serviceGroup.Use(middleware.Audit(auditService))
+	serviceGroup.Use(apikey.Auth(apiKeyService))
 	{
-		adminGroup.POST("/internal/login", authHandler.InternalLogin)          // 内部系统登录代理
-		adminGroup.POST("/proxy/refresh-token", internalProxy.ForceRefreshTokenHandler()) // 强制刷新内部token
+		serviceGroup.GET("/suppliers/:customerCode/full-info", apikey.RequireScope(apikey.ScopeSupplierRead), supplierHandler.GetFullInfoByCode)
+		serviceGroup.GET("/audit-logs", apikey.RequireScope(apikey.ScopeAuditRead), auditHandler.List)
+		serviceGroup.GET("/audit-logs/export", apikey.RequireScope(apikey.ScopeAuditRead), auditHandler.Export)
 	}
 
 	// 静态文件服务 - 营业执照图片
@@ -136,12 +513,128 @@ func SetupRouter() (*gin.Engine, func()) {
 	})
 
 	cleanup := func() {
+		apiKeyTicker.Stop()
+		webhookService.Stop()
+		trackerService.Stop()
+		streamService.Stop()
+		reminderScheduler.Stop()
+		notifyService.Stop()
 		auditService.Stop()
+		auditPartitions.Stop()
+		if wechatFake {
+			stopFakeWechat()
+			stopFakeWechatOA()
+		}
 	}
 
 	return router, cleanup
 }
 
+// loadCheckpointKeys 读取审计检查点签名私钥（AUDIT_CHECKPOINT_KEY）和校验旧检查点用的公钥（AUDIT_CHECKPOINT_PUBLIC_KEYS）
+func loadCheckpointKeys(cfg *audit.Config) error {
+	if v := getEnv("AUDIT_CHECKPOINT_KEY", ""); v != "" {
+		key, err := audit.ParseCheckpointKey(v)
+		if err != nil {
+			return err
+		}
+		cfg.CheckpointKey = key
+	} else {
+		log.Println("Warning: AUDIT_CHECKPOINT_KEY 未设置，不签发审计检查点")
+	}
+	keys, err := audit.ParsePublicKeys(getEnv("AUDIT_CHECKPOINT_PUBLIC_KEYS", ""))
+	if err != nil {
+		return err
+	}
+	cfg.CheckpointPublicKeys = keys
+	return nil
+}
+
+// loadAuditPartitionConfig 读取审计日志保留策略和分区配置
+func loadAuditPartitionConfig() (audit.PartitionConfig, error) {
+	retention, err := audit.ParseRetentionPolicy(getEnv("AUDIT_RETENTION_RULES", ""), getEnvInt("AUDIT_RETENTION_DEFAULT_DAYS", 365))
+	if err != nil {
+		return audit.PartitionConfig{}, err
+	}
+	return audit.PartitionConfig{
+		Enabled:     getEnv("AUDIT_PARTITIONING", "true") == "true",
+		MonthsAhead: getEnvInt("AUDIT_PARTITION_MONTHS_AHEAD", 2),
+		ArchiveDir:  getEnv("AUDIT_ARCHIVE_DIR", "./data/audit-archive"),
+		Interval:    time.Duration(getEnvInt("AUDIT_RETENTION_INTERVAL_MINUTES", 60)) * time.Minute,
+		Retention:   retention,
+	}, nil
+}
+
+// loadAuditSinks 按 AUDIT_SINKS（逗号分隔：database,file,syslog,webhook）创建审计输出目标，
+// 各目标用 AUDIT_<NAME>_ACTIONS 按 action 过滤
+func loadAuditSinks(cfg *audit.Config) error {
+	enabled := make(map[string]bool)
+	for _, name := range strings.Split(getEnv("AUDIT_SINKS", "database"), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled[name] = true
+		}
+	}
+
+	cfg.DatabaseDisabled = !enabled["database"]
+	cfg.DatabaseFilter = audit.ParseActionFilter(getEnv("AUDIT_DATABASE_ACTIONS", ""))
+	if cfg.DatabaseDisabled {
+		log.Println("Warning: AUDIT_SINKS 未包含 database，审计记录不写入数据库")
+	}
+	delete(enabled, "database")
+
+	if enabled["file"] {
+		sink, err := audit.NewFileSink(
+			getEnv("AUDIT_FILE_PATH", "./data/audit.jsonl"),
+			int64(getEnvInt("AUDIT_FILE_MAX_MB", 100))<<20,
+			getEnvInt("AUDIT_FILE_MAX_BACKUPS", 10),
+		)
+		if err != nil {
+			return err
+		}
+		cfg.Sinks = append(cfg.Sinks, audit.SinkConfig{
+			Sink:   sink,
+			Filter: audit.ParseActionFilter(getEnv("AUDIT_FILE_ACTIONS", "")),
+		})
+		delete(enabled, "file")
+	}
+	if enabled["syslog"] {
+		sink, err := audit.NewSyslogSink(
+			getEnv("AUDIT_SYSLOG_NETWORK", "udp"),
+			getEnv("AUDIT_SYSLOG_ADDR", ""),
+			getEnv("AUDIT_SYSLOG_APP_NAME", "bounty-server"),
+			getEnvInt("AUDIT_SYSLOG_FACILITY", audit.FacilityLogAudit),
+		)
+		if err != nil {
+			return err
+		}
+		cfg.Sinks = append(cfg.Sinks, audit.SinkConfig{
+			Sink:   sink,
+			Filter: audit.ParseActionFilter(getEnv("AUDIT_SYSLOG_ACTIONS", "")),
+		})
+		delete(enabled, "syslog")
+	}
+	if enabled["webhook"] {
+		sink, err := audit.NewWebhookSink(
+			getEnv("AUDIT_WEBHOOK_URL", ""),
+			getEnv("AUDIT_WEBHOOK_SECRET", ""),
+			time.Duration(getEnvInt("AUDIT_WEBHOOK_TIMEOUT_SECONDS", 10))*time.Second,
+		)
+		if err != nil {
+			return err
+		}
+		cfg.Sinks = append(cfg.Sinks, audit.SinkConfig{
+			Sink:          sink,
+			Filter:        audit.ParseActionFilter(getEnv("AUDIT_WEBHOOK_ACTIONS", "")),
+			BatchSize:     getEnvInt("AUDIT_WEBHOOK_BATCH_SIZE", 100),
+			FlushInterval: time.Duration(getEnvInt("AUDIT_WEBHOOK_FLUSH_INTERVAL_MS", 2000)) * time.Millisecond,
+		})
+		delete(enabled, "webhook")
+	}
+	for name := range enabled {
+		return fmt.Errorf("未知的审计输出目标: %s", name)
+	}
+	return nil
+}
+
 // getEnv 获取环境变量，如果不存在则返回默认值
 func getEnv(key, defaultValue string) string {
 	value := os.Getenv(key)
@@ -0,0 +1,20 @@
+//go:build wechatfake
+
+package config
+
+import (
+	"back/pkg/wechat/wechattest"
+	"log"
+
+	"github.com/gin-gonic/gin"
+)
+
+// startWechatFake 启动进程内微信假服务，返回服务地址和关闭函数
+// 假服务接受任意 code，只编译进 -tags wechatfake 的开发构建，且仅允许在 GIN_MODE=debug 下启动
+func startWechatFake(appID, secret string) (string, func()) {
+	if gin.Mode() != gin.DebugMode {
+		log.Fatal("WECHAT_FAKE=true 仅允许在 GIN_MODE=debug 下使用")
+	}
+	server := wechattest.NewServer(appID, secret)
+	return server.URL, server.Close
+}
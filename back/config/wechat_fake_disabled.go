@@ -0,0 +1,11 @@
+//go:build !wechatfake
+
+package config
+
+import "log"
+
+// startWechatFake 正式构建不包含微信假服务，设置 WECHAT_FAKE=true 时拒绝启动
+func startWechatFake(appID, secret string) (string, func()) {
+	log.Fatal("WECHAT_FAKE=true 需要使用 go build -tags wechatfake 构建的开发版本")
+	return "", nil
+}
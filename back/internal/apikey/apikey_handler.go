@@ -0,0 +1,136 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+
+	"back/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler API key 管理处理器（管理端）
+type Handler struct {
+	service Service
+}
+
+// NewHandler 创建新的处理器实例
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Create 创建 API key
+// @Summary 创建 API key（管理端）
+// @Description secret 仅在本次响应中返回；签名密钥为 SHA-256(secret)；限定供应商（customerCode）时只能分配按供应商过滤数据的权限范围
+// @Tags admin-apikey
+// @Accept json
+// @Produce json
+// @Param request body CreateAPIKeyRequest true "API key 信息"
+// @Success 201 {object} APIKeyResponse
+// @Router /api/v1/admin/api-keys [post]
+func (h *Handler) Create(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rc := middleware.GetRequestContext(c)
+	createdBy := ""
+	if rc != nil {
+		createdBy = rc.StaffUsername
+	}
+
+	key, secret, err := h.service.Create(c.Request.Context(), &req, createdBy)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidScope) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	h.setAuditInfo(c, "admin.apikey_create", key)
+
+	c.JSON(http.StatusCreated, APIKeyResponse{
+		Code:    http.StatusCreated,
+		Message: "API key 已创建，secret 只显示这一次",
+		Data:    key,
+		Secret:  secret,
+	})
+}
+
+// List 列出 API key
+// @Summary API key 列表（管理端）
+// @Tags admin-apikey
+// @Produce json
+// @Success 200 {object} APIKeyListResponse
+// @Router /api/v1/admin/api-keys [get]
+func (h *Handler) List(c *gin.Context) {
+	keys, err := h.service.List(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	h.setAuditInfo(c, "admin.apikey_list", nil)
+
+	c.JSON(http.StatusOK, APIKeyListResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    keys,
+	})
+}
+
+// Revoke 吊销 API key
+// @Summary 吊销 API key（管理端）
+// @Tags admin-apikey
+// @Produce json
+// @Param id path string true "API key ID"
+// @Success 200 {object} APIKeyResponse
+// @Router /api/v1/admin/api-keys/{id} [delete]
+func (h *Handler) Revoke(c *gin.Context) {
+	key, err := h.service.Revoke(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, ErrorResponse{Code: status, Message: err.Error()})
+		return
+	}
+
+	h.setAuditInfo(c, "admin.apikey_revoke", key)
+
+	c.JSON(http.StatusOK, APIKeyResponse{
+		Code:    http.StatusOK,
+		Message: "API key 已吊销",
+		Data:    key,
+	})
+}
+
+// setAuditInfo 设置审计信息
+func (h *Handler) setAuditInfo(c *gin.Context, action string, key *APIKey) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil {
+		return
+	}
+	rc.Action = action
+	rc.Resource = "api_key"
+	if key != nil {
+		rc.ResourceID = key.ID
+		rc.Detail = map[string]any{
+			"key_id":        key.KeyID,
+			"name":          key.Name,
+			"scopes":        key.Scopes,
+			"customer_code": key.CustomerCode,
+		}
+	}
+}
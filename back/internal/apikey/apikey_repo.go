@@ -0,0 +1,80 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Repository API key 数据访问
+type Repository interface {
+	Create(ctx context.Context, key *APIKey) error
+	GetByID(ctx context.Context, id string) (*APIKey, error)
+	GetByKeyID(ctx context.Context, keyID string) (*APIKey, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+	TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error
+	// UseNonce 登记 nonce，同一 key 的 nonce 未过期时返回 false；已过期的记录直接复用
+	UseNonce(ctx context.Context, keyID, nonce string, now, expiresAt time.Time) (bool, error)
+	DeleteExpiredNonces(ctx context.Context, before time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 API key 仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, key *APIKey) error {
+	return r.db.WithContext(ctx).Create(key).Error
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) GetByKeyID(ctx context.Context, keyID string) (*APIKey, error) {
+	var key APIKey
+	if err := r.db.WithContext(ctx).Where("key_id = ?", keyID).First(&key).Error; err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *repository) List(ctx context.Context) ([]APIKey, error) {
+	var keys []APIKey
+	err := r.db.WithContext(ctx).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+func (r *repository) Revoke(ctx context.Context, id string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", at).Error
+}
+
+func (r *repository) TouchLastUsed(ctx context.Context, id string, at time.Time, ip string) error {
+	return r.db.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).
+		Updates(map[string]interface{}{"last_used_at": at, "last_used_ip": ip}).Error
+}
+
+func (r *repository) UseNonce(ctx context.Context, keyID, nonce string, now, expiresAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Exec(
+		"INSERT INTO api_key_nonces (key_id, nonce, expires_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT (key_id, nonce) DO UPDATE SET expires_at = EXCLUDED.expires_at WHERE api_key_nonces.expires_at <= ?",
+		keyID, nonce, expiresAt, now,
+	)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *repository) DeleteExpiredNonces(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at <= ?", before).Delete(&Nonce{}).Error
+}
@@ -0,0 +1,234 @@
+package apikey
+
+import (
+	"back/pkg/crypto"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// signatureWindow 请求时间戳允许的偏差，nonce 保留两个窗口
+	signatureWindow = 5 * time.Minute
+	// maxNonceLength nonce 最大长度（与 api_key_nonces.nonce 字段一致）
+	maxNonceLength = 64
+	// lastUsedInterval 最近使用时间的最小更新间隔，避免每个请求都写库
+	lastUsedInterval = time.Minute
+	defaultKeyTTL    = 365 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidKey       = errors.New("invalid api key")
+	ErrKeyExpired       = errors.New("api key expired or revoked")
+	ErrBadTimestamp     = errors.New("timestamp outside allowed window")
+	ErrReplay           = errors.New("nonce already used")
+	ErrInvalidNonce     = errors.New("nonce too long")
+	ErrBadSignature     = errors.New("signature mismatch")
+	ErrInvalidScope     = errors.New("invalid scope")
+	ErrNotFound         = errors.New("api key not found")
+	ErrMissingSignature = errors.New("missing signature headers")
+)
+
+// SignedRequest 待校验的签名请求
+type SignedRequest struct {
+	KeyID     string
+	Timestamp string // Unix 秒
+	Nonce     string
+	Signature string // hex(HMAC-SHA256(signingKey, StringToSign))
+	Method    string
+	Path      string // 含查询串，如 /api/v1/service/suppliers/C001/profile?x=1
+	Body      []byte
+}
+
+// StringToSign 签名原文：METHOD\nPATH\nTIMESTAMP\nNONCE\nhex(SHA256(body))
+func StringToSign(method, path, timestamp, nonce string, body []byte) string {
+	sum := sha256.Sum256(body)
+	return strings.Join([]string{strings.ToUpper(method), path, timestamp, nonce, hex.EncodeToString(sum[:])}, "\n")
+}
+
+// SigningKey 由 secret 推导签名密钥：SHA-256(secret)，调用方用同样方式计算
+func SigningKey(secret string) []byte {
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+// Service API key 服务
+type Service interface {
+	Create(ctx context.Context, req *CreateAPIKeyRequest, createdBy string) (*APIKey, string, error)
+	List(ctx context.Context) ([]APIKey, error)
+	Revoke(ctx context.Context, id string) (*APIKey, error)
+	Verify(ctx context.Context, req *SignedRequest, clientIP string) (*APIKey, error)
+	CleanupNonces()
+}
+
+type service struct {
+	repo   Repository
+	crypto *crypto.Crypto
+}
+
+// NewService 创建 API key 服务
+func NewService(repo Repository, cryptoService *crypto.Crypto) Service {
+	return &service{
+		repo:   repo,
+		crypto: cryptoService,
+	}
+}
+
+// Create 创建 API key，返回明文 secret（只此一次）
+func (s *service) Create(ctx context.Context, req *CreateAPIKeyRequest, createdBy string) (*APIKey, string, error) {
+	for _, scope := range req.Scopes {
+		if !ValidScope(scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+		if strings.TrimSpace(req.CustomerCode) != "" && !customerScoped[scope] {
+			return nil, "", fmt.Errorf("%w: %s 不支持限定供应商", ErrInvalidScope, scope)
+		}
+	}
+
+	keyID, err := randomHex(8)
+	if err != nil {
+		return nil, "", err
+	}
+	secret, err := randomHex(32)
+	if err != nil {
+		return nil, "", err
+	}
+	encrypted, err := s.crypto.Encrypt(hex.EncodeToString(SigningKey(secret)))
+	if err != nil {
+		return nil, "", err
+	}
+
+	ttl := defaultKeyTTL
+	if req.ExpiresInDays > 0 {
+		ttl = time.Duration(req.ExpiresInDays) * 24 * time.Hour
+	}
+	expiresAt := time.Now().Add(ttl)
+
+	key := &APIKey{
+		KeyID:               "ak_" + keyID,
+		Name:                req.Name,
+		Scopes:              strings.Join(req.Scopes, ","),
+		CustomerCode:        strings.TrimSpace(req.CustomerCode),
+		SigningKeyEncrypted: encrypted,
+		CreatedBy:           createdBy,
+		ExpiresAt:           &expiresAt,
+	}
+	if err := s.repo.Create(ctx, key); err != nil {
+		return nil, "", err
+	}
+	return key, "sk_" + secret, nil
+}
+
+// List 列出全部 API key
+func (s *service) List(ctx context.Context) ([]APIKey, error) {
+	return s.repo.List(ctx)
+}
+
+// Revoke 吊销 API key，立即生效
+func (s *service) Revoke(ctx context.Context, id string) (*APIKey, error) {
+	key, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if key.RevokedAt == nil {
+		now := time.Now()
+		if err := s.repo.Revoke(ctx, id, now); err != nil {
+			return nil, err
+		}
+		key.RevokedAt = &now
+	}
+	return key, nil
+}
+
+// Verify 校验签名请求：key 有效、时间戳在窗口内、nonce 未使用过、签名正确
+func (s *service) Verify(ctx context.Context, req *SignedRequest, clientIP string) (*APIKey, error) {
+	if req.KeyID == "" || req.Timestamp == "" || req.Nonce == "" || req.Signature == "" {
+		return nil, ErrMissingSignature
+	}
+	if len(req.Nonce) > maxNonceLength {
+		return nil, ErrInvalidNonce
+	}
+
+	now := time.Now()
+	ts, err := strconv.ParseInt(req.Timestamp, 10, 64)
+	if err != nil {
+		return nil, ErrBadTimestamp
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > signatureWindow || skew < -signatureWindow {
+		return nil, ErrBadTimestamp
+	}
+
+	key, err := s.repo.GetByKeyID(ctx, req.KeyID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidKey
+		}
+		return nil, err
+	}
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, ErrKeyExpired
+	}
+
+	signingKeyHex, err := s.crypto.Decrypt(key.SigningKeyEncrypted)
+	if err != nil {
+		return nil, err
+	}
+	signingKey, err := hex.DecodeString(signingKeyHex)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, signingKey)
+	mac.Write([]byte(StringToSign(req.Method, req.Path, req.Timestamp, req.Nonce, req.Body)))
+	expected := mac.Sum(nil)
+	given, err := hex.DecodeString(req.Signature)
+	if err != nil || !hmac.Equal(expected, given) {
+		return nil, ErrBadSignature
+	}
+
+	// 签名通过后再登记 nonce，避免伪造请求占用合法 nonce。
+	// 时间戳允许前后各偏差一个窗口，nonce 需保留两个窗口才能覆盖
+	fresh, err := s.repo.UseNonce(ctx, key.KeyID, req.Nonce, now, now.Add(2*signatureWindow))
+	if err != nil {
+		return nil, err
+	}
+	if !fresh {
+		return nil, ErrReplay
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > lastUsedInterval {
+		if err := s.repo.TouchLastUsed(ctx, key.ID, now, clientIP); err == nil {
+			key.LastUsedAt = &now
+			key.LastUsedIP = clientIP
+		}
+	}
+	return key, nil
+}
+
+// CleanupNonces 清理过期 nonce，多个实例同时清理不冲突
+func (s *service) CleanupNonces() {
+	if err := s.repo.DeleteExpiredNonces(context.Background(), time.Now()); err != nil {
+		log.Printf("[API-KEY] 清理过期 nonce 失败: %v", err)
+	}
+}
+
+// randomHex 生成 n 字节随机数的十六进制表示
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,103 @@
+package apikey
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+
+	"back/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 请求头
+	HeaderKeyID     = "X-Api-Key"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+
+	contextKey   = "api_key"
+	maxBodyBytes = 1 << 20
+)
+
+// Auth API key 签名认证中间件
+// 校验通过后把 key ID 写入 RequestContext.APIKeyID，限定了供应商的 key 同时写入 CustomerCode
+func Auth(service Service) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var body []byte
+		if c.Request.Body != nil {
+			b, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes+1))
+			if err != nil || len(b) > maxBodyBytes {
+				c.JSON(http.StatusRequestEntityTooLarge, ErrorResponse{Code: http.StatusRequestEntityTooLarge, Message: "request body too large"})
+				c.Abort()
+				return
+			}
+			body = b
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		key, err := service.Verify(c.Request.Context(), &SignedRequest{
+			KeyID:     c.GetHeader(HeaderKeyID),
+			Timestamp: c.GetHeader(HeaderTimestamp),
+			Nonce:     c.GetHeader(HeaderNonce),
+			Signature: c.GetHeader(HeaderSignature),
+			Method:    c.Request.Method,
+			Path:      c.Request.URL.RequestURI(),
+			Body:      body,
+		}, c.ClientIP())
+
+		rc := middleware.GetRequestContext(c)
+		if err != nil {
+			log.Printf("[API-KEY] 认证失败: key=%s err=%v 路径: %s", c.GetHeader(HeaderKeyID), err, c.Request.URL.Path)
+			if rc != nil {
+				rc.Action = "apikey.auth_failed"
+				rc.Detail = map[string]any{"key_id": c.GetHeader(HeaderKeyID), "error": err.Error()}
+			}
+			status := http.StatusUnauthorized
+			if !isAuthError(err) {
+				status = http.StatusInternalServerError
+			}
+			c.JSON(status, ErrorResponse{Code: status, Message: err.Error()})
+			c.Abort()
+			return
+		}
+
+		if rc != nil {
+			rc.APIKeyID = key.KeyID
+			rc.CustomerCode = key.CustomerCode
+		}
+		c.Set(contextKey, key)
+		c.Next()
+	}
+}
+
+// RequireScope 权限范围校验中间件，需在 Auth 之后使用
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		v, _ := c.Get(contextKey)
+		key, ok := v.(*APIKey)
+		if !ok || !key.HasScope(scope) {
+			if rc := middleware.GetRequestContext(c); rc != nil {
+				rc.Action = "apikey.scope_denied"
+				rc.Detail = map[string]any{"scope": scope}
+			}
+			c.JSON(http.StatusForbidden, ErrorResponse{Code: http.StatusForbidden, Message: "api key lacks scope " + scope})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// isAuthError 是否为调用方凭据问题（其余视为服务端错误）
+func isAuthError(err error) bool {
+	for _, e := range []error{ErrInvalidKey, ErrKeyExpired, ErrBadTimestamp, ErrReplay, ErrInvalidNonce, ErrBadSignature, ErrMissingSignature} {
+		if errors.Is(err, e) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package apikey
+
+import (
+	"strings"
+	"time"
+)
+
+// 接口权限范围
+const (
+	ScopeSupplierRead = "supplier:read" // 读取供应商档案
+	ScopeAuditRead    = "audit:read"    // 查询审计日志
+)
+
+// validScopes 可分配的权限范围
+var validScopes = map[string]bool{
+	ScopeSupplierRead: true,
+	ScopeAuditRead:    true,
+}
+
+// customerScoped 对应接口按 RequestContext.CustomerCode 过滤数据的权限范围（/service 下的
+// 供应商档案、审计日志查询与导出），限定了供应商的 key 只能分配这些。
+// 新增权限范围时，接口未按 key 的供应商过滤之前不要加入这里
+var customerScoped = map[string]bool{
+	ScopeSupplierRead: true,
+	ScopeAuditRead:    true,
+}
+
+// ValidScope 是否为合法权限范围
+func ValidScope(scope string) bool {
+	return validScopes[scope]
+}
+
+// APIKey 机器调用方的 API key
+// 明文 secret 只在创建时返回一次；库里只保存 SHA-256(secret)（即 HMAC 签名密钥），并用 CRYPTO_KEY 加密
+type APIKey struct {
+	ID                  string     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	KeyID               string     `json:"keyId" gorm:"type:varchar(32);not null;uniqueIndex"` // 公开的 key 标识，请求头 X-Api-Key
+	Name                string     `json:"name" gorm:"type:varchar(100);not null"`
+	Scopes              string     `json:"scopes" gorm:"type:varchar(500)"`      // 逗号分隔
+	CustomerCode        string     `json:"customerCode" gorm:"type:varchar(50)"` // 非空时只能访问该供应商的数据
+	SigningKeyEncrypted string     `json:"-" gorm:"type:varchar(255);not null"`
+	CreatedBy           string     `json:"createdBy" gorm:"type:varchar(64)"` // 创建的员工
+	ExpiresAt           *time.Time `json:"expiresAt"`
+	LastUsedAt          *time.Time `json:"lastUsedAt"`
+	LastUsedIP          string     `json:"lastUsedIp" gorm:"type:varchar(45)"`
+	RevokedAt           *time.Time `json:"revokedAt"`
+	CreatedAt           time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// Nonce 已使用的请求 nonce，(key_id, nonce) 唯一，各实例共享同一份记录防止重放；过期后定期清理
+type Nonce struct {
+	KeyID     string    `gorm:"type:varchar(32);primaryKey"`
+	Nonce     string    `gorm:"type:varchar(64);primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+}
+
+func (Nonce) TableName() string {
+	return "api_key_nonces"
+}
+
+// HasScope 是否拥有指定权限范围
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// ========== Request ==========
+
+// CreateAPIKeyRequest 创建 API key 请求
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" binding:"required,max=100"`
+	Scopes        []string `json:"scopes" binding:"required,min=1"`
+	CustomerCode  string   `json:"customerCode"`                                    // 可选，限定供应商
+	ExpiresInDays int      `json:"expiresInDays" binding:"omitempty,min=1,max=730"` // 不填默认 365 天
+}
+
+// ========== Response ==========
+
+// APIKeyResponse 单个 API key 响应
+type APIKeyResponse struct {
+	Code    int     `json:"code"`
+	Message string  `json:"message"`
+	Data    *APIKey `json:"data,omitempty"`
+	Secret  string  `json:"secret,omitempty"` // 仅创建时返回一次
+}
+
+// APIKeyListResponse API key 列表响应
+type APIKeyListResponse struct {
+	Code    int      `json:"code"`
+	Message string   `json:"message"`
+	Data    []APIKey `json:"data"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,471 @@
+// Package auditapi 审计日志的查询与导出接口
+// （pkg/middleware 依赖 audit 包，处理器需要读写 RequestContext，因此单独成包）
+package auditapi
+
+import (
+	"back/internal/audit"
+	"back/pkg/middleware"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出格式
+const (
+	FormatCSV    = "csv"
+	FormatNDJSON = "ndjson"
+)
+
+// flushEvery 导出时每写出多少条刷新一次
+const flushEvery = 500
+
+// csvHeader CSV 导出的列
+var csvHeader = []string{
+	"created_at", "request_id", "username", "customer_code", "staff_username", "impersonation", "api_key_id",
+	"action", "resource", "resource_id", "method", "path", "status_code", "client_ip", "user_agent", "duration_ms", "detail",
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Handler 审计日志查询处理器（管理端与机器调用方共用）
+type Handler struct {
+	service    audit.Service
+	partitions *audit.PartitionManager
+}
+
+// NewHandler 创建审计日志查询处理器
+func NewHandler(service audit.Service, partitions *audit.PartitionManager) *Handler {
+	return &Handler{service: service, partitions: partitions}
+}
+
+// List 按条件查询审计日志
+// @Summary 审计日志查询
+// @Description 按时间倒序，游标分页；action 以 * 结尾时按前缀匹配（如 admin.*）；限定了供应商的 API key 只返回该供应商的记录
+// @Tags admin-audit
+// @Produce json
+// @Param username query string false "用户名"
+// @Param customer_code query string false "供应商编码"
+// @Param staff_username query string false "员工用户名"
+// @Param api_key_id query string false "API key ID"
+// @Param action query string false "操作"
+// @Param resource query string false "资源类型"
+// @Param resource_id query string false "资源ID"
+// @Param status query int false "HTTP 状态码"
+// @Param client_ip query string false "来源IP"
+// @Param request_id query string false "请求ID"
+// @Param from query string false "时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param to query string false "时间止（不含）"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Param limit query int false "每页条数，默认 50，最大 500"
+// @Success 200 {object} audit.LogListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/admin/audit-logs [get]
+func (h *Handler) List(c *gin.Context) {
+	q, err := parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if !restrictToKeyCustomer(c, q) {
+		return
+	}
+
+	logs, nextCursor, err := h.service.Search(c.Request.Context(), q)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, audit.ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ErrorResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "audit.search"
+		rc.Resource = "audit_log"
+		rc.Detail = map[string]any{"filters": queryDetail(q), "count": len(logs)}
+	}
+
+	c.JSON(http.StatusOK, audit.LogListResponse{
+		Code:       http.StatusOK,
+		Message:    "获取成功",
+		Data:       logs,
+		NextCursor: nextCursor,
+	})
+}
+
+// Export 按条件流式导出审计日志
+// @Summary 审计日志导出
+// @Description 条件与查询接口相同，按时间倒序流式输出 CSV 或 NDJSON（每行一个 JSON 对象）；limit 为最多导出条数，默认且最大 1000000
+// @Tags admin-audit
+// @Produce text/csv
+// @Produce application/x-ndjson
+// @Param format query string false "导出格式" Enums(csv, ndjson)
+// @Param username query string false "用户名"
+// @Param action query string false "操作"
+// @Param from query string false "时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param to query string false "时间止（不含）"
+// @Param limit query int false "最多导出条数"
+// @Success 200 {string} string "导出文件"
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/admin/audit-logs/export [get]
+func (h *Handler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatCSV)
+	if format != FormatCSV && format != FormatNDJSON {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid format",
+		})
+		return
+	}
+	q, err := parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if q.Cursor != "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "export does not support cursor",
+		})
+		return
+	}
+	if !restrictToKeyCustomer(c, q) {
+		return
+	}
+	rc := middleware.GetRequestContext(c)
+	if rc != nil {
+		rc.Action = "audit.export"
+		rc.Resource = "audit_log"
+	}
+
+	filename := fmt.Sprintf("audit-logs-%s.%s", time.Now().Format("20060102-150405"), format)
+	contentType := "text/csv; charset=utf-8"
+	if format == FormatNDJSON {
+		contentType = "application/x-ndjson"
+	}
+	w := c.Writer
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	var write func(*audit.AuditLog) error
+	var flush func()
+	switch format {
+	case FormatCSV:
+		// 带 BOM 便于 Excel 按 UTF-8 打开
+		w.WriteString("\ufeff")
+		cw := csv.NewWriter(w)
+		cw.Write(csvHeader)
+		write = func(l *audit.AuditLog) error {
+			return cw.Write(csvRow(l))
+		}
+		flush = func() {
+			cw.Flush()
+			w.Flush()
+		}
+	default:
+		enc := json.NewEncoder(w)
+		write = func(l *audit.AuditLog) error {
+			return enc.Encode(l)
+		}
+		flush = w.Flush
+	}
+
+	n := 0
+	count, err := h.service.Export(c.Request.Context(), q, func(l *audit.AuditLog) error {
+		if err := write(l); err != nil {
+			return err
+		}
+		n++
+		if n%flushEvery == 0 {
+			flush()
+		}
+		return nil
+	})
+	flush()
+
+	// 响应头已发出，出错时只能截断输出，记入审计
+	if rc != nil {
+		detail := map[string]any{"filters": queryDetail(q), "format": format, "count": count}
+		if err != nil {
+			detail["error"] = err.Error()
+		}
+		rc.Detail = detail
+	}
+	if err != nil {
+		log.Printf("[AUDIT] 导出中断，已导出 %d 条: %v", count, err)
+	}
+}
+
+// Stats 审计写入管道指标
+// @Summary 审计写入管道指标
+// @Description 内存队列深度、已写入/溢出/回放/丢弃条数等，本实例自启动起累计；sinks 为数据库之外各输出目标的指标
+// @Tags admin-audit
+// @Produce json
+// @Success 200 {object} audit.StatsResponse
+// @Router /api/v1/admin/audit-logs/metrics [get]
+func (h *Handler) Stats(c *gin.Context) {
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "audit.metrics"
+		rc.Resource = "audit_log"
+	}
+	c.JSON(http.StatusOK, audit.StatsResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    h.service.Stats(),
+	})
+}
+
+// Verify 校验审计日志哈希链
+// @Summary 审计日志防篡改校验
+// @Description 校验时间范围内记录的序号连续性、链式哈希和签名检查点，返回第一个断裂处；不指定时间时校验整条链
+// @Tags admin-audit
+// @Produce json
+// @Param from query string false "时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param to query string false "时间止（不含）"
+// @Success 200 {object} audit.VerifyResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/admin/audit-logs/verify [get]
+func (h *Handler) Verify(c *gin.Context) {
+	var from, to *time.Time
+	for key, dst := range map[string]**time.Time{
+		"from": &from,
+		"to":   &to,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid " + key,
+			})
+			return
+		}
+		*dst = &t
+	}
+
+	result, err := h.service.Verify(c.Request.Context(), from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "audit.verify"
+		rc.Resource = "audit_log"
+		detail := map[string]any{"ok": result.OK, "checked": result.Checked}
+		if from != nil {
+			detail["from"] = from.Format(time.RFC3339)
+		}
+		if to != nil {
+			detail["to"] = to.Format(time.RFC3339)
+		}
+		if result.BrokenLink != nil {
+			detail["broken_seq"] = result.BrokenLink.Seq
+			detail["reason"] = result.BrokenLink.Reason
+		}
+		rc.Detail = detail
+	}
+
+	message := "校验通过"
+	if !result.OK {
+		message = "哈希链已断裂"
+	}
+	c.JSON(http.StatusOK, audit.VerifyResponse{
+		Code:    http.StatusOK,
+		Message: message,
+		Data:    *result,
+	})
+}
+
+// Storage 审计日志存储占用
+// @Summary 审计日志存储占用
+// @Description 各分区（保留类别 × 月份）的估算行数、表和索引大小、到期归档时间，以及保留策略和最近的归档记录
+// @Tags admin-audit
+// @Produce json
+// @Success 200 {object} audit.StorageResponse
+// @Router /api/v1/admin/audit-logs/storage [get]
+func (h *Handler) Storage(c *gin.Context) {
+	info, err := h.partitions.Storage(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "audit.storage"
+		rc.Resource = "audit_log"
+	}
+
+	c.JSON(http.StatusOK, audit.StorageResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    *info,
+	})
+}
+
+// parseQuery 解析查询条件
+func parseQuery(c *gin.Context) (*audit.Query, error) {
+	q := &audit.Query{
+		Username:      strings.TrimSpace(c.Query("username")),
+		CustomerCode:  strings.TrimSpace(c.Query("customer_code")),
+		StaffUsername: strings.TrimSpace(c.Query("staff_username")),
+		APIKeyID:      strings.TrimSpace(c.Query("api_key_id")),
+		Action:        strings.TrimSpace(c.Query("action")),
+		Resource:      strings.TrimSpace(c.Query("resource")),
+		ResourceID:    strings.TrimSpace(c.Query("resource_id")),
+		ClientIP:      strings.TrimSpace(c.Query("client_ip")),
+		RequestID:     strings.TrimSpace(c.Query("request_id")),
+		Cursor:        c.Query("cursor"),
+	}
+	if v := c.Query("status"); v != "" {
+		status, err := strconv.Atoi(v)
+		if err != nil || status < 100 || status > 599 {
+			return nil, errors.New("invalid status")
+		}
+		q.StatusCode = status
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			return nil, errors.New("invalid limit")
+		}
+		q.Limit = limit
+	}
+	for key, dst := range map[string]**time.Time{
+		"from": &q.From,
+		"to":   &q.To,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return nil, errors.New("invalid " + key)
+		}
+		*dst = &t
+	}
+	return q, nil
+}
+
+// restrictToKeyCustomer 限定了供应商的 API key 只能查询该供应商的记录：
+// 未指定 customer_code 时强制按 key 的供应商过滤，指定了其他供应商时返回 403
+func restrictToKeyCustomer(c *gin.Context, q *audit.Query) bool {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.APIKeyID == "" || rc.CustomerCode == "" {
+		return true
+	}
+	if q.CustomerCode != "" && q.CustomerCode != rc.CustomerCode {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "该 API key 无权访问此供应商",
+		})
+		return false
+	}
+	q.CustomerCode = rc.CustomerCode
+	return true
+}
+
+// parseTimeParam 解析时间参数，支持 RFC3339 和 YYYY-MM-DD（按本地时区）
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// queryDetail 记入审计的查询条件（只保留非空项）
+func queryDetail(q *audit.Query) map[string]any {
+	detail := make(map[string]any)
+	for key, value := range map[string]string{
+		"username":       q.Username,
+		"customer_code":  q.CustomerCode,
+		"staff_username": q.StaffUsername,
+		"api_key_id":     q.APIKeyID,
+		"action":         q.Action,
+		"resource":       q.Resource,
+		"resource_id":    q.ResourceID,
+		"client_ip":      q.ClientIP,
+		"request_id":     q.RequestID,
+	} {
+		if value != "" {
+			detail[key] = value
+		}
+	}
+	if q.StatusCode != 0 {
+		detail["status"] = q.StatusCode
+	}
+	if q.From != nil {
+		detail["from"] = q.From.Format(time.RFC3339)
+	}
+	if q.To != nil {
+		detail["to"] = q.To.Format(time.RFC3339)
+	}
+	return detail
+}
+
+// csvRow 一条审计日志的 CSV 行
+func csvRow(l *audit.AuditLog) []string {
+	return []string{
+		l.CreatedAt.Format(time.RFC3339Nano),
+		l.RequestID,
+		safeCell(l.Username),
+		safeCell(l.CustomerCode),
+		safeCell(l.StaffUsername),
+		strconv.FormatBool(l.Impersonation),
+		safeCell(l.APIKeyID),
+		safeCell(l.Action),
+		safeCell(l.Resource),
+		safeCell(l.ResourceID),
+		l.Method,
+		safeCell(l.Path),
+		strconv.Itoa(l.StatusCode),
+		safeCell(l.ClientIP),
+		safeCell(l.UserAgent),
+		strconv.FormatInt(l.Duration, 10),
+		safeCell(l.Detail),
+	}
+}
+
+// safeCell 以公式字符开头的单元格前加单引号，防止在表格软件中被当作公式执行
+func safeCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
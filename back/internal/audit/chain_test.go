@@ -0,0 +1,87 @@
+package audit
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// testEntry 构造一条内容随 i 变化的记录
+func testEntry(i int, createdAt time.Time) *AuditLog {
+	return &AuditLog{
+		ID:         fmt.Sprintf("00000000-0000-0000-0000-%012d", i),
+		RequestID:  fmt.Sprintf("req-%d", i),
+		Username:   "alice",
+		Action:     "user.update",
+		Resource:   "user",
+		ResourceID: fmt.Sprintf("%d", i),
+		Method:     "PUT",
+		Path:       "/api/v1/admin/users/1",
+		StatusCode: 200,
+		ClientIP:   "10.0.0.1",
+		Duration:   int64(i),
+		Detail:     fmt.Sprintf(`{"i":%d}`, i),
+		CreatedAt:  createdAt,
+	}
+}
+
+func TestComputeHashStableAcrossJSONBRoundTrip(t *testing.T) {
+	// 写入时 CreatedAt 为本地时区、纳秒精度；读回时为 UTC、微秒精度
+	written := time.Date(2025, 3, 1, 8, 30, 15, 123456789, time.FixedZone("CST", 8*3600))
+	stored := written.Truncate(time.Microsecond).UTC()
+
+	tests := []struct {
+		name     string
+		detail   string // 写入时计算哈希用的 Detail（encoding/json 输出）
+		readBack string // 从 jsonb 列读回的 Detail
+		wantSame bool
+	}{
+		{name: "空", detail: "", readBack: "", wantSame: true},
+		{name: "null", detail: "null", readBack: "null", wantSame: true},
+		{name: "键顺序与空白", detail: `{"b":1,"a":{"d":[1,2],"c":"x"}}`, readBack: `{"a": {"c": "x", "d": [1, 2]}, "b": 1}`, wantSame: true},
+		{name: "HTML 字符转义", detail: `{"q":"a\u003cb\u0026c\u003ed"}`, readBack: `{"q": "a<b&c>d"}`, wantSame: true},
+		{name: "Unicode 转义", detail: `{"name":"\u5f20\u4e09"}`, readBack: `{"name": "张三"}`, wantSame: true},
+		{name: "数字写法", detail: `{"n":1.50,"m":1e2}`, readBack: `{"m": 100, "n": 1.50}`, wantSame: true},
+		{name: "重复键保留最后一个", detail: `{"a":1,"a":2}`, readBack: `{"a": 2}`, wantSame: true},
+		{name: "非 JSON 按原文", detail: "not json", readBack: "not json", wantSame: true},
+		{name: "值被修改", detail: `{"a":1}`, readBack: `{"a": 2}`, wantSame: false},
+		{name: "增加字段", detail: `{"a":1}`, readBack: `{"a": 1, "b": null}`, wantSame: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := testEntry(1, written)
+			before.Detail = tt.detail
+			after := *before
+			after.Detail = tt.readBack
+			after.CreatedAt = stored
+
+			same := ComputeHash(before) == ComputeHash(&after)
+			if same != tt.wantSame {
+				t.Fatalf("哈希相同 = %v, want %v（canonical: %q / %q）", same, tt.wantSame, canonicalJSON(tt.detail), canonicalJSON(tt.readBack))
+			}
+		})
+	}
+}
+
+func TestChainEntries(t *testing.T) {
+	base := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	entries := []*AuditLog{testEntry(1, base), testEntry(2, base.Add(time.Second)), testEntry(3, base.Add(2*time.Second))}
+
+	lastSeq, lastHash := chainEntries(entries, 41, "prev")
+	if lastSeq != 44 || lastHash != entries[2].Hash {
+		t.Fatalf("chainEntries = (%d, %s), want (44, %s)", lastSeq, lastHash, entries[2].Hash)
+	}
+	prev := "prev"
+	for i, e := range entries {
+		if e.Seq != int64(42+i) || e.PrevHash != prev {
+			t.Fatalf("entries[%d]: seq=%d prev=%q, want seq=%d prev=%q", i, e.Seq, e.PrevHash, 42+i, prev)
+		}
+		if e.OriginalUsername != e.Username {
+			t.Fatalf("entries[%d]: OriginalUsername = %q, want %q", i, e.OriginalUsername, e.Username)
+		}
+		if e.Hash != ComputeHash(e) {
+			t.Fatalf("entries[%d]: Hash 与内容不符", i)
+		}
+		prev = e.Hash
+	}
+}
@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseCheckpointKey 解析检查点签名私钥（base64 编码的 32 字节 Ed25519 种子）
+func ParseCheckpointKey(s string) (ed25519.PrivateKey, error) {
+	seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("检查点签名私钥应为 base64 编码的 %d 字节种子", ed25519.SeedSize)
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// ParsePublicKeys 解析逗号分隔的 base64 公钥列表（轮换后用于校验旧检查点）
+func ParsePublicKeys(s string) ([]ed25519.PublicKey, error) {
+	var keys []ed25519.PublicKey
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(part)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("无效的检查点公钥: %s", part)
+		}
+		keys = append(keys, ed25519.PublicKey(raw))
+	}
+	return keys, nil
+}
+
+// KeyID 公钥指纹，记录在检查点上用于选择校验公钥
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// checkpointMessage 检查点的签名内容
+func checkpointMessage(cp *Checkpoint) []byte {
+	return []byte("audit-checkpoint:v1:" + strconv.FormatInt(cp.Seq, 10) + ":" + cp.Hash + ":" +
+		truncateTime(cp.CreatedAt).UTC().Format(time.RFC3339Nano))
+}
+
+// verifyCheckpoint 校验检查点签名，公钥未知时返回 false
+func verifyCheckpoint(cp *Checkpoint, keys map[string]ed25519.PublicKey) bool {
+	pub, ok := keys[cp.KeyID]
+	if !ok {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(cp.Signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(pub, checkpointMessage(cp), sig)
+}
+
+// checkpoint 链尾有新记录时签发检查点，多实例同时签发同一序号时只保留一条
+func (s *service) checkpoint(ctx context.Context) (*Checkpoint, error) {
+	seq, hash, err := s.repo.ChainHead(ctx)
+	if err != nil || seq == 0 {
+		return nil, err
+	}
+	latest, err := s.repo.LatestCheckpoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if latest != nil && latest.Seq >= seq {
+		return nil, nil
+	}
+
+	cp := &Checkpoint{
+		Seq:       seq,
+		Hash:      hash,
+		KeyID:     KeyID(s.cfg.CheckpointKey.Public().(ed25519.PublicKey)),
+		CreatedAt: truncateTime(time.Now()),
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(s.cfg.CheckpointKey, checkpointMessage(cp)))
+	if err := s.repo.CreateCheckpoint(ctx, cp); err != nil {
+		return nil, err
+	}
+	return cp, nil
+}
+
+// runCheckpoints 按间隔签发检查点，停止时再签发一次覆盖最后写入的记录
+func (s *service) runCheckpoints() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.cfg.CheckpointInterval)
+	defer ticker.Stop()
+
+	issue := func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		cp, err := s.checkpoint(ctx)
+		if err != nil {
+			log.Printf("[AUDIT] 签发检查点失败: %v", err)
+			return
+		}
+		if cp != nil {
+			log.Printf("[AUDIT] 已签发检查点 seq=%d", cp.Seq)
+		}
+	}
+
+	for {
+		select {
+		case <-ticker.C:
+			issue()
+		case <-s.writerDone:
+			issue()
+			return
+		}
+	}
+}
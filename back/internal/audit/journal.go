@@ -0,0 +1,183 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ErrJournalFull 溢出日志已达上限
+var ErrJournalFull = errors.New("audit journal full")
+
+// journal 本地只追加的溢出日志（每行一条 JSON），数据库不可用或队列满时暂存审计记录
+// 回放时先把日志改名为 .replay 文件，新的溢出写入新文件，互不干扰
+type journal struct {
+	path     string
+	maxBytes int64
+	mu       sync.Mutex // 保护 path 文件的追加和改名
+}
+
+func newJournal(path string, maxBytes int64) *journal {
+	return &journal{path: path, maxBytes: maxBytes}
+}
+
+func (j *journal) replayPath() string {
+	return j.path + ".replay"
+}
+
+// size 待回放的字节数（含回放中的文件）
+func (j *journal) size() int64 {
+	var total int64
+	for _, p := range []string{j.path, j.replayPath()} {
+		if fi, err := os.Stat(p); err == nil {
+			total += fi.Size()
+		}
+	}
+	return total
+}
+
+// append 追加记录并落盘
+func (j *journal) append(entries []*AuditLog) error {
+	var buf []byte
+	for _, e := range entries {
+		line, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, line...)
+		buf = append(buf, '\n')
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.size()+int64(len(buf)) > j.maxBytes {
+		return ErrJournalFull
+	}
+	if err := os.MkdirAll(filepath.Dir(j.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// replay 按批回放日志中的记录，返回成功写入的条数；write 失败时保留该批及之后的记录，下次继续
+func (j *journal) replay(batchSize int, write func([]*AuditLog) error) (int, error) {
+	replaying := j.replayPath()
+	// 上次回放中断时先处理遗留的回放文件
+	if _, err := os.Stat(replaying); errors.Is(err, os.ErrNotExist) {
+		j.mu.Lock()
+		err := os.Rename(j.path, replaying)
+		j.mu.Unlock()
+		if errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	f, err := os.Open(replaying)
+	if err != nil {
+		return 0, err
+	}
+	r := bufio.NewReader(f)
+
+	written := 0
+	var batch []*AuditLog
+	var offset, batchStart int64
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := write(batch); err != nil {
+			return err
+		}
+		written += len(batch)
+		batch = batch[:0]
+		batchStart = offset
+		return nil
+	}
+
+	for {
+		line, readErr := r.ReadBytes('\n')
+		offset += int64(len(line))
+		if len(line) > 0 {
+			var entry AuditLog
+			if err := json.Unmarshal(line, &entry); err != nil {
+				// 进程在写入中途退出会留下不完整的行，跳过
+				log.Printf("[AUDIT] 跳过溢出日志中无法解析的行: %v", err)
+			} else {
+				batch = append(batch, &entry)
+			}
+		}
+		if len(batch) >= batchSize || (readErr != nil && len(batch) > 0) {
+			if err := flush(); err != nil {
+				f.Close()
+				if kerr := keepFrom(replaying, batchStart); kerr != nil {
+					return written, fmt.Errorf("%w（保留未回放部分失败: %v）", err, kerr)
+				}
+				return written, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			f.Close()
+			return written, readErr
+		}
+	}
+
+	f.Close()
+	return written, os.Remove(replaying)
+}
+
+// keepFrom 只保留文件 offset 之后的内容
+func keepFrom(path string, offset int64) error {
+	if offset == 0 {
+		return nil
+	}
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	if _, err := src.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	dst, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Sync(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,116 @@
+package audit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestJournalReplayResumesAfterPartialFailure(t *testing.T) {
+	errDown := errors.New("database down")
+	tests := []struct {
+		name        string
+		total       int
+		batchSize   int
+		failOnCall  int // 第几次 write 失败（从 1 开始）
+		wantWritten int // 失败前已写入的条数
+	}{
+		{name: "第一批失败，文件原样保留", total: 10, batchSize: 3, failOnCall: 1, wantWritten: 0},
+		{name: "中间一批失败", total: 10, batchSize: 3, failOnCall: 2, wantWritten: 3},
+		{name: "最后不满一批时失败", total: 10, batchSize: 3, failOnCall: 4, wantWritten: 9},
+		{name: "批大小整除总数，最后一批失败", total: 9, batchSize: 3, failOnCall: 3, wantWritten: 6},
+		{name: "批大小为 1", total: 5, batchSize: 1, failOnCall: 4, wantWritten: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j := newJournal(filepath.Join(t.TempDir(), "audit.journal"), 1<<20)
+			for i := 1; i <= tt.total; i++ {
+				if err := j.append([]*AuditLog{testEntry(i, time.Unix(int64(i), 0))}); err != nil {
+					t.Fatalf("append: %v", err)
+				}
+			}
+
+			var got []string
+			calls := 0
+			written, err := j.replay(tt.batchSize, func(batch []*AuditLog) error {
+				calls++
+				if calls == tt.failOnCall {
+					return errDown
+				}
+				for _, e := range batch {
+					got = append(got, e.ID)
+				}
+				return nil
+			})
+			if !errors.Is(err, errDown) || written != tt.wantWritten {
+				t.Fatalf("replay = (%d, %v), want (%d, %v)", written, err, tt.wantWritten, errDown)
+			}
+			if _, err := os.Stat(j.replayPath()); err != nil {
+				t.Fatalf("回放失败后应保留回放文件: %v", err)
+			}
+
+			// 回放中断期间新的溢出写入新文件，下次先回放遗留的回放文件，再回放新文件
+			extra := testEntry(tt.total+1, time.Unix(int64(tt.total+1), 0))
+			if err := j.append([]*AuditLog{extra}); err != nil {
+				t.Fatalf("append: %v", err)
+			}
+			write := func(batch []*AuditLog) error {
+				for _, e := range batch {
+					got = append(got, e.ID)
+				}
+				return nil
+			}
+			n, err := j.replay(tt.batchSize, write)
+			if err != nil || n != tt.total-tt.wantWritten {
+				t.Fatalf("续传回放 = (%d, %v), want (%d, nil)", n, err, tt.total-tt.wantWritten)
+			}
+			if n, err := j.replay(tt.batchSize, write); err != nil || n != 1 {
+				t.Fatalf("回放新文件 = (%d, %v), want (1, nil)", n, err)
+			}
+
+			if len(got) != tt.total+1 {
+				t.Fatalf("回放 %d 条, want %d: %v", len(got), tt.total+1, got)
+			}
+			for i, id := range got {
+				if want := testEntry(i+1, time.Time{}).ID; id != want {
+					t.Fatalf("第 %d 条 = %s, want %s（重复或遗漏）", i+1, id, want)
+				}
+			}
+			if j.size() != 0 {
+				t.Fatalf("回放完成后仍有 %d 字节未回放", j.size())
+			}
+		})
+	}
+}
+
+func TestJournalReplaySkipsTruncatedLine(t *testing.T) {
+	j := newJournal(filepath.Join(t.TempDir(), "audit.journal"), 1<<20)
+	if err := j.append([]*AuditLog{testEntry(1, time.Unix(1, 0))}); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	// 模拟进程在写入中途退出留下的不完整行
+	f, err := os.OpenFile(j.path, os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"id":"00000000-0000-0000-0000-0000000`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	var got []string
+	n, err := j.replay(10, func(batch []*AuditLog) error {
+		for _, e := range batch {
+			got = append(got, e.ID)
+		}
+		return nil
+	})
+	if err != nil || n != 1 || len(got) != 1 || got[0] != testEntry(1, time.Time{}).ID {
+		t.Fatalf("replay = (%d, %v) %v, want 1 条", n, err, got)
+	}
+	if j.size() != 0 {
+		t.Fatalf("回放完成后仍有 %d 字节未回放", j.size())
+	}
+}
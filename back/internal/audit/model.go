@@ -4,18 +4,252 @@ import "time"
 
 // AuditLog represents a single audit log entry persisted to the database.
 type AuditLog struct {
-	ID         string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
-	RequestID  string    `gorm:"type:varchar(36);index"`
-	Username   string    `gorm:"type:varchar(64);index"`
-	Action     string    `gorm:"type:varchar(100);index"`
-	Resource   string    `gorm:"type:varchar(50)"`
-	ResourceID string    `gorm:"type:varchar(36)"`
-	Method     string    `gorm:"type:varchar(10)"`
-	Path       string    `gorm:"type:varchar(500)"`
-	StatusCode int
-	ClientIP   string    `gorm:"type:varchar(45)"`
-	UserAgent  string    `gorm:"type:varchar(500)"`
-	Duration   int64     // milliseconds
-	Detail     string    `gorm:"type:jsonb"`
-	CreatedAt  time.Time `gorm:"autoCreateTime;index"`
+	ID            string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	RequestID     string    `json:"requestId" gorm:"type:varchar(36);index"`
+	Username      string    `json:"username" gorm:"type:varchar(64);index"`
+	CustomerCode  string    `json:"customerCode" gorm:"type:varchar(50);index"`
+	StaffUsername string    `json:"staffUsername" gorm:"type:varchar(64);index"`
+	Impersonation bool      `json:"impersonation" gorm:"not null;default:false"`
+	APIKeyID      string    `json:"apiKeyId" gorm:"type:varchar(32);index"`
+	Action        string    `json:"action" gorm:"type:varchar(100);index"`
+	Resource      string    `json:"resource" gorm:"type:varchar(50);index:idx_audit_logs_resource"`
+	ResourceID    string    `json:"resourceId" gorm:"type:varchar(36);index:idx_audit_logs_resource"`
+	Method        string    `json:"method" gorm:"type:varchar(10)"`
+	Path          string    `json:"path" gorm:"type:varchar(500)"`
+	StatusCode    int       `json:"statusCode"`
+	ClientIP      string    `json:"clientIp" gorm:"type:varchar(45);index"`
+	UserAgent     string    `json:"userAgent" gorm:"type:varchar(500)"`
+	Duration      int64     `json:"durationMs"` // milliseconds
+	Detail        string    `json:"detail" gorm:"type:jsonb"`
+	CreatedAt     time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+
+	// 哈希链（见 chain.go），Seq 为 0 表示启用哈希链之前的历史记录
+	Seq              int64  `json:"seq" gorm:"not null;default:0;index"`
+	PrevHash         string `json:"prevHash" gorm:"type:varchar(64)"`
+	Hash             string `json:"hash" gorm:"type:varchar(64)"`
+	OriginalUsername string `json:"originalUsername" gorm:"type:varchar(64)"` // 写入时的用户名，账号合并只改 Username，哈希覆盖此字段
+
+	// 保留类别（见 retention.go），写入时按 action 归类，决定所在分区和保留时长；不参与哈希
+	Category string `json:"category,omitempty" gorm:"type:varchar(20);not null;default:'default'"`
+}
+
+// ChainLink 已归档记录在哈希链上的位置。分区归档删除后保留序号和哈希，
+// 校验跨过归档的记录时仍能确认链是连续的
+type ChainLink struct {
+	Seq       int64     `gorm:"primaryKey;autoIncrement:false"`
+	ID        string    `gorm:"type:uuid;not null"`
+	CreatedAt time.Time `gorm:"not null"`
+	PrevHash  string    `gorm:"type:varchar(64)"`
+	Hash      string    `gorm:"type:varchar(64);not null"`
+}
+
+// TableName 指定表名
+func (ChainLink) TableName() string {
+	return "audit_chain_links"
+}
+
+// Archive 已归档并删除的分区
+type Archive struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Partition string    `json:"partition" gorm:"type:varchar(63);not null"`
+	Category  string    `json:"category" gorm:"type:varchar(20);not null"`
+	RangeFrom time.Time `json:"rangeFrom" gorm:"not null"`
+	RangeTo   time.Time `json:"rangeTo" gorm:"not null"`
+	Rows      int64     `json:"rows"`
+	FirstSeq  int64     `json:"firstSeq"` // 入链记录的序号范围，0 表示没有
+	LastSeq   int64     `json:"lastSeq"`
+	Path      string    `json:"path" gorm:"type:varchar(500);not null"`
+	Bytes     int64     `json:"bytes"`
+	SHA256    string    `json:"sha256" gorm:"column:sha256;type:varchar(64);not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+// TableName 指定表名
+func (Archive) TableName() string {
+	return "audit_archives"
+}
+
+// Checkpoint 哈希链检查点：定期对链尾的序号和哈希签名，
+// 防止整段改写记录并重算哈希（没有签名私钥无法伪造检查点）
+type Checkpoint struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Seq       int64     `json:"seq" gorm:"not null;uniqueIndex"`
+	Hash      string    `json:"hash" gorm:"type:varchar(64);not null"`
+	KeyID     string    `json:"keyId" gorm:"type:varchar(16);not null"` // 签名公钥指纹
+	Signature string    `json:"signature" gorm:"type:varchar(128);not null"`
+	CreatedAt time.Time `json:"createdAt" gorm:"not null"`
+}
+
+// TableName 指定表名
+func (Checkpoint) TableName() string {
+	return "audit_checkpoints"
+}
+
+// Query 审计日志查询条件，字符串条件为空时不过滤
+type Query struct {
+	Username      string
+	CustomerCode  string
+	StaffUsername string
+	APIKeyID      string
+	Action        string // 精确匹配；以 * 结尾时按前缀匹配，如 admin.*
+	Resource      string
+	ResourceID    string
+	StatusCode    int // 0 表示不过滤
+	ClientIP      string
+	RequestID     string
+	From          *time.Time // 时间范围 [From, To)
+	To            *time.Time
+	Cursor        string // 上一页返回的 nextCursor
+	Limit         int
+}
+
+// StaffAccessView 用户可见的员工访问记录
+type StaffAccessView struct {
+	Time          time.Time `json:"time"`
+	Staff         string    `json:"staff"`         // 员工用户名
+	Impersonation bool      `json:"impersonation"` // 以用户身份代查看
+	Action        string    `json:"action"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	StatusCode    int       `json:"statusCode"`
+}
+
+// StaffAccessResponse 员工访问记录响应
+type StaffAccessResponse struct {
+	Code    int               `json:"code"`
+	Message string            `json:"message"`
+	Data    []StaffAccessView `json:"data"`
+}
+
+// LogListResponse 审计日志列表响应
+type LogListResponse struct {
+	Code       int        `json:"code"`
+	Message    string     `json:"message"`
+	Data       []AuditLog `json:"data"`
+	NextCursor string     `json:"nextCursor,omitempty"` // 为空表示没有更多
+}
+
+// ActivityView 用户可见的本账号操作记录
+type ActivityView struct {
+	Time          time.Time `json:"time"`
+	Action        string    `json:"action"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	StatusCode    int       `json:"statusCode"`
+	ClientIP      string    `json:"clientIp"`
+	UserAgent     string    `json:"userAgent"`
+	Staff         string    `json:"staff,omitempty"` // 员工操作或代查看时的员工用户名
+	Impersonation bool      `json:"impersonation"`
+}
+
+// ActivityResponse 本账号操作记录响应
+type ActivityResponse struct {
+	Code       int            `json:"code"`
+	Message    string         `json:"message"`
+	Data       []ActivityView `json:"data"`
+	NextCursor string         `json:"nextCursor,omitempty"`
+}
+
+// Stats 审计写入管道指标（自进程启动起累计）
+type Stats struct {
+	QueueDepth    int        `json:"queueDepth"`    // 内存队列中待写入的条数
+	QueueCapacity int        `json:"queueCapacity"` // 内存队列容量
+	Written       int64      `json:"written"`       // 已写入数据库
+	Spilled       int64      `json:"spilled"`       // 写入溢出日志
+	Replayed      int64      `json:"replayed"`      // 从溢出日志回放写入数据库
+	Dropped       int64      `json:"dropped"`       // 丢弃（未配置溢出日志或溢出日志写满）
+	Failed        int64      `json:"failed"`        // 数据不合法无法写入而丢弃
+	Batches       int64      `json:"batches"`       // 写库次数（含重试）
+	Retries       int64      `json:"retries"`       // 瞬时错误重试次数
+	JournalBytes  int64      `json:"journalBytes"`  // 溢出日志待回放字节数
+	LastFlushAt   *time.Time `json:"lastFlushAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+
+	Sinks []SinkStats `json:"sinks,omitempty"` // 数据库之外的输出目标，以上各项为数据库写入管道
+}
+
+// StatsResponse 审计写入管道指标响应
+type StatsResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    Stats  `json:"data"`
+}
+
+// 哈希链断裂原因
+const (
+	BreakMissing             = "missing"              // 序号缺失（记录被删除）
+	BreakDuplicate           = "duplicate"            // 序号重复（记录被插入）
+	BreakPrevHash            = "prev_hash_mismatch"   // PrevHash 与上一条的 Hash 不符
+	BreakHash                = "hash_mismatch"        // 记录内容与 Hash 不符（记录被修改）
+	BreakCheckpointHash      = "checkpoint_mismatch"  // 检查点的哈希与该序号记录的 Hash 不符（整段被改写）
+	BreakCheckpointSignature = "checkpoint_signature" // 检查点签名无效或签名公钥未知
+)
+
+// BrokenLink 第一个校验失败的位置
+type BrokenLink struct {
+	Seq       int64      `json:"seq"`
+	ID        string     `json:"id,omitempty"`
+	CreatedAt *time.Time `json:"createdAt,omitempty"`
+	Reason    string     `json:"reason"`
+	Expected  string     `json:"expected,omitempty"`
+	Actual    string     `json:"actual,omitempty"`
+}
+
+// VerifyResult 哈希链校验结果
+type VerifyResult struct {
+	OK                 bool        `json:"ok"`
+	From               *time.Time  `json:"from,omitempty"`
+	To                 *time.Time  `json:"to,omitempty"`
+	FirstSeq           int64       `json:"firstSeq"` // 校验的序号范围，覆盖时间范围内的所有记录
+	LastSeq            int64       `json:"lastSeq"`
+	Checked            int64       `json:"checked"`            // 已校验的记录数
+	Unchained          int64       `json:"unchained"`          // 时间范围内启用哈希链之前的历史记录数（不校验）
+	Checkpoints        int         `json:"checkpoints"`        // 已校验的检查点数
+	Archived           int64       `json:"archived"`           // 已归档删除、只校验链接连续性的记录数
+	SignaturesVerified bool        `json:"signaturesVerified"` // 未配置公钥时只比对检查点哈希，不验签
+	BrokenLink         *BrokenLink `json:"brokenLink,omitempty"`
+}
+
+// VerifyResponse 哈希链校验响应
+type VerifyResponse struct {
+	Code    int          `json:"code"`
+	Message string       `json:"message"`
+	Data    VerifyResult `json:"data"`
+}
+
+// PartitionInfo 一个分区的存储占用
+type PartitionInfo struct {
+	Name          string     `json:"name"`
+	Category      string     `json:"category"`
+	RangeFrom     *time.Time `json:"rangeFrom,omitempty"` // 为空表示兜底（default）分区
+	RangeTo       *time.Time `json:"rangeTo,omitempty"`
+	ExpiresAt     *time.Time `json:"expiresAt,omitempty"` // 到期后归档删除
+	EstimatedRows int64      `json:"estimatedRows"`       // 统计信息估算，ANALYZE 之前可能为 0
+	TableBytes    int64      `json:"tableBytes"`
+	IndexBytes    int64      `json:"indexBytes"`
+	TotalBytes    int64      `json:"totalBytes"`
+}
+
+// RetentionView 保留策略
+type RetentionView struct {
+	Category string `json:"category"`
+	Actions  string `json:"actions"`
+	Days     int    `json:"days"`
+}
+
+// StorageInfo 审计日志存储占用
+type StorageInfo struct {
+	Partitioned   bool            `json:"partitioned"`
+	TotalBytes    int64           `json:"totalBytes"`
+	EstimatedRows int64           `json:"estimatedRows"`
+	Partitions    []PartitionInfo `json:"partitions"`
+	Retention     []RetentionView `json:"retention"`
+	Archives      []Archive       `json:"archives"` // 最近的归档
+}
+
+// StorageResponse 存储占用响应
+type StorageResponse struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    StorageInfo `json:"data"`
 }
@@ -2,13 +2,38 @@ package audit
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Repository defines the persistence interface for audit logs.
 type Repository interface {
 	Create(ctx context.Context, log *AuditLog) error
+	// CreateBatch 批量写入并接入哈希链，ID 已存在的记录跳过（溢出日志回放可能重复写入）
+	CreateBatch(ctx context.Context, logs []*AuditLog) error
+	ListStaffAccess(ctx context.Context, username string, limit int) ([]AuditLog, error)
+	// Search 按条件查询，按时间倒序；after 不为空时只返回游标之后（更早）的记录
+	Search(ctx context.Context, q *Query, after *logCursor, limit int) ([]AuditLog, error)
+
+	// ChainHead 链尾的序号和哈希，尚无入链记录时为 0 和空串
+	ChainHead(ctx context.Context) (int64, string, error)
+	// SeqRange 时间范围内入链记录的最小、最大序号，以及未入链的历史记录数
+	SeqRange(ctx context.Context, from, to *time.Time) (int64, int64, int64, error)
+	// ListBySeq 序号在 [fromSeq, toSeq] 内的记录，按序号升序
+	ListBySeq(ctx context.Context, fromSeq, toSeq int64, limit int) ([]AuditLog, error)
+	// ListLinks 序号在 [fromSeq, toSeq] 内已归档记录的链接
+	ListLinks(ctx context.Context, fromSeq, toSeq int64) ([]ChainLink, error)
+	// CreateCheckpoint 写入检查点，同一序号已有检查点时跳过
+	CreateCheckpoint(ctx context.Context, cp *Checkpoint) error
+	LatestCheckpoint(ctx context.Context) (*Checkpoint, error)
+	// ListCheckpoints 序号在 [fromSeq, toSeq] 内的检查点
+	ListCheckpoints(ctx context.Context, fromSeq, toSeq int64) ([]Checkpoint, error)
 }
 
 type repository struct {
@@ -21,5 +46,248 @@ func NewRepository(db *gorm.DB) Repository {
 }
 
 func (r *repository) Create(ctx context.Context, log *AuditLog) error {
-	return r.db.WithContext(ctx).Create(log).Error
+	return r.CreateBatch(ctx, []*AuditLog{log})
+}
+
+// CreateBatch 在事务内持 advisory lock 读取链尾，为新记录依次分配序号和哈希后写入，
+// 多实例并发写入时序号连续、不分叉
+func (r *repository) CreateBatch(ctx context.Context, logs []*AuditLog) error {
+	if len(logs) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(logs))
+	for _, l := range logs {
+		if l.ID == "" {
+			l.ID = uuid.NewString()
+		}
+		ids = append(ids, l.ID)
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", chainLockKey).Error; err != nil {
+			return err
+		}
+
+		var existing []string
+		if err := tx.Model(&AuditLog{}).Where("id IN ?", ids).Pluck("id", &existing).Error; err != nil {
+			return err
+		}
+		skip := make(map[string]bool, len(existing))
+		for _, id := range existing {
+			skip[id] = true
+		}
+		pending := make([]*AuditLog, 0, len(logs))
+		for _, l := range logs {
+			if skip[l.ID] {
+				continue
+			}
+			skip[l.ID] = true
+			pending = append(pending, l)
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+
+		seq, hash, err := chainHead(tx)
+		if err != nil {
+			return err
+		}
+		chainEntries(pending, seq, hash)
+		return tx.Create(pending).Error
+	})
+}
+
+func (r *repository) ChainHead(ctx context.Context) (int64, string, error) {
+	return chainHead(r.db.WithContext(ctx))
+}
+
+// chainHead 链尾取记录表和归档链接中序号最大的一条（最新的记录也可能已被归档）
+func chainHead(db *gorm.DB) (int64, string, error) {
+	var last AuditLog
+	err := db.Select("seq", "hash").
+		Where("seq > 0").
+		Order("seq DESC").
+		Limit(1).
+		Take(&last).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, "", err
+	}
+	var link ChainLink
+	err = db.Select("seq", "hash").
+		Where("seq > ?", last.Seq).
+		Order("seq DESC").
+		Limit(1).
+		Take(&link).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return last.Seq, last.Hash, nil
+	}
+	if err != nil {
+		return 0, "", err
+	}
+	return link.Seq, link.Hash, nil
+}
+
+func (r *repository) SeqRange(ctx context.Context, from, to *time.Time) (int64, int64, int64, error) {
+	scope := func() *gorm.DB {
+		db := r.db.WithContext(ctx).Model(&AuditLog{})
+		if from != nil {
+			db = db.Where("created_at >= ?", *from)
+		}
+		if to != nil {
+			db = db.Where("created_at < ?", *to)
+		}
+		return db
+	}
+
+	var bounds struct {
+		MinSeq *int64
+		MaxSeq *int64
+	}
+	if err := scope().Select("MIN(seq) AS min_seq, MAX(seq) AS max_seq").Where("seq > 0").Scan(&bounds).Error; err != nil {
+		return 0, 0, 0, err
+	}
+	var unchained int64
+	if err := scope().Where("seq = 0").Count(&unchained).Error; err != nil {
+		return 0, 0, 0, err
+	}
+	if bounds.MinSeq == nil || bounds.MaxSeq == nil {
+		return 0, 0, unchained, nil
+	}
+	return *bounds.MinSeq, *bounds.MaxSeq, unchained, nil
+}
+
+func (r *repository) ListBySeq(ctx context.Context, fromSeq, toSeq int64, limit int) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := r.db.WithContext(ctx).
+		Where("seq >= ? AND seq <= ?", fromSeq, toSeq).
+		Order("seq ASC, id ASC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *repository) ListLinks(ctx context.Context, fromSeq, toSeq int64) ([]ChainLink, error) {
+	var links []ChainLink
+	err := r.db.WithContext(ctx).
+		Where("seq >= ? AND seq <= ?", fromSeq, toSeq).
+		Order("seq ASC").
+		Find(&links).Error
+	return links, err
+}
+
+func (r *repository) CreateCheckpoint(ctx context.Context, cp *Checkpoint) error {
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "seq"}}, DoNothing: true}).
+		Create(cp).Error
+}
+
+func (r *repository) LatestCheckpoint(ctx context.Context) (*Checkpoint, error) {
+	var cp Checkpoint
+	err := r.db.WithContext(ctx).Order("seq DESC").Limit(1).Take(&cp).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+func (r *repository) ListCheckpoints(ctx context.Context, fromSeq, toSeq int64) ([]Checkpoint, error) {
+	var cps []Checkpoint
+	err := r.db.WithContext(ctx).
+		Where("seq >= ? AND seq <= ?", fromSeq, toSeq).
+		Order("seq ASC").
+		Find(&cps).Error
+	return cps, err
+}
+
+// ListStaffAccess 员工对某用户账号的访问记录（管理端操作和代查看），按时间倒序
+func (r *repository) ListStaffAccess(ctx context.Context, username string, limit int) ([]AuditLog, error) {
+	var logs []AuditLog
+	err := r.db.WithContext(ctx).
+		Where("username = ? AND staff_username <> ''", username).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&logs).Error
+	return logs, err
+}
+
+func (r *repository) Search(ctx context.Context, q *Query, after *logCursor, limit int) ([]AuditLog, error) {
+	db := r.db.WithContext(ctx).Model(&AuditLog{})
+	for column, value := range map[string]string{
+		"username":       q.Username,
+		"customer_code":  q.CustomerCode,
+		"staff_username": q.StaffUsername,
+		"api_key_id":     q.APIKeyID,
+		"resource":       q.Resource,
+		"resource_id":    q.ResourceID,
+		"client_ip":      q.ClientIP,
+		"request_id":     q.RequestID,
+	} {
+		if value != "" {
+			db = db.Where(column+" = ?", value)
+		}
+	}
+	if q.Action != "" {
+		if prefix, ok := strings.CutSuffix(q.Action, "*"); ok {
+			db = db.Where("action LIKE ?", escapeLike(prefix)+"%")
+		} else {
+			db = db.Where("action = ?", q.Action)
+		}
+	}
+	if q.StatusCode != 0 {
+		db = db.Where("status_code = ?", q.StatusCode)
+	}
+	if q.From != nil {
+		db = db.Where("created_at >= ?", *q.From)
+	}
+	if q.To != nil {
+		db = db.Where("created_at < ?", *q.To)
+	}
+	if after != nil {
+		db = db.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+
+	var logs []AuditLog
+	err := db.Order("created_at DESC, id DESC").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+// escapeLike 转义 LIKE 通配符
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// logCursor 审计日志游标（created_at, id）
+type logCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func encodeLogCursor(l *AuditLog) string {
+	raw := l.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + l.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeLogCursor(cursor string) (*logCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &logCursor{CreatedAt: t, ID: parts[1]}, nil
+}
+
+// ReassignUsername 将 from 用户的审计记录归属到 to（账号合并时在调用方事务内执行）。
+// 哈希链覆盖的是 OriginalUsername，改 Username 不影响校验
+func ReassignUsername(tx *gorm.DB, from, to string) error {
+	return tx.Model(&AuditLog{}).Where("username = ?", from).Update("username", to).Error
 }
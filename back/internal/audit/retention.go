@@ -0,0 +1,122 @@
+package audit
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CategoryDefault 未匹配任何规则的记录的保留类别
+const CategoryDefault = "default"
+
+// categoryPattern 类别名用作分区表名的一部分，只允许小写字母和数字
+var categoryPattern = regexp.MustCompile(`^[a-z0-9]{1,20}$`)
+
+// RetentionRule 一个保留类别：匹配的 action 及保留时长
+type RetentionRule struct {
+	Category string
+	Filter   ActionFilter
+	Keep     time.Duration
+}
+
+// RetentionPolicy 按 action 分类的保留策略。记录写入时按规则顺序归入第一个匹配的类别
+// （之后修改规则只影响新记录的归类，修改保留时长对已有数据生效），未匹配的归入 default
+type RetentionPolicy struct {
+	Rules       []RetentionRule
+	DefaultKeep time.Duration
+}
+
+// ParseRetentionPolicy 解析保留规则，分号分隔，每条为 类别:天数:action 过滤规则，如
+// "auth:1095:auth.*,admin.*;read:90:*.list*,*.get*"
+func ParseRetentionPolicy(rules string, defaultDays int) (RetentionPolicy, error) {
+	if defaultDays <= 0 {
+		return RetentionPolicy{}, fmt.Errorf("默认保留天数必须大于 0: %d", defaultDays)
+	}
+	p := RetentionPolicy{DefaultKeep: days(defaultDays)}
+	seen := map[string]bool{CategoryDefault: true}
+	for _, rule := range strings.Split(rules, ";") {
+		rule = strings.TrimSpace(rule)
+		if rule == "" {
+			continue
+		}
+		parts := strings.SplitN(rule, ":", 3)
+		if len(parts) != 3 {
+			return RetentionPolicy{}, fmt.Errorf("保留规则格式应为 类别:天数:action: %s", rule)
+		}
+		category := strings.TrimSpace(parts[0])
+		if !categoryPattern.MatchString(category) || seen[category] {
+			return RetentionPolicy{}, fmt.Errorf("无效或重复的保留类别: %s", category)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err != nil || n <= 0 {
+			return RetentionPolicy{}, fmt.Errorf("类别 %s 的保留天数无效: %s", category, parts[1])
+		}
+		seen[category] = true
+		p.Rules = append(p.Rules, RetentionRule{
+			Category: category,
+			Filter:   ParseActionFilter(parts[2]),
+			Keep:     days(n),
+		})
+	}
+	return p, nil
+}
+
+func days(n int) time.Duration {
+	return time.Duration(n) * 24 * time.Hour
+}
+
+// Category 记录的保留类别
+func (p RetentionPolicy) Category(action string) string {
+	for _, r := range p.Rules {
+		if r.Filter.Match(action) {
+			return r.Category
+		}
+	}
+	return CategoryDefault
+}
+
+// Keep 类别的保留时长，已不在规则中的类别按默认时长
+func (p RetentionPolicy) Keep(category string) time.Duration {
+	for _, r := range p.Rules {
+		if r.Category == category {
+			return r.Keep
+		}
+	}
+	return p.DefaultKeep
+}
+
+// Categories 所有类别（含 default）
+func (p RetentionPolicy) Categories() []string {
+	categories := make([]string, 0, len(p.Rules)+1)
+	for _, r := range p.Rules {
+		categories = append(categories, r.Category)
+	}
+	return append(categories, CategoryDefault)
+}
+
+// Views 保留策略的展示形式
+func (p RetentionPolicy) Views() []RetentionView {
+	views := make([]RetentionView, 0, len(p.Rules)+1)
+	for _, r := range p.Rules {
+		views = append(views, RetentionView{Category: r.Category, Actions: r.Filter.String(), Days: int(r.Keep / (24 * time.Hour))})
+	}
+	return append(views, RetentionView{Category: CategoryDefault, Actions: "*", Days: int(p.DefaultKeep / (24 * time.Hour))})
+}
+
+// classifySQL 按规则归类的 SQL CASE 表达式（转换历史数据时使用）
+func (p RetentionPolicy) classifySQL() (string, []any) {
+	var b strings.Builder
+	var args []any
+	b.WriteString("CASE")
+	for _, r := range p.Rules {
+		cond, condArgs := r.Filter.sqlCondition("action")
+		b.WriteString(" WHEN " + cond + " THEN ?")
+		args = append(args, condArgs...)
+		args = append(args, r.Category)
+	}
+	b.WriteString(" ELSE ? END")
+	args = append(args, CategoryDefault)
+	return b.String(), args
+}
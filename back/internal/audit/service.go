@@ -2,55 +2,149 @@ package audit
 
 import (
 	"context"
-	"fmt"
+	"errors"
 	"sync"
+	"time"
 )
 
+const (
+	// exportBatch 导出时每次查询的条数
+	exportBatch = 1000
+	// MaxExportRows 单次导出的最大条数
+	MaxExportRows = 1000000
+)
+
+// ErrInvalidCursor 分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 // Service defines the audit logging interface.
 type Service interface {
+	// Log 提交一条审计记录，不阻塞；分发给数据库和匹配的其他输出目标，数据库队列满或停止后写入溢出日志
 	Log(entry *AuditLog)
+	// Start 回放上次遗留的溢出日志并启动后台批量写入
 	Start()
+	// Stop 停止接收并写完队列中的记录，可重复调用
 	Stop()
+	// Stats 写入管道的运行指标
+	Stats() Stats
+
+	// ListStaffAccess 员工访问某用户账号的记录，供用户自查
+	ListStaffAccess(ctx context.Context, username string, limit int) ([]AuditLog, error)
+	// Search 按条件查询（游标分页），返回当前页和下一页游标
+	Search(ctx context.Context, q *Query) ([]AuditLog, string, error)
+	// Export 按条件逐条回调满足条件的记录（时间倒序），最多 q.Limit 条（0 为 MaxExportRows），返回导出条数
+	Export(ctx context.Context, q *Query, fn func(*AuditLog) error) (int, error)
+	// Verify 校验时间范围内记录的哈希链和检查点，报告第一个断裂处
+	Verify(ctx context.Context, from, to *time.Time) (*VerifyResult, error)
 }
 
 type service struct {
 	repo Repository
-	ch   chan *AuditLog
-	wg   sync.WaitGroup
+	db   Sink
+	cfg  Config
+
+	// mu 保护 ch 的关闭：Log 持读锁发送，Stop 持写锁关闭，Stop 之后的 Log 不会向已关闭的 channel 发送
+	mu      sync.RWMutex
+	ch      chan *AuditLog
+	stopped bool
+	sinks   []*sinkWorker // 数据库之外的输出目标，与 ch 一起在 Stop 时关闭
+
+	writerDone chan struct{} // 写入协程退出（队列已写完）时关闭
+
+	journal *journal
+	stats   counters
+	wg      sync.WaitGroup
 }
 
 // NewService creates a new audit service with a buffered channel for async writes.
-func NewService(repo Repository) Service {
-	return &service{
+// cfg 的零值字段使用默认值
+func NewService(repo Repository, cfg Config) Service {
+	cfg = cfg.withDefaults()
+	s := &service{
 		repo: repo,
-		ch:   make(chan *AuditLog, 256),
+		db:   NewDatabaseSink(repo),
+		cfg:  cfg,
+		ch:   make(chan *AuditLog, cfg.BufferSize),
+
+		writerDone: make(chan struct{}),
+	}
+	if cfg.JournalPath != "" {
+		s.journal = newJournal(cfg.JournalPath, cfg.JournalMaxBytes)
 	}
+	for _, sc := range cfg.Sinks {
+		s.sinks = append(s.sinks, newSinkWorker(sc, cfg))
+	}
+	return s
 }
 
-// Start begins the background goroutine that drains the audit channel.
-func (s *service) Start() {
-	s.wg.Add(1)
-	go func() {
-		defer s.wg.Done()
-		for entry := range s.ch {
-			if err := s.repo.Create(context.Background(), entry); err != nil {
-				fmt.Printf("audit: failed to write log: %v\n", err)
-			}
-		}
-	}()
+// ListStaffAccess 员工访问某用户账号的记录，limit 默认 50、最大 200
+func (s *service) ListStaffAccess(ctx context.Context, username string, limit int) ([]AuditLog, error) {
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	return s.repo.ListStaffAccess(ctx, username, limit)
 }
 
-// Stop closes the channel and waits for all pending entries to be flushed.
-func (s *service) Stop() {
-	close(s.ch)
-	s.wg.Wait()
+// Search 按条件查询审计日志，limit 默认 50、最大 500
+func (s *service) Search(ctx context.Context, q *Query) ([]AuditLog, string, error) {
+	if q.Limit < 1 || q.Limit > 500 {
+		q.Limit = 50
+	}
+	var after *logCursor
+	if q.Cursor != "" {
+		c, err := decodeLogCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		after = c
+	}
+
+	// 多取一条判断是否还有下一页
+	logs, err := s.repo.Search(ctx, q, after, q.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	nextCursor := ""
+	if len(logs) > q.Limit {
+		logs = logs[:q.Limit]
+		nextCursor = encodeLogCursor(&logs[q.Limit-1])
+	}
+	return logs, nextCursor, nil
 }
 
-// Log sends an audit entry to the background writer. Non-blocking; drops if buffer full.
-func (s *service) Log(entry *AuditLog) {
-	select {
-	case s.ch <- entry:
-	default:
-		fmt.Println("audit: channel full, dropping log entry")
+// Export 分批查询后逐条回调，不把结果集整体放进内存
+func (s *service) Export(ctx context.Context, q *Query, fn func(*AuditLog) error) (int, error) {
+	limit := q.Limit
+	if limit < 1 || limit > MaxExportRows {
+		limit = MaxExportRows
+	}
+	var after *logCursor
+	if q.Cursor != "" {
+		c, err := decodeLogCursor(q.Cursor)
+		if err != nil {
+			return 0, err
+		}
+		after = c
+	}
+
+	count := 0
+	for count < limit {
+		batch := min(exportBatch, limit-count)
+		logs, err := s.repo.Search(ctx, q, after, batch)
+		if err != nil {
+			return count, err
+		}
+		for i := range logs {
+			if err := fn(&logs[i]); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if len(logs) < batch {
+			break
+		}
+		last := &logs[len(logs)-1]
+		after = &logCursor{CreatedAt: last.CreatedAt, ID: last.ID}
 	}
+	return count, nil
 }
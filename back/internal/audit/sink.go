@@ -0,0 +1,297 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Sink 审计记录的输出目标。Write 收到的是一批记录，返回错误时整批按配置重试
+type Sink interface {
+	Name() string
+	Write(ctx context.Context, entries []*AuditLog) error
+	Close() error
+}
+
+// databaseSink 写入 PostgreSQL（接入哈希链），是查询、导出和校验的数据来源
+type databaseSink struct {
+	repo Repository
+}
+
+// NewDatabaseSink 创建数据库输出目标
+func NewDatabaseSink(repo Repository) Sink {
+	return &databaseSink{repo: repo}
+}
+
+func (s *databaseSink) Name() string { return "database" }
+
+func (s *databaseSink) Write(ctx context.Context, entries []*AuditLog) error {
+	return s.repo.CreateBatch(ctx, entries)
+}
+
+func (s *databaseSink) Close() error { return nil }
+
+// ActionFilter 按 action 选择记录，逗号分隔：精确匹配，以 * 结尾按前缀匹配（如 auth.*），
+// 以 * 开头按后缀匹配（如 *.list），首尾都是 * 时按包含匹配；
+// 以 ! 开头表示排除；只有排除项时默认接收其余全部，为空时接收全部
+type ActionFilter struct {
+	include []string
+	exclude []string
+}
+
+// ParseActionFilter 解析过滤规则，如 "auth.*,admin.*" 或 "*,!supplier.list"
+func ParseActionFilter(s string) ActionFilter {
+	var f ActionFilter
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		if rest, ok := strings.CutPrefix(part, "!"); ok {
+			f.exclude = append(f.exclude, rest)
+		} else {
+			f.include = append(f.include, part)
+		}
+	}
+	return f
+}
+
+// Match 是否接收该 action
+func (f ActionFilter) Match(action string) bool {
+	for _, p := range f.exclude {
+		if matchAction(p, action) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, p := range f.include {
+		if matchAction(p, action) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f ActionFilter) String() string {
+	parts := append([]string(nil), f.include...)
+	for _, p := range f.exclude {
+		parts = append(parts, "!"+p)
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, ",")
+}
+
+func matchAction(pattern, action string) bool {
+	prefix, hasPrefix := strings.CutSuffix(pattern, "*")
+	inner, hasSuffix := strings.CutPrefix(prefix, "*")
+	switch {
+	case hasPrefix && hasSuffix:
+		return strings.Contains(action, inner)
+	case hasPrefix:
+		return strings.HasPrefix(action, prefix)
+	case hasSuffix:
+		return strings.HasSuffix(action, inner)
+	}
+	return pattern == action
+}
+
+// sqlCondition 与 Match 等价的 SQL 条件
+func (f ActionFilter) sqlCondition(column string) (string, []any) {
+	match := func(pattern string) (string, any) {
+		prefix, hasPrefix := strings.CutSuffix(pattern, "*")
+		inner, hasSuffix := strings.CutPrefix(prefix, "*")
+		switch {
+		case hasPrefix && hasSuffix:
+			return column + " LIKE ?", "%" + escapeLike(inner) + "%"
+		case hasPrefix:
+			return column + " LIKE ?", escapeLike(prefix) + "%"
+		case hasSuffix:
+			return column + " LIKE ?", "%" + escapeLike(inner)
+		}
+		return column + " = ?", pattern
+	}
+	join := func(patterns []string) (string, []any) {
+		conds := make([]string, 0, len(patterns))
+		args := make([]any, 0, len(patterns))
+		for _, p := range patterns {
+			cond, arg := match(p)
+			conds = append(conds, cond)
+			args = append(args, arg)
+		}
+		return "(" + strings.Join(conds, " OR ") + ")", args
+	}
+
+	cond, args := "TRUE", []any(nil)
+	if len(f.include) > 0 {
+		cond, args = join(f.include)
+	}
+	if len(f.exclude) > 0 {
+		exCond, exArgs := join(f.exclude)
+		cond = "(" + cond + " AND NOT " + exCond + ")"
+		args = append(args, exArgs...)
+	}
+	return cond, args
+}
+
+// SinkConfig 数据库之外的输出目标，各自独立的队列和写入协程，
+// 一个目标变慢或不可用只会丢弃它自己的记录，不影响数据库和其他目标
+type SinkConfig struct {
+	Sink          Sink
+	Filter        ActionFilter
+	BufferSize    int           // 队列长度，默认同 Config.BufferSize
+	BatchSize     int           // 每批最多条数，默认同 Config.BatchSize
+	FlushInterval time.Duration // 不满一批时最长等待，默认同 Config.FlushInterval
+}
+
+// SinkStats 单个输出目标的运行指标
+type SinkStats struct {
+	Name          string     `json:"name"`
+	Actions       string     `json:"actions"`       // 过滤规则
+	QueueDepth    int        `json:"queueDepth"`    // 队列中待写入的条数
+	QueueCapacity int        `json:"queueCapacity"` // 队列容量
+	Written       int64      `json:"written"`       // 已写出
+	Dropped       int64      `json:"dropped"`       // 队列满或已停止而丢弃
+	Failed        int64      `json:"failed"`        // 重试后仍写出失败而丢弃
+	Retries       int64      `json:"retries"`
+	LastFlushAt   *time.Time `json:"lastFlushAt,omitempty"`
+	LastError     string     `json:"lastError,omitempty"`
+}
+
+// sinkWorker 一个输出目标的队列和写入协程
+type sinkWorker struct {
+	cfg          SinkConfig
+	maxRetries   int
+	retryBackoff time.Duration
+	ch           chan *AuditLog
+
+	written   atomic.Int64
+	dropped   atomic.Int64
+	failed    atomic.Int64
+	retries   atomic.Int64
+	lastFlush atomic.Int64 // unix 毫秒
+	lastError atomic.Value // string
+}
+
+func newSinkWorker(sc SinkConfig, cfg Config) *sinkWorker {
+	if sc.BufferSize <= 0 {
+		sc.BufferSize = cfg.BufferSize
+	}
+	if sc.BatchSize <= 0 {
+		sc.BatchSize = cfg.BatchSize
+	}
+	if sc.FlushInterval <= 0 {
+		sc.FlushInterval = cfg.FlushInterval
+	}
+	return &sinkWorker{
+		cfg:          sc,
+		maxRetries:   cfg.MaxRetries,
+		retryBackoff: cfg.RetryBackoff,
+		ch:           make(chan *AuditLog, sc.BufferSize),
+	}
+}
+
+// offer 不阻塞地放入队列，调用方持有 service.mu 读锁保证 ch 未关闭。
+// 放入的是副本：数据库写入时会给原记录分配序号和哈希
+func (w *sinkWorker) offer(entry *AuditLog) {
+	if !w.cfg.Filter.Match(entry.Action) {
+		return
+	}
+	cp := *entry
+	select {
+	case w.ch <- &cp:
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// run 按批次写出，ch 关闭后写完剩余记录退出
+func (w *sinkWorker) run() {
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*AuditLog, 0, w.cfg.BatchSize)
+	for {
+		select {
+		case entry, ok := <-w.ch:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= w.cfg.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush 写出一批，失败时按退避重试，仍失败则丢弃这批并计数
+func (w *sinkWorker) flush(batch []*AuditLog) {
+	if len(batch) == 0 {
+		return
+	}
+	backoff := w.retryBackoff
+	for attempt := 0; ; attempt++ {
+		err := w.write(batch)
+		if err == nil {
+			w.written.Add(int64(len(batch)))
+			w.lastFlush.Store(time.Now().UnixMilli())
+			return
+		}
+		w.lastError.Store(err.Error())
+		if attempt >= w.maxRetries {
+			w.failed.Add(int64(len(batch)))
+			log.Printf("[AUDIT] 输出目标 %s 写出失败，丢弃 %d 条记录: %v", w.cfg.Sink.Name(), len(batch), err)
+			return
+		}
+		w.retries.Add(1)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// write 调用输出目标，panic 按错误处理，不影响其他目标
+func (w *sinkWorker) write(batch []*AuditLog) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return w.cfg.Sink.Write(ctx, batch)
+}
+
+func (w *sinkWorker) stats() SinkStats {
+	st := SinkStats{
+		Name:          w.cfg.Sink.Name(),
+		Actions:       w.cfg.Filter.String(),
+		QueueDepth:    len(w.ch),
+		QueueCapacity: cap(w.ch),
+		Written:       w.written.Load(),
+		Dropped:       w.dropped.Load(),
+		Failed:        w.failed.Load(),
+		Retries:       w.retries.Load(),
+	}
+	if ms := w.lastFlush.Load(); ms > 0 {
+		t := time.UnixMilli(ms)
+		st.LastFlushAt = &t
+	}
+	if v, ok := w.lastError.Load().(string); ok {
+		st.LastError = v
+	}
+	return st
+}
@@ -0,0 +1,84 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// webhookSink 按批 POST 到 HTTP 接口，请求体为 {"count": n, "events": [...]}。
+// 配置了密钥时带签名头，与接收 ERP 事件的签名方式相同：
+// X-Audit-Signature = hex(HMAC-SHA256(secret, X-Audit-Timestamp + "." + body))。
+// 失败重试整批，接收方按记录 id 去重
+type webhookSink struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+// NewWebhookSink 创建 HTTP 输出目标
+func NewWebhookSink(url, secret string, timeout time.Duration) (Sink, error) {
+	if url == "" {
+		return nil, errors.New("审计 webhook 地址不能为空")
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &webhookSink{
+		url:    url,
+		secret: secret,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (s *webhookSink) Name() string { return "webhook" }
+
+func (s *webhookSink) Write(ctx context.Context, entries []*AuditLog) error {
+	body, err := json.Marshal(map[string]any{
+		"count":  len(entries),
+		"events": entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.secret != "" {
+		ts := strconv.FormatInt(time.Now().Unix(), 10)
+		mac := hmac.New(sha256.New, []byte(s.secret))
+		mac.Write([]byte(ts + "."))
+		mac.Write(body)
+		req.Header.Set("X-Audit-Timestamp", ts)
+		req.Header.Set("X-Audit-Signature", hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("审计 webhook 返回 %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
+
+func (s *webhookSink) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
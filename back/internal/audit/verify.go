@@ -0,0 +1,218 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"sort"
+	"time"
+)
+
+// verifyBatch 校验时每次读取的记录数
+const verifyBatch = 1000
+
+// publicKeys 校验检查点可用的公钥，按指纹索引
+func (s *service) publicKeys() map[string]ed25519.PublicKey {
+	keys := make(map[string]ed25519.PublicKey)
+	if s.cfg.CheckpointKey != nil {
+		pub := s.cfg.CheckpointKey.Public().(ed25519.PublicKey)
+		keys[KeyID(pub)] = pub
+	}
+	for _, pub := range s.cfg.CheckpointPublicKeys {
+		keys[KeyID(pub)] = pub
+	}
+	return keys
+}
+
+// Verify 校验时间范围内的哈希链。
+// 溢出日志回放的记录时间较早而序号较大，因此按时间范围内记录的最小、最大序号确定校验区间，
+// 区间内的记录逐条校验序号连续、PrevHash 与上一条一致、内容与 Hash 一致，
+// 区间内的检查点校验签名以及与对应记录的 Hash 一致。
+// 未指定结束时间时，若已有检查点的序号超过链尾，说明链尾的记录被删除。
+// 按保留策略归档删除的记录用保留的链接（序号和哈希）校验连续性
+func (s *service) Verify(ctx context.Context, from, to *time.Time) (*VerifyResult, error) {
+	keys := s.publicKeys()
+	res := &VerifyResult{From: from, To: to, SignaturesVerified: len(keys) > 0}
+
+	minSeq, maxSeq, unchained, err := s.repo.SeqRange(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+	res.Unchained = unchained
+	if minSeq == 0 {
+		res.OK = true
+		return res, nil
+	}
+	res.FirstSeq, res.LastSeq = minSeq, maxSeq
+
+	checkpoints, err := s.repo.ListCheckpoints(ctx, minSeq, maxSeq)
+	if err != nil {
+		return nil, err
+	}
+	cpBySeq := make(map[int64]*Checkpoint, len(checkpoints))
+	for i := range checkpoints {
+		cp := &checkpoints[i]
+		if res.SignaturesVerified && !verifyCheckpoint(cp, keys) {
+			res.BrokenLink = &BrokenLink{Seq: cp.Seq, Reason: BreakCheckpointSignature, Actual: cp.KeyID}
+			return res, nil
+		}
+		cpBySeq[cp.Seq] = cp
+	}
+
+	if to == nil {
+		latest, err := s.repo.LatestCheckpoint(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if latest != nil && latest.Seq > maxSeq {
+			if res.SignaturesVerified && !verifyCheckpoint(latest, keys) {
+				res.BrokenLink = &BrokenLink{Seq: latest.Seq, Reason: BreakCheckpointSignature, Actual: latest.KeyID}
+			} else {
+				res.BrokenLink = &BrokenLink{Seq: maxSeq + 1, Reason: BreakMissing}
+			}
+			return res, nil
+		}
+	}
+
+	// 区间第一条的 PrevHash 取自上一条记录，上一条本身也要校验
+	prevHash := ""
+	if minSeq > 1 {
+		rows, archived, err := s.loadChain(ctx, minSeq-1, minSeq-1, 2)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case len(rows) == 0:
+			res.BrokenLink = &BrokenLink{Seq: minSeq - 1, Reason: BreakMissing}
+			return res, nil
+		case len(rows) > 1:
+			res.BrokenLink = brokenAt(&rows[1], BreakDuplicate, "", "")
+			return res, nil
+		}
+		if !archived[rows[0].Seq] {
+			if h := ComputeHash(&rows[0]); h != rows[0].Hash {
+				res.BrokenLink = brokenAt(&rows[0], BreakHash, h, rows[0].Hash)
+				return res, nil
+			}
+		}
+		prevHash = rows[0].Hash
+	}
+
+	expected := minSeq
+	lastID := ""
+	for expected <= maxSeq {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// 从上一批最后一条开始取，才能发现跨批次的重复序号
+		start := expected
+		if lastID != "" {
+			start = expected - 1
+		}
+		rows, archived, err := s.loadChain(ctx, start, maxSeq, verifyBatch)
+		if err != nil {
+			return nil, err
+		}
+		progressed := false
+		for i := range rows {
+			l := &rows[i]
+			if l.Seq == expected-1 && l.ID == lastID {
+				continue
+			}
+			progressed = true
+			switch {
+			case l.Seq < expected:
+				res.BrokenLink = brokenAt(l, BreakDuplicate, "", "")
+				return res, nil
+			case l.Seq > expected:
+				res.BrokenLink = &BrokenLink{Seq: expected, Reason: BreakMissing}
+				return res, nil
+			case l.PrevHash != prevHash:
+				res.BrokenLink = brokenAt(l, BreakPrevHash, prevHash, l.PrevHash)
+				return res, nil
+			}
+			// 已归档的记录只有链接，无法重算内容哈希
+			if archived[l.Seq] {
+				res.Archived++
+			} else if h := ComputeHash(l); h != l.Hash {
+				res.BrokenLink = brokenAt(l, BreakHash, h, l.Hash)
+				return res, nil
+			}
+			if cp, ok := cpBySeq[l.Seq]; ok {
+				if cp.Hash != l.Hash {
+					res.BrokenLink = brokenAt(l, BreakCheckpointHash, cp.Hash, l.Hash)
+					return res, nil
+				}
+				res.Checkpoints++
+			}
+			prevHash = l.Hash
+			lastID = l.ID
+			expected++
+			res.Checked++
+		}
+		if !progressed {
+			res.BrokenLink = &BrokenLink{Seq: expected, Reason: BreakMissing}
+			return res, nil
+		}
+	}
+
+	res.OK = true
+	return res, nil
+}
+
+// loadChain 读取 [fromSeq, toSeq] 内的链，记录表中没有的序号用归档链接补齐，
+// 返回按序号排列的记录（归档的只有序号、ID、时间和哈希）及其中归档的序号
+func (s *service) loadChain(ctx context.Context, fromSeq, toSeq int64, limit int) ([]AuditLog, map[int64]bool, error) {
+	rows, err := s.repo.ListBySeq(ctx, fromSeq, toSeq, limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 记录取满一批时，链接只补到这批的最后一个序号
+	hi := toSeq
+	if len(rows) >= limit {
+		hi = rows[len(rows)-1].Seq
+	}
+	links, err := s.repo.ListLinks(ctx, fromSeq, hi)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	present := make(map[int64]bool, len(rows))
+	for i := range rows {
+		present[rows[i].Seq] = true
+	}
+	archived := make(map[int64]bool)
+	for _, link := range links {
+		if present[link.Seq] {
+			continue
+		}
+		archived[link.Seq] = true
+		rows = append(rows, AuditLog{
+			ID:        link.ID,
+			CreatedAt: link.CreatedAt,
+			Seq:       link.Seq,
+			PrevHash:  link.PrevHash,
+			Hash:      link.Hash,
+		})
+	}
+	if len(archived) > 0 {
+		sort.SliceStable(rows, func(i, j int) bool {
+			if rows[i].Seq != rows[j].Seq {
+				return rows[i].Seq < rows[j].Seq
+			}
+			return rows[i].ID < rows[j].ID
+		})
+	}
+	return rows, archived, nil
+}
+
+func brokenAt(l *AuditLog, reason, expected, actual string) *BrokenLink {
+	createdAt := l.CreatedAt
+	return &BrokenLink{
+		Seq:       l.Seq,
+		ID:        l.ID,
+		CreatedAt: &createdAt,
+		Reason:    reason,
+		Expected:  expected,
+		Actual:    actual,
+	}
+}
@@ -0,0 +1,426 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"sort"
+	"testing"
+	"time"
+)
+
+// fakeChainRepo 内存中的记录表、归档链接和检查点，只实现 Verify 用到的方法
+type fakeChainRepo struct {
+	Repository
+
+	logs        []AuditLog
+	links       []ChainLink
+	checkpoints []Checkpoint
+}
+
+func (r *fakeChainRepo) SeqRange(ctx context.Context, from, to *time.Time) (int64, int64, int64, error) {
+	var minSeq, maxSeq, unchained int64
+	for _, l := range r.logs {
+		if (from != nil && l.CreatedAt.Before(*from)) || (to != nil && !l.CreatedAt.Before(*to)) {
+			continue
+		}
+		if l.Seq == 0 {
+			unchained++
+			continue
+		}
+		if minSeq == 0 || l.Seq < minSeq {
+			minSeq = l.Seq
+		}
+		maxSeq = max(maxSeq, l.Seq)
+	}
+	return minSeq, maxSeq, unchained, nil
+}
+
+func (r *fakeChainRepo) ListBySeq(ctx context.Context, fromSeq, toSeq int64, limit int) ([]AuditLog, error) {
+	var out []AuditLog
+	for _, l := range r.logs {
+		if l.Seq >= fromSeq && l.Seq <= toSeq {
+			out = append(out, l)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Seq != out[j].Seq {
+			return out[i].Seq < out[j].Seq
+		}
+		return out[i].ID < out[j].ID
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (r *fakeChainRepo) ListLinks(ctx context.Context, fromSeq, toSeq int64) ([]ChainLink, error) {
+	var out []ChainLink
+	for _, link := range r.links {
+		if link.Seq >= fromSeq && link.Seq <= toSeq {
+			out = append(out, link)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Seq < out[j].Seq })
+	return out, nil
+}
+
+func (r *fakeChainRepo) LatestCheckpoint(ctx context.Context) (*Checkpoint, error) {
+	var latest *Checkpoint
+	for i := range r.checkpoints {
+		if latest == nil || r.checkpoints[i].Seq > latest.Seq {
+			latest = &r.checkpoints[i]
+		}
+	}
+	return latest, nil
+}
+
+func (r *fakeChainRepo) ListCheckpoints(ctx context.Context, fromSeq, toSeq int64) ([]Checkpoint, error) {
+	var out []Checkpoint
+	for _, cp := range r.checkpoints {
+		if cp.Seq >= fromSeq && cp.Seq <= toSeq {
+			out = append(out, cp)
+		}
+	}
+	return out, nil
+}
+
+// index 序号为 seq 的记录下标
+func (r *fakeChainRepo) index(seq int64) int {
+	for i := range r.logs {
+		if r.logs[i].Seq == seq {
+			return i
+		}
+	}
+	panic("no log with seq")
+}
+
+func (r *fakeChainRepo) remove(seq int64) {
+	i := r.index(seq)
+	r.logs = append(r.logs[:i], r.logs[i+1:]...)
+}
+
+// archive 模拟分区归档：把 [fromSeq, toSeq] 的记录移出记录表，只保留链接
+func (r *fakeChainRepo) archive(fromSeq, toSeq int64) {
+	for seq := fromSeq; seq <= toSeq; seq++ {
+		l := r.logs[r.index(seq)]
+		r.links = append(r.links, ChainLink{Seq: l.Seq, ID: l.ID, CreatedAt: l.CreatedAt, PrevHash: l.PrevHash, Hash: l.Hash})
+		r.remove(seq)
+	}
+}
+
+// rechain 修改 seq 处的记录后重算它及之后所有记录的哈希（整段改写）
+func (r *fakeChainRepo) rechain(seq int64, mutate func(*AuditLog)) {
+	mutate(&r.logs[r.index(seq)])
+	prev := ""
+	if seq > 1 {
+		prev = r.logs[r.index(seq-1)].Hash
+	}
+	for s := seq; s <= int64(len(r.logs)); s++ {
+		l := &r.logs[r.index(s)]
+		l.PrevHash = prev
+		l.Hash = ComputeHash(l)
+		prev = l.Hash
+	}
+}
+
+var (
+	testChainStart = time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	testSigningKey = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+)
+
+// newFakeChain 生成 n 条完整的链
+func newFakeChain(n int) *fakeChainRepo {
+	entries := make([]*AuditLog, n)
+	for i := range entries {
+		entries[i] = testEntry(i+1, testChainStart.Add(time.Duration(i)*time.Minute))
+	}
+	chainEntries(entries, 0, "")
+	r := &fakeChainRepo{}
+	for _, e := range entries {
+		r.logs = append(r.logs, *e)
+	}
+	return r
+}
+
+// signCheckpoint 用 key 为 (seq, hash) 签发检查点
+func signCheckpoint(key ed25519.PrivateKey, seq int64, hash string) Checkpoint {
+	cp := Checkpoint{
+		Seq:       seq,
+		Hash:      hash,
+		KeyID:     KeyID(key.Public().(ed25519.PublicKey)),
+		CreatedAt: testChainStart,
+	}
+	cp.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, checkpointMessage(&cp)))
+	return cp
+}
+
+func (r *fakeChainRepo) addCheckpoint(seq int64) {
+	r.checkpoints = append(r.checkpoints, signCheckpoint(testSigningKey, seq, r.logs[r.index(seq)].Hash))
+}
+
+func TestVerifyBrokenLinks(t *testing.T) {
+	const n = 10
+	tests := []struct {
+		name       string
+		setup      func(r *fakeChainRepo)
+		from       *time.Time
+		wantReason string // 为空表示校验通过
+		wantSeq    int64
+		wantID     string
+	}{
+		{
+			name:  "完整的链",
+			setup: func(r *fakeChainRepo) { r.addCheckpoint(5) },
+		},
+		{
+			name:       "中间记录被删除",
+			setup:      func(r *fakeChainRepo) { r.remove(4) },
+			wantReason: BreakMissing,
+			wantSeq:    4,
+		},
+		{
+			name: "链尾记录被删除，检查点序号超过链尾",
+			setup: func(r *fakeChainRepo) {
+				r.addCheckpoint(n)
+				r.remove(n)
+			},
+			wantReason: BreakMissing,
+			wantSeq:    n,
+		},
+		{
+			name:       "时间范围前一条被删除",
+			setup:      func(r *fakeChainRepo) { r.remove(3) },
+			from:       timePtr(testChainStart.Add(3 * time.Minute)),
+			wantReason: BreakMissing,
+			wantSeq:    3,
+		},
+		{
+			name: "插入重复序号",
+			setup: func(r *fakeChainRepo) {
+				dup := r.logs[r.index(6)]
+				dup.ID = "ffffffff-0000-0000-0000-000000000006"
+				dup.Hash = ComputeHash(&dup)
+				r.logs = append(r.logs, dup)
+			},
+			wantReason: BreakDuplicate,
+			wantSeq:    6,
+			wantID:     "ffffffff-0000-0000-0000-000000000006",
+		},
+		{
+			name: "PrevHash 被改并重算本条哈希",
+			setup: func(r *fakeChainRepo) {
+				l := &r.logs[r.index(5)]
+				l.PrevHash = "forged"
+				l.Hash = ComputeHash(l)
+			},
+			wantReason: BreakPrevHash,
+			wantSeq:    5,
+		},
+		{
+			name:       "记录内容被修改",
+			setup:      func(r *fakeChainRepo) { r.logs[r.index(7)].Action = "user.delete" },
+			wantReason: BreakHash,
+			wantSeq:    7,
+		},
+		{
+			name: "整段改写并重算哈希",
+			setup: func(r *fakeChainRepo) {
+				r.addCheckpoint(6)
+				r.rechain(3, func(l *AuditLog) { l.Action = "user.delete" })
+			},
+			wantReason: BreakCheckpointHash,
+			wantSeq:    6,
+		},
+		{
+			name: "检查点签名无效",
+			setup: func(r *fakeChainRepo) {
+				r.addCheckpoint(5)
+				r.checkpoints[0].Hash = r.logs[r.index(6)].Hash
+			},
+			wantReason: BreakCheckpointSignature,
+			wantSeq:    5,
+		},
+		{
+			name: "检查点签名公钥未知",
+			setup: func(r *fakeChainRepo) {
+				other := ed25519.NewKeyFromSeed(append(make([]byte, ed25519.SeedSize-1), 1))
+				r.checkpoints = append(r.checkpoints, signCheckpoint(other, 5, r.logs[r.index(5)].Hash))
+			},
+			wantReason: BreakCheckpointSignature,
+			wantSeq:    5,
+		},
+		{
+			name: "链尾之后的检查点签名无效",
+			setup: func(r *fakeChainRepo) {
+				r.addCheckpoint(n)
+				r.checkpoints[0].Signature = base64.StdEncoding.EncodeToString(make([]byte, ed25519.SignatureSize))
+				r.remove(n)
+			},
+			wantReason: BreakCheckpointSignature,
+			wantSeq:    n,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeChain(n)
+			tt.setup(repo)
+			s := &service{repo: repo, cfg: Config{CheckpointKey: testSigningKey}}
+
+			res, err := s.Verify(context.Background(), tt.from, nil)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if tt.wantReason == "" {
+				if !res.OK || res.BrokenLink != nil {
+					t.Fatalf("校验失败: %+v", res.BrokenLink)
+				}
+				if res.Checked != n || res.Checkpoints != len(repo.checkpoints) {
+					t.Fatalf("checked=%d checkpoints=%d, want %d/%d", res.Checked, res.Checkpoints, n, len(repo.checkpoints))
+				}
+				return
+			}
+			if res.OK || res.BrokenLink == nil {
+				t.Fatalf("应校验失败，得到 %+v", res)
+			}
+			if res.BrokenLink.Reason != tt.wantReason || res.BrokenLink.Seq != tt.wantSeq {
+				t.Fatalf("broken = %s@%d, want %s@%d", res.BrokenLink.Reason, res.BrokenLink.Seq, tt.wantReason, tt.wantSeq)
+			}
+			if tt.wantID != "" && res.BrokenLink.ID != tt.wantID {
+				t.Fatalf("broken id = %s, want %s", res.BrokenLink.ID, tt.wantID)
+			}
+		})
+	}
+}
+
+func TestVerifyAcrossArchivedLinks(t *testing.T) {
+	tests := []struct {
+		name         string
+		n            int
+		setup        func(r *fakeChainRepo)
+		from         *time.Time
+		wantReason   string // 为空表示校验通过
+		wantSeq      int64
+		wantArchived int64
+	}{
+		{
+			name:         "中间一段已归档",
+			n:            10,
+			setup:        func(r *fakeChainRepo) { r.archive(4, 6) },
+			wantArchived: 3,
+		},
+		{
+			name: "检查点落在归档链接上",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.addCheckpoint(5)
+				r.archive(4, 6)
+			},
+			wantArchived: 3,
+		},
+		{
+			// 记录表中最早的记录之前的链接不在校验区间内，只用来核对区间第一条的 PrevHash
+			name:         "链首已归档",
+			n:            10,
+			setup:        func(r *fakeChainRepo) { r.archive(1, 4) },
+			wantArchived: 0,
+		},
+		{
+			name: "链首已归档，区间前一条链接的哈希被改",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(1, 4)
+				r.links[3].Hash = "forged"
+			},
+			wantReason: BreakPrevHash,
+			wantSeq:    5,
+		},
+		{
+			name: "链首已归档，区间前一条链接缺失",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(1, 4)
+				r.links = r.links[:3]
+			},
+			wantReason: BreakMissing,
+			wantSeq:    4,
+		},
+		{
+			name:         "归档段跨越校验批次",
+			n:            verifyBatch + 300,
+			setup:        func(r *fakeChainRepo) { r.archive(verifyBatch-100, verifyBatch+100) },
+			wantArchived: 201,
+		},
+		{
+			name: "归档链接缺失",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(4, 6)
+				r.links = append(r.links[:1], r.links[2:]...)
+			},
+			wantReason: BreakMissing,
+			wantSeq:    5,
+		},
+		{
+			name: "归档链接的哈希被改",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(4, 6)
+				r.links[2].Hash = "forged"
+			},
+			wantReason: BreakPrevHash,
+			wantSeq:    7,
+		},
+		{
+			name: "归档链接的 PrevHash 被改",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(4, 6)
+				r.links[1].PrevHash = "forged"
+			},
+			wantReason: BreakPrevHash,
+			wantSeq:    5,
+		},
+		{
+			name: "归档后记录表里仍有同序号记录",
+			n:    10,
+			setup: func(r *fakeChainRepo) {
+				r.archive(4, 6)
+				r.links = append(r.links, ChainLink{Seq: 8, ID: "ffffffff-0000-0000-0000-000000000008", Hash: "x"})
+			},
+			wantArchived: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeChain(tt.n)
+			tt.setup(repo)
+			s := &service{repo: repo, cfg: Config{CheckpointKey: testSigningKey}}
+
+			res, err := s.Verify(context.Background(), tt.from, nil)
+			if err != nil {
+				t.Fatalf("Verify: %v", err)
+			}
+			if tt.wantReason != "" {
+				if res.BrokenLink == nil || res.BrokenLink.Reason != tt.wantReason || res.BrokenLink.Seq != tt.wantSeq {
+					t.Fatalf("broken = %+v, want %s@%d", res.BrokenLink, tt.wantReason, tt.wantSeq)
+				}
+				return
+			}
+			if !res.OK || res.BrokenLink != nil {
+				t.Fatalf("校验失败: %+v", res.BrokenLink)
+			}
+			if res.Archived != tt.wantArchived {
+				t.Fatalf("archived = %d, want %d", res.Archived, tt.wantArchived)
+			}
+			if res.Checkpoints != len(repo.checkpoints) {
+				t.Fatalf("checkpoints = %d, want %d", res.Checkpoints, len(repo.checkpoints))
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
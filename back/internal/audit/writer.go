@@ -0,0 +1,337 @@
+package audit
+
+import (
+	"context"
+	"crypto/ed25519"
+	"database/sql/driver"
+	"errors"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config 审计写入管道配置
+type Config struct {
+	BufferSize      int           // 内存队列长度，默认 4096
+	BatchSize       int           // 每批最多写入条数，默认 200
+	FlushInterval   time.Duration // 不满一批时最长等待，默认 1s
+	MaxRetries      int           // 数据库瞬时错误的重试次数，默认 3
+	RetryBackoff    time.Duration // 重试退避基数（翻倍），默认 200ms
+	JournalPath     string        // 溢出日志路径，为空时队列满或数据库不可用会丢弃记录
+	JournalMaxBytes int64         // 溢出日志上限，超过后丢弃，默认 512MB
+
+	CheckpointKey        ed25519.PrivateKey  // 检查点签名私钥，为空时不签发检查点
+	CheckpointPublicKeys []ed25519.PublicKey // 校验检查点的其他公钥（密钥轮换前的旧公钥）
+	CheckpointInterval   time.Duration       // 签发检查点的间隔，默认 1h
+
+	DatabaseDisabled bool         // 不写数据库，只输出到 Sinks（查询、导出和校验只覆盖已有数据）
+	DatabaseFilter   ActionFilter // 写入数据库的 action，零值为全部
+	Sinks            []SinkConfig // 数据库之外的输出目标
+
+	Retention RetentionPolicy // 写入数据库时按策略归入保留类别
+}
+
+func (c Config) withDefaults() Config {
+	if c.BufferSize <= 0 {
+		c.BufferSize = 4096
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 200
+	}
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+	if c.MaxRetries < 0 {
+		c.MaxRetries = 0
+	} else if c.MaxRetries == 0 {
+		c.MaxRetries = 3
+	}
+	if c.RetryBackoff <= 0 {
+		c.RetryBackoff = 200 * time.Millisecond
+	}
+	if c.JournalMaxBytes <= 0 {
+		c.JournalMaxBytes = 512 << 20
+	}
+	if c.CheckpointInterval <= 0 {
+		c.CheckpointInterval = time.Hour
+	}
+	return c
+}
+
+// replayInterval 数据库恢复后回放溢出日志的检查间隔
+const replayInterval = time.Minute
+
+// counters 管道计数，原子读写
+type counters struct {
+	written  atomic.Int64
+	spilled  atomic.Int64
+	replayed atomic.Int64
+	dropped  atomic.Int64
+	failed   atomic.Int64
+	batches  atomic.Int64
+	retries  atomic.Int64
+
+	lastFlush atomic.Int64 // unix 毫秒
+	lastError atomic.Value // string
+}
+
+// Log 提交一条审计记录，分发给数据库和匹配的其他输出目标。
+// 数据库队列满、或 Stop 之后，直接写入溢出日志（未配置时丢弃并计数）；其他目标队列满时丢弃
+func (s *service) Log(entry *AuditLog) {
+	// 先分配 ID 和时间，各输出目标收到的同一条记录一致，便于对账去重
+	if entry.ID == "" {
+		entry.ID = uuid.NewString()
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = truncateTime(entry.CreatedAt)
+
+	s.mu.RLock()
+	for _, w := range s.sinks {
+		if s.stopped {
+			w.dropped.Add(1)
+			continue
+		}
+		w.offer(entry)
+	}
+	if s.cfg.DatabaseDisabled || !s.cfg.DatabaseFilter.Match(entry.Action) {
+		s.mu.RUnlock()
+		return
+	}
+	if s.stopped {
+		s.mu.RUnlock()
+		s.spill([]*AuditLog{entry}, "service stopped")
+		return
+	}
+	select {
+	case s.ch <- entry:
+		s.mu.RUnlock()
+	default:
+		s.mu.RUnlock()
+		s.spill([]*AuditLog{entry}, "queue full")
+	}
+}
+
+// Start 先回放上次遗留的溢出日志，再按批次写入队列中的记录；配置了签名私钥时定期签发检查点
+func (s *service) Start() {
+	if s.cfg.CheckpointKey != nil {
+		s.wg.Add(1)
+		go s.runCheckpoints()
+	}
+	for _, w := range s.sinks {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			w.run()
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer close(s.writerDone)
+		s.replayJournal()
+
+		flushTicker := time.NewTicker(s.cfg.FlushInterval)
+		defer flushTicker.Stop()
+		replayTicker := time.NewTicker(replayInterval)
+		defer replayTicker.Stop()
+
+		batch := make([]*AuditLog, 0, s.cfg.BatchSize)
+		for {
+			select {
+			case entry, ok := <-s.ch:
+				if !ok {
+					s.flush(batch)
+					return
+				}
+				batch = append(batch, entry)
+				if len(batch) >= s.cfg.BatchSize {
+					s.flush(batch)
+					batch = batch[:0]
+				}
+			case <-flushTicker.C:
+				if len(batch) > 0 {
+					s.flush(batch)
+					batch = batch[:0]
+				}
+			case <-replayTicker.C:
+				s.replayJournal()
+			}
+		}
+	}()
+}
+
+// Stop closes the channel and waits for all pending entries to be flushed.
+func (s *service) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	close(s.ch)
+	for _, w := range s.sinks {
+		close(w.ch)
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	for _, w := range s.sinks {
+		if err := w.cfg.Sink.Close(); err != nil {
+			log.Printf("[AUDIT] 关闭输出目标 %s 失败: %v", w.cfg.Sink.Name(), err)
+		}
+	}
+}
+
+// Stats 写入管道的运行指标
+func (s *service) Stats() Stats {
+	st := Stats{
+		QueueDepth:    len(s.ch),
+		QueueCapacity: cap(s.ch),
+		Written:       s.stats.written.Load(),
+		Spilled:       s.stats.spilled.Load(),
+		Replayed:      s.stats.replayed.Load(),
+		Dropped:       s.stats.dropped.Load(),
+		Failed:        s.stats.failed.Load(),
+		Batches:       s.stats.batches.Load(),
+		Retries:       s.stats.retries.Load(),
+	}
+	if ms := s.stats.lastFlush.Load(); ms > 0 {
+		t := time.UnixMilli(ms)
+		st.LastFlushAt = &t
+	}
+	if v, ok := s.stats.lastError.Load().(string); ok {
+		st.LastError = v
+	}
+	if s.journal != nil {
+		st.JournalBytes = s.journal.size()
+	}
+	for _, w := range s.sinks {
+		st.Sinks = append(st.Sinks, w.stats())
+	}
+	return st
+}
+
+// flush 写入一批记录，数据库不可用时整批溢出到日志
+func (s *service) flush(batch []*AuditLog) {
+	if err := s.persist(batch); err != nil {
+		s.spill(batch, err.Error())
+	}
+}
+
+// persist 写入一批记录：瞬时错误按退避重试，仍失败时返回错误由调用方保留这批记录；
+// 非瞬时错误（数据不合法等）逐条重写，只丢弃写不进去的那几条。
+// 写入前预先生成 ID，同一批记录重复写入（溢出后回放）时按主键去重
+func (s *service) persist(batch []*AuditLog) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	for _, e := range batch {
+		if e.ID == "" {
+			e.ID = uuid.NewString()
+		}
+		// 每次写入时重新归类：溢出日志回放的记录可能是修改保留规则之前产生的
+		e.Category = s.cfg.Retention.Category(e.Action)
+	}
+
+	err := s.writeWithRetry(batch)
+	if err == nil {
+		s.stats.written.Add(int64(len(batch)))
+		return nil
+	}
+	if isTransient(err) {
+		return err
+	}
+	for _, entry := range batch {
+		if err := s.writeWithRetry([]*AuditLog{entry}); err != nil {
+			if isTransient(err) {
+				return err
+			}
+			s.stats.failed.Add(1)
+			log.Printf("[AUDIT] 记录无法写入，已丢弃: action=%s path=%s err=%v", entry.Action, entry.Path, err)
+			continue
+		}
+		s.stats.written.Add(1)
+	}
+	return nil
+}
+
+// writeWithRetry 写入一批记录，瞬时错误时重试
+func (s *service) writeWithRetry(batch []*AuditLog) error {
+	backoff := s.cfg.RetryBackoff
+	var err error
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		err = s.db.Write(ctx, batch)
+		cancel()
+		s.stats.batches.Add(1)
+		if err == nil {
+			s.stats.lastFlush.Store(time.Now().UnixMilli())
+			return nil
+		}
+		s.stats.lastError.Store(err.Error())
+		if !isTransient(err) || attempt >= s.cfg.MaxRetries {
+			return err
+		}
+		s.stats.retries.Add(1)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// spill 写入溢出日志，之后由 replayJournal 回放
+func (s *service) spill(entries []*AuditLog, reason string) {
+	if s.journal == nil {
+		s.stats.dropped.Add(int64(len(entries)))
+		log.Printf("[AUDIT] 未配置溢出日志，丢弃 %d 条记录: %s", len(entries), reason)
+		return
+	}
+	if err := s.journal.append(entries); err != nil {
+		s.stats.dropped.Add(int64(len(entries)))
+		log.Printf("[AUDIT] 写入溢出日志失败，丢弃 %d 条记录: %v（原因: %s）", len(entries), err, reason)
+		return
+	}
+	s.stats.spilled.Add(int64(len(entries)))
+}
+
+// replayJournal 把溢出日志中的记录写回数据库，数据库仍不可用时保留剩余部分等下次回放
+func (s *service) replayJournal() {
+	if s.journal == nil {
+		return
+	}
+	n, err := s.journal.replay(s.cfg.BatchSize, s.persist)
+	if n > 0 {
+		s.stats.replayed.Add(int64(n))
+		log.Printf("[AUDIT] 已从溢出日志回放 %d 条记录", n)
+	}
+	if err != nil {
+		log.Printf("[AUDIT] 回放溢出日志中断: %v", err)
+	}
+}
+
+// isTransient 是否为可重试的数据库错误：连接类错误、超时，以及
+// SQLSTATE 08（连接异常）、40（事务回滚/死锁）、53（资源不足）、57（管理员干预/关库）
+func isTransient(err error) bool {
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	var pgErr interface{ SQLState() string }
+	if errors.As(err, &pgErr) && len(pgErr.SQLState()) >= 2 {
+		switch pgErr.SQLState()[:2] {
+		case "08", "40", "53", "57":
+			return true
+		}
+		return false
+	}
+	// 非 SQL 错误（如连接池拿不到连接）按瞬时处理
+	return true
+}
@@ -5,11 +5,14 @@ import (
 	"back/pkg/crypto"
 	"back/pkg/jwt"
 	"back/pkg/middleware"
+	"back/pkg/wechat"
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"math/rand"
 	"net/http"
 	"os"
@@ -31,12 +34,24 @@ type codeEntry struct {
 	ExpiresAt time.Time
 }
 
+// 微信端类型（与内部系统 BC_Customer_* 的 Type 参数一致）
+const (
+	wechatTypeOfficialAccount = "0" // 公众号
+	wechatTypeMiniProgram     = "1" // 小程序
+)
+
+// sessionKeyTTL 微信 session_key 在本地保留的时长，仅用于短时间内解密小程序数据
+const sessionKeyTTL = 30 * time.Minute
+
 // Handler 认证处理器
 type Handler struct {
 	jwtService       *jwt.JWTService
 	userService      user.Service
 	getInternalToken func() (string, error) // 获取内部系统 token
 	internalAPIURL   string                 // 内部系统基础 URL
+	wechat           *wechat.Client         // 小程序接口客户端
+	oa               OfficialAccountConfig  // 公众号网页授权配置
+	qr               QRLoginConfig          // 网页扫码登录配置
 }
 
 // NewHandler 创建新的 handler 实例
@@ -45,8 +60,11 @@ func NewHandler(
 	userService user.Service,
 	getInternalToken func() (string, error),
 	internalAPIURL string,
+	wechatClient *wechat.Client,
+	oa OfficialAccountConfig,
+	qr QRLoginConfig,
 ) *Handler {
-	// 启动后台清理过期验证码（每2分钟执行一次）
+	// 启动后台清理过期验证码、网页授权 state 和扫码登录票据（每2分钟执行一次）
 	go func() {
 		ticker := time.NewTicker(2 * time.Minute)
 		defer ticker.Stop()
@@ -59,6 +77,11 @@ func NewHandler(
 				}
 			}
 			codeStoreLock.Unlock()
+			cleanupOAuthStates(now)
+			// 多个实例都会清理，删除已过期的行不冲突
+			if err := qr.Store.DeleteExpired(context.Background(), now.Add(-time.Minute)); err != nil {
+				log.Printf("[QR-LOGIN] 清理过期票据失败: %v", err)
+			}
 		}
 	}()
 
@@ -67,6 +90,9 @@ func NewHandler(
 		userService:      userService,
 		getInternalToken: getInternalToken,
 		internalAPIURL:   internalAPIURL,
+		wechat:           wechatClient,
+		oa:               oa,
+		qr:               qr,
 	}
 }
 
@@ -216,7 +242,7 @@ func (h *Handler) VerifyCode(c *gin.Context) {
 	_ = h.userService.UpdateLastLogin(ctx, u.ID)
 
 	// 生成 JWT token（只使用 username）
-	token, err := h.jwtService.GenerateToken(u.Username)
+	token, err := h.issueToken(u)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
@@ -273,8 +299,9 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 
 	tokenString := parts[1]
 
-	// 先尝试正常验证（token 还没过期，直接返回）
-	if claims, err := h.jwtService.ValidateToken(tokenString); err == nil {
+	// 先尝试正常验证（token 还没过期且会话有效，直接返回）
+	if claims, err := h.jwtService.ValidateToken(tokenString); err == nil &&
+		h.userService.CheckSession(c.Request.Context(), claims.Username, issuedAt(claims)) == nil {
 		c.JSON(http.StatusOK, RefreshTokenResponse{
 			Code:    http.StatusOK,
 			Message: "token 仍然有效",
@@ -306,8 +333,33 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 		}
 	}
 
-	// 签发新 token
-	newToken, err := h.jwtService.GenerateToken(claims.Username)
+	// 已停用或会话已作废的用户不允许刷新
+	if err := h.userService.CheckSession(c.Request.Context(), claims.Username, issuedAt(claims)); err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "登录已失效，请重新登录",
+		})
+		return
+	}
+
+	u, err := h.userService.GetUserByUsername(c.Request.Context(), claims.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户不存在，请重新登录",
+		})
+		return
+	}
+
+	// 新 token 沿用本会话所代表的供应商（不受其他会话切换影响），绑定前签发的 token 取用户的默认供应商
+	customerCode := claims.CustomerCode
+	if customerCode == "" {
+		customerCode = u.CustomerCode
+	}
+	newToken, err := h.jwtService.GenerateTokenWithClaims(&jwt.Claims{
+		Username:     u.Username,
+		CustomerCode: customerCode,
+	})
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
@@ -340,7 +392,7 @@ func (h *Handler) RefreshToken(c *gin.Context) {
 // @Success 200 {object} map[string]interface{}
 // @Failure 400 {object} ErrorResponse
 // @Failure 502 {object} ErrorResponse
-// @Router /api/v1/internal/login [post]
+// @Router /api/v1/admin/internal/login [post]
 func (h *Handler) InternalLogin(c *gin.Context) {
 	var req InternalLoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -440,9 +492,7 @@ func (h *Handler) WechatLogin(c *gin.Context) {
 	}
 
 	// Step 1: 用 code 向微信服务器换取 openid
-	appid := os.Getenv("WECHAT_APPID")
-	secret := os.Getenv("WECHAT_SECRET")
-	if appid == "" || secret == "" {
+	if !h.wechat.Configured() {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
 			Message: "微信配置未设置(WECHAT_APPID/WECHAT_SECRET)",
@@ -450,49 +500,313 @@ func (h *Handler) WechatLogin(c *gin.Context) {
 		return
 	}
 
-	wxURL := fmt.Sprintf(
-		"https://api.weixin.qq.com/sns/jscode2session?appid=%s&secret=%s&js_code=%s&grant_type=authorization_code",
-		appid, secret, req.Code,
-	)
-
-	wxResp, err := http.Get(wxURL) //nolint:gosec
+	session, err := h.wechat.Code2Session(c.Request.Context(), req.Code)
 	if err != nil {
+		var wxErr *wechat.Error
+		if errors.As(err, &wxErr) {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Code:    http.StatusUnauthorized,
+				Message: fmt.Sprintf("微信授权失败: %s", wxErr.Msg),
+			})
+			return
+		}
+		fmt.Printf("[WECHAT-LOGIN] 微信服务器请求失败: %v\n", err)
 		c.JSON(http.StatusBadGateway, ErrorResponse{
 			Code:    http.StatusBadGateway,
 			Message: "微信服务器请求失败",
 		})
 		return
 	}
-	defer wxResp.Body.Close()
+	fmt.Printf("[WECHAT-LOGIN] 获取 openid 成功: %s\n", session.OpenID)
+
+	// Step 2: 用 openid 查询内部系统绑定的供应商信息，并自动注册用户（隐藏注册）
+	ctx := context.Background()
+	result, err := h.resolveWechatUser(ctx, h.wechat.AppID(), session.OpenID, session.UnionID, wechatTypeMiniProgram)
+	if err != nil {
+		writeResolveError(c, err)
+		return
+	}
+	u, isBound := result.User, result.IsBound
+
+	if u.Status == "disabled" {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "账号已被禁用",
+		})
+		return
+	}
+
+	// 保存 session_key（加密），供后续解密 getPhoneNumber 等数据
+	if err := h.userService.SaveSessionKey(ctx, u.ID, session.SessionKey, sessionKeyTTL); err != nil {
+		fmt.Printf("[WECHAT-LOGIN] 保存 session_key 失败: %v\n", err)
+	}
 
-	var session wechatSessionResult
-	if err := json.NewDecoder(wxResp.Body).Decode(&session); err != nil {
+	// Step 3: 生成 JWT（以 username 作为标识）
+	token, err := h.issueToken(u)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Code:    http.StatusInternalServerError,
-			Message: "解析微信响应失败",
+			Message: "生成 token 失败",
+		})
+		return
+	}
+
+	message := "登录成功"
+	if !isBound {
+		message = "登录成功，该微信暂未绑定供应商"
+	}
+
+	// 设置审计信息
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "auth.wechat_login"
+		rc.Resource = "auth"
+		rc.Username = u.Username
+		rc.Detail = map[string]any{
+			"openid":   session.OpenID,
+			"username": u.Username,
+			"is_bound": isBound,
+			"linked":   result.Linked,
+		}
+	}
+
+	c.JSON(http.StatusOK, WechatLoginResponse{
+		Code:    http.StatusOK,
+		Message: message,
+		Data: WechatLoginData{
+			Token:        token,
+			OpenID:       session.OpenID,
+			IsBound:      isBound,
+			SupplierInfo: result.SupplierData,
+		},
+	})
+}
+
+// WechatPhone 解密小程序手机号并保存为已校验手机号
+// @Summary 校验微信手机号
+// @Description 使用登录时保存的 session_key 解密 getPhoneNumber 返回的 encryptedData，校验 appid 水印后保存手机号
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body WechatPhoneRequest true "encryptedData 和 iv"
+// @Success 200 {object} WechatPhoneResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/v1/auth/wechat-phone [post]
+func (h *Handler) WechatPhone(c *gin.Context) {
+	var req WechatPhoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
 		})
 		return
 	}
 
-	if session.ErrCode != 0 {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Code:    http.StatusUnauthorized,
-			Message: fmt.Sprintf("微信授权失败: %s", session.ErrMsg),
+			Message: "用户未登录",
 		})
 		return
 	}
-	fmt.Printf("[WECHAT-LOGIN] 获取 openid 成功: %s\n", session.OpenID)
 
-	// Step 2: 用 openid 查询内部系统绑定的供应商信息
-	supplierRaw, err := h.querySupplierByOpenID(session.OpenID)
+	ctx := c.Request.Context()
+	u, err := h.userService.GetUserByUsername(ctx, rc.Username)
 	if err != nil {
-		fmt.Printf("[WECHAT-LOGIN] 查询供应商失败: %v\n", err)
-		c.JSON(http.StatusBadGateway, ErrorResponse{
-			Code:    http.StatusBadGateway,
-			Message: "查询供应商信息失败: " + err.Error(),
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户不存在",
+		})
+		return
+	}
+
+	sessionKey, err := h.userService.GetSessionKey(ctx, u.ID)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "微信会话已失效，请重新登录",
+		})
+		return
+	}
+
+	plaintext, err := wechat.DecryptData(sessionKey, req.EncryptedData, req.IV)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "解密失败: " + err.Error(),
+		})
+		return
+	}
+
+	var info wechatPhoneInfo
+	if err := json.Unmarshal(plaintext, &info); err != nil || info.PurePhoneNumber == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "解密数据格式错误",
+		})
+		return
+	}
+
+	// 校验水印 appid，防止使用其他小程序的数据
+	if info.Watermark.AppID != h.wechat.AppID() {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "数据水印校验失败",
+		})
+		return
+	}
+
+	u, err = h.userService.UpdateVerifiedPhone(ctx, u.ID, info.PurePhoneNumber)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "保存手机号失败: " + err.Error(),
+		})
+		return
+	}
+
+	// 设置审计信息
+	rc.Action = "auth.wechat_phone"
+	rc.Resource = "user"
+	rc.ResourceID = u.ID
+	rc.Detail = map[string]any{
+		"phone_masked": u.PhoneMasked,
+		"country_code": info.CountryCode,
+	}
+
+	c.JSON(http.StatusOK, WechatPhoneResponse{
+		Code:    http.StatusOK,
+		Message: "手机号校验成功",
+		Data: WechatPhoneData{
+			PhoneMasked: u.PhoneMasked,
+			CountryCode: info.CountryCode,
+		},
+	})
+}
+
+// MergeAccount 合并账号（自助）
+// @Summary 合并账号
+// @Description 将另一个入口（如公众号）登录产生的账号合并到当前账号，需提供被合并账号的 token 证明归属
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body MergeAccountRequest true "被合并账号的 token"
+// @Success 200 {object} MergeAccountResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Router /api/v1/auth/merge-account [post]
+func (h *Handler) MergeAccount(c *gin.Context) {
+	var req MergeAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未登录",
+		})
+		return
+	}
+
+	sourceClaims, err := h.jwtService.ValidateToken(req.SourceToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "被合并账号的 token 无效或已过期",
+		})
+		return
+	}
+	// 员工代查看的 token 不能证明持有该账号，否则员工可借代查看把用户账号并入自己名下
+	if sourceClaims.IsImpersonation() {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "不能使用代查看 token 合并账号",
+		})
+		return
+	}
+
+	ctx := c.Request.Context()
+	// 已作废（停用、强制下线、已被合并）的会话不能作为合并凭证
+	if err := h.userService.CheckSession(ctx, sourceClaims.Username, issuedAt(sourceClaims)); err != nil {
+		status, message := http.StatusServiceUnavailable, "会话校验失败"
+		if errors.Is(err, middleware.ErrSessionRevoked) {
+			status, message = http.StatusUnauthorized, "被合并账号的会话已失效，请重新登录"
+		}
+		c.JSON(status, ErrorResponse{
+			Code:    status,
+			Message: message,
+		})
+		return
+	}
+	target, err := h.userService.GetUserByUsername(ctx, rc.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户不存在",
+		})
+		return
+	}
+	source, err := h.userService.GetUserByUsername(ctx, sourceClaims.Username)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "被合并账号不存在",
+		})
+		return
+	}
+
+	merged, err := h.userService.MergeUsers(ctx, source.ID, target.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "合并失败: " + err.Error(),
 		})
 		return
 	}
+
+	// 设置审计信息
+	rc.Action = "auth.merge_account"
+	rc.Resource = "user"
+	rc.ResourceID = merged.ID
+	rc.Detail = map[string]any{
+		"source_username": source.Username,
+		"target_username": merged.Username,
+		"initiator":       "self",
+	}
+
+	c.JSON(http.StatusOK, MergeAccountResponse{
+		Code:    http.StatusOK,
+		Message: "账号合并成功",
+		Data:    merged,
+	})
+}
+
+// wechatLoginResult openid 登录解析结果
+type wechatLoginResult struct {
+	User         *user.User
+	Linked       bool // 通过 unionid 关联到已有用户
+	IsBound      bool
+	SupplierData json.RawMessage // 内部系统返回的 data 数组，未绑定时为 []
+}
+
+// errQuerySupplier 查询内部系统失败（对外返回 502）
+var errQuerySupplier = errors.New("查询供应商信息失败")
+
+// resolveWechatUser 查询 openid 绑定的供应商，并查找或自动创建本地用户
+// wxType: 0=公众号 1=小程序
+func (h *Handler) resolveWechatUser(ctx context.Context, appid, openid, unionid, wxType string) (*wechatLoginResult, error) {
+	supplierRaw, err := h.querySupplierByOpenID(openid, wxType)
+	if err != nil {
+		fmt.Printf("[WECHAT-LOGIN] 查询供应商失败: %v\n", err)
+		return nil, fmt.Errorf("%w: %v", errQuerySupplier, err)
+	}
 	fmt.Printf("[WECHAT-LOGIN] 内部系统原始响应: %s\n", string(supplierRaw))
 
 	// 解析内部系统响应，只取 data 字段
@@ -506,10 +820,8 @@ func (h *Handler) WechatLogin(c *gin.Context) {
 	isBound := internalResp.IsSucceed && len(internalResp.Data) > 0
 	fmt.Printf("[WECHAT-LOGIN] isBound=%v isSucceed=%v dataLen=%d\n", isBound, internalResp.IsSucceed, len(internalResp.Data))
 
-	// Step 2.5: 自动注册用户（隐藏注册）
-	ctx := context.Background()
 	var mobile, customerCode string
-	
+
 	// 解析供应商信息（如果绑定）
 	if isBound && len(internalResp.Data) > 0 {
 		var supplier struct {
@@ -523,75 +835,59 @@ func (h *Handler) WechatLogin(c *gin.Context) {
 		}
 	}
 
-	// 查询用户是否存在
-	u, err := h.userService.GetUserByOpenID(ctx, session.OpenID)
+	// 查找用户：已登记的 openid → 同一 unionid 的已有用户 → 自动创建
+	resolved, err := h.userService.ResolveWechatUser(ctx, &user.WechatIdentityRequest{
+		AppID:        appid,
+		OpenID:       openid,
+		UnionID:      unionid,
+		Type:         wxType,
+		Mobile:       mobile,
+		CustomerCode: customerCode,
+	})
 	if err != nil {
-		// 用户不存在，自动创建
-		fmt.Printf("[WECHAT-LOGIN] 用户不存在，自动创建: openid=%s\n", session.OpenID)
-		u, err = h.userService.CreateUser(ctx, &user.CreateUserRequest{
-			OpenID:      session.OpenID,
-			UnionID:     session.UnionID,
-			Mobile:      mobile,
-			CustomerCode: customerCode,
-		})
-		if err != nil {
-			fmt.Printf("[WECHAT-LOGIN] 创建用户失败: %v\n", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Code:    http.StatusInternalServerError,
-				Message: "创建用户失败: " + err.Error(),
-			})
-			return
-		}
+		fmt.Printf("[WECHAT-LOGIN] 创建用户失败: %v\n", err)
+		return nil, fmt.Errorf("创建用户失败: %w", err)
+	}
+	u := resolved.User
+	if resolved.Created {
+		fmt.Printf("[WECHAT-LOGIN] 用户不存在，自动创建: openid=%s username=%s\n", openid, u.Username)
 	} else {
 		// 用户存在，更新最后登录时间
-		fmt.Printf("[WECHAT-LOGIN] 用户已存在，更新登录时间: username=%s\n", u.Username)
+		fmt.Printf("[WECHAT-LOGIN] 用户已存在，更新登录时间: username=%s linked=%v\n", u.Username, resolved.Linked)
 		_ = h.userService.UpdateLastLogin(ctx, u.ID)
 	}
 
 	// 将 data 序列化为 JSON（未绑定时为空数组 []）
 	supplierData, _ := json.Marshal(internalResp.Data)
-
-	// Step 3: 生成 JWT（以 username 作为标识）
-	token, err := h.jwtService.GenerateToken(u.Username)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Code:    http.StatusInternalServerError,
-			Message: "生成 token 失败",
-		})
-		return
+	if internalResp.Data == nil {
+		supplierData = []byte("[]")
 	}
 
-	message := "登录成功"
-	if !isBound {
-		message = "登录成功，该微信暂未绑定供应商"
-	}
+	return &wechatLoginResult{
+		User:         u,
+		Linked:       resolved.Linked,
+		IsBound:      isBound,
+		SupplierData: json.RawMessage(supplierData),
+	}, nil
+}
 
-	// 设置审计信息
-	if rc := middleware.GetRequestContext(c); rc != nil {
-		rc.Action = "auth.wechat_login"
-		rc.Resource = "auth"
-		rc.Username = u.Username
-		rc.Detail = map[string]any{
-			"openid":   session.OpenID,
-			"username": u.Username,
-			"is_bound": isBound,
-		}
+// writeResolveError 将 resolveWechatUser 的错误转换为响应
+func writeResolveError(c *gin.Context, err error) {
+	if errors.Is(err, errQuerySupplier) {
+		c.JSON(http.StatusBadGateway, ErrorResponse{
+			Code:    http.StatusBadGateway,
+			Message: err.Error(),
+		})
+		return
 	}
-
-	c.JSON(http.StatusOK, WechatLoginResponse{
-		Code:    http.StatusOK,
-		Message: message,
-		Data: WechatLoginData{
-			Token:        token,
-			OpenID:       session.OpenID,
-			IsBound:      isBound,
-			SupplierInfo: json.RawMessage(supplierData),
-		},
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
 	})
 }
 
 // querySupplierByOpenID 调用内部系统 BC_Customer_GetByWeChat，返回原始 JSON
-func (h *Handler) querySupplierByOpenID(openid string) ([]byte, error) {
+func (h *Handler) querySupplierByOpenID(openid, wxType string) ([]byte, error) {
 	internalToken, err := h.getInternalToken()
 	if err != nil {
 		return nil, fmt.Errorf("获取内部 token 失败: %w", err)
@@ -601,7 +897,7 @@ func (h *Handler) querySupplierByOpenID(openid string) ([]byte, error) {
 		"code": "BC_Customer_GetByWeChat",
 		"pars": map[string]interface{}{
 			"Openid": openid,
-			"Type":   "1", // 1=小程序
+			"Type":   wxType, // 0=公众号 1=小程序
 		},
 		"outPars": map[string]interface{}{},
 	}
@@ -628,3 +924,19 @@ func (h *Handler) querySupplierByOpenID(openid string) ([]byte, error) {
 	fmt.Printf("[WECHAT-LOGIN] 内部系统 HTTP %d, 响应: %s\n", resp.StatusCode, string(body))
 	return body, err
 }
+
+// issuedAt 取 token 签发时间，未携带 iat 时返回零值（视为最早签发）
+func issuedAt(claims *jwt.Claims) time.Time {
+	if claims.IssuedAt == nil {
+		return time.Time{}
+	}
+	return claims.IssuedAt.Time
+}
+
+// issueToken 为用户签发 JWT，携带用户的默认供应商编码（登录时使用）
+func (h *Handler) issueToken(u *user.User) (string, error) {
+	return h.jwtService.GenerateTokenWithClaims(&jwt.Claims{
+		Username:     u.Username,
+		CustomerCode: u.CustomerCode,
+	})
+}
@@ -1,6 +1,9 @@
 package auth
 
-import "encoding/json"
+import (
+	"back/internal/user"
+	"encoding/json"
+)
 
 // SendCodeRequest 发送验证码请求
 type SendCodeRequest struct {
@@ -53,15 +56,6 @@ type WechatLoginRequest struct {
 	UserInfo interface{} `json:"userInfo"`
 }
 
-// wechatSessionResult 微信服务器 jscode2session 响应（内部使用）
-type wechatSessionResult struct {
-	OpenID     string `json:"openid"`
-	SessionKey string `json:"session_key"`
-	UnionID    string `json:"unionid"`
-	ErrCode    int    `json:"errcode"`
-	ErrMsg     string `json:"errmsg"`
-}
-
 // WechatLoginData 微信登录响应 data 字段
 type WechatLoginData struct {
 	Token        string          `json:"token"`
@@ -76,3 +70,84 @@ type WechatLoginResponse struct {
 	Message string          `json:"message"`
 	Data    WechatLoginData `json:"data"`
 }
+
+// WechatPhoneRequest 小程序 getPhoneNumber 加密数据
+type WechatPhoneRequest struct {
+	EncryptedData string `json:"encryptedData" binding:"required"`
+	IV            string `json:"iv"            binding:"required"`
+}
+
+// wechatPhoneInfo 解密后的手机号数据（内部使用）
+type wechatPhoneInfo struct {
+	PhoneNumber     string `json:"phoneNumber"`
+	PurePhoneNumber string `json:"purePhoneNumber"`
+	CountryCode     string `json:"countryCode"`
+	Watermark       struct {
+		AppID     string `json:"appid"`
+		Timestamp int64  `json:"timestamp"`
+	} `json:"watermark"`
+}
+
+// WechatPhoneData 手机号校验响应 data 字段
+type WechatPhoneData struct {
+	PhoneMasked string `json:"phoneMasked"`
+	CountryCode string `json:"countryCode"`
+}
+
+// WechatPhoneResponse 手机号校验响应
+type WechatPhoneResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    WechatPhoneData `json:"data"`
+}
+
+// MergeAccountRequest 合并账号请求
+type MergeAccountRequest struct {
+	SourceToken string `json:"sourceToken" binding:"required"` // 被合并账号的 token
+}
+
+// MergeAccountResponse 合并账号响应
+type MergeAccountResponse struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    *user.User `json:"data,omitempty"`
+}
+
+// QRTicketResponse 扫码登录票据响应
+type QRTicketResponse struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Ticket    string `json:"ticket"`    // 小程序码 scene
+	PollToken string `json:"pollToken"` // 轮询凭证，仅网页持有
+	ImageURL  string `json:"imageUrl"`  // 小程序码图片地址
+	ExpiresIn int    `json:"expiresIn"` // 有效期（秒）
+}
+
+// QRTicketRequest 小程序扫码请求
+type QRTicketRequest struct {
+	Ticket string `json:"ticket" binding:"required"`
+}
+
+// QRScanResponse 小程序扫码响应，返回发起登录的网页信息供用户核对
+type QRScanResponse struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	ClientIP  string `json:"clientIp"`
+	UserAgent string `json:"userAgent"`
+	ExpiresIn int    `json:"expiresIn"`
+}
+
+// QRConfirmRequest 小程序确认/拒绝登录请求
+type QRConfirmRequest struct {
+	Ticket  string `json:"ticket" binding:"required"`
+	Approve bool   `json:"approve"`
+}
+
+// QRPollResponse 网页轮询扫码结果响应
+type QRPollResponse struct {
+	Code     int    `json:"code"`
+	Message  string `json:"message"`
+	Status   string `json:"status"` // pending, scanned, confirmed, rejected, consumed, expired
+	Token    string `json:"token,omitempty"`
+	Username string `json:"username,omitempty"`
+}
@@ -0,0 +1,189 @@
+package auth
+
+import (
+	"back/pkg/middleware"
+	"back/pkg/wechat"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OfficialAccountConfig 公众号网页授权配置
+type OfficialAccountConfig struct {
+	Client      *wechat.Client // 公众号接口客户端
+	CallbackURL string         // 授权回调地址，即本服务 /api/v1/auth/wechat-oa/callback 的外网地址
+	FrontURL    string         // 登录完成后跳回的前端地址
+}
+
+// oauthStateTTL 网页授权 state 有效期
+const oauthStateTTL = 5 * time.Minute
+
+// 网页授权 state 存储（内存，生产环境应使用 Redis）
+var (
+	oauthStateStore     = make(map[string]oauthState)
+	oauthStateStoreLock sync.Mutex
+)
+
+type oauthState struct {
+	Redirect  string // 登录完成后前端跳转路径
+	ExpiresAt time.Time
+}
+
+// cleanupOAuthStates 清理过期的 state
+func cleanupOAuthStates(now time.Time) {
+	oauthStateStoreLock.Lock()
+	defer oauthStateStoreLock.Unlock()
+	for state, entry := range oauthStateStore {
+		if now.After(entry.ExpiresAt) {
+			delete(oauthStateStore, state)
+		}
+	}
+}
+
+// takeOAuthState 取出并删除 state（一次性）
+func takeOAuthState(state string) (oauthState, bool) {
+	oauthStateStoreLock.Lock()
+	defer oauthStateStoreLock.Unlock()
+	entry, ok := oauthStateStore[state]
+	if !ok {
+		return oauthState{}, false
+	}
+	delete(oauthStateStore, state)
+	if time.Now().After(entry.ExpiresAt) {
+		return oauthState{}, false
+	}
+	return entry, true
+}
+
+// WechatOAAuthorize 公众号网页授权入口
+// @Summary 公众号网页授权登录
+// @Description 生成 state 并 302 跳转到微信网页授权页，授权完成后微信回调 /auth/wechat-oa/callback
+// @Tags auth
+// @Param redirect query string false "登录完成后前端跳转路径，默认 /"
+// @Success 302
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/auth/wechat-oa/authorize [get]
+func (h *Handler) WechatOAAuthorize(c *gin.Context) {
+	if h.oa.Client == nil || !h.oa.Client.Configured() || h.oa.CallbackURL == "" {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "公众号配置未设置(WECHAT_OA_APPID/WECHAT_OA_SECRET/WECHAT_OA_CALLBACK_URL)",
+		})
+		return
+	}
+
+	redirect := c.DefaultQuery("redirect", "/")
+	if !isSafeRedirect(redirect) {
+		redirect = "/"
+	}
+
+	state := randomState()
+	oauthStateStoreLock.Lock()
+	oauthStateStore[state] = oauthState{
+		Redirect:  redirect,
+		ExpiresAt: time.Now().Add(oauthStateTTL),
+	}
+	oauthStateStoreLock.Unlock()
+
+	c.Redirect(http.StatusFound, h.oa.Client.AuthorizeURL(h.oa.CallbackURL, state, wechat.ScopeBase))
+}
+
+// WechatOACallback 公众号网页授权回调
+// @Summary 公众号网页授权回调
+// @Description 用 code 换取 openid，按 Type=0 查询内部系统绑定的供应商，签发 JWT 后跳回前端（token 放在 URL fragment 中）
+// @Tags auth
+// @Param code query string true "微信授权 code"
+// @Param state query string true "授权 state"
+// @Success 302
+// @Router /api/v1/auth/wechat-oa/callback [get]
+func (h *Handler) WechatOACallback(c *gin.Context) {
+	code := c.Query("code")
+	entry, ok := takeOAuthState(c.Query("state"))
+	if !ok {
+		h.redirectFront(c, "/", url.Values{"error": {"授权已过期，请重新登录"}})
+		return
+	}
+	if code == "" {
+		// 用户拒绝授权时微信不会带 code
+		h.redirectFront(c, entry.Redirect, url.Values{"error": {"未获得微信授权"}})
+		return
+	}
+
+	ctx := context.Background()
+	oauthToken, err := h.oa.Client.OAuthAccessToken(ctx, code)
+	if err != nil {
+		fmt.Printf("[WECHAT-OA-LOGIN] 换取 openid 失败: %v\n", err)
+		h.redirectFront(c, entry.Redirect, url.Values{"error": {"微信授权失败"}})
+		return
+	}
+	fmt.Printf("[WECHAT-OA-LOGIN] 获取 openid 成功: %s\n", oauthToken.OpenID)
+
+	result, err := h.resolveWechatUser(ctx, h.oa.Client.AppID(), oauthToken.OpenID, oauthToken.UnionID, wechatTypeOfficialAccount)
+	if err != nil {
+		h.redirectFront(c, entry.Redirect, url.Values{"error": {err.Error()}})
+		return
+	}
+	u := result.User
+
+	if u.Status != "active" {
+		h.redirectFront(c, entry.Redirect, url.Values{"error": {"账号已被禁用"}})
+		return
+	}
+
+	token, err := h.issueToken(u)
+	if err != nil {
+		h.redirectFront(c, entry.Redirect, url.Values{"error": {"生成 token 失败"}})
+		return
+	}
+
+	// 设置审计信息
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "auth.wechat_oa_login"
+		rc.Resource = "auth"
+		rc.Username = u.Username
+		rc.Detail = map[string]any{
+			"openid":   oauthToken.OpenID,
+			"username": u.Username,
+			"is_bound": result.IsBound,
+			"linked":   result.Linked,
+		}
+	}
+
+	h.redirectFront(c, entry.Redirect, url.Values{
+		"token":    {token},
+		"username": {u.Username},
+		"bound":    {strconv.FormatBool(result.IsBound)},
+	})
+}
+
+// redirectFront 跳回前端页面，参数放在 fragment 中避免 token 出现在服务器日志和 Referer 里
+func (h *Handler) redirectFront(c *gin.Context, path string, params url.Values) {
+	target := strings.TrimRight(h.oa.FrontURL, "/") + path
+	c.Redirect(http.StatusFound, target+"#"+params.Encode())
+}
+
+// isSafeRedirect 仅允许站内相对路径，防止开放重定向
+func isSafeRedirect(path string) bool {
+	return strings.HasPrefix(path, "/") && !strings.HasPrefix(path, "//") && !strings.Contains(path, "\\")
+}
+
+// randomState 生成随机 state
+func randomState() string {
+	return randomHex(16)
+}
+
+// randomHex 生成 n 字节的随机十六进制串
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
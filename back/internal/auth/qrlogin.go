@@ -0,0 +1,428 @@
+package auth
+
+import (
+	"back/pkg/middleware"
+	"back/pkg/wechat"
+	"crypto/subtle"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// QRLoginConfig 扫码登录配置
+type QRLoginConfig struct {
+	Page       string             // 小程序中处理扫码登录的页面，如 pages/qr-login/index
+	EnvVersion string             // 小程序码打开的版本：release / trial / develop
+	MaxTickets int                // 同时未过期的票据上限，0 为不限制
+	Store      QRTicketRepository // 票据存储（数据库），多实例共享
+}
+
+// 扫码登录参数
+const (
+	qrTicketTTL       = 2 * time.Minute  // 票据有效期
+	qrPollMaxWait     = 25 * time.Second // 长轮询最长等待
+	qrPollCheckPeriod = time.Second      // 长轮询期间重新读取票据状态的间隔（状态可能由其他实例更新）
+	qrTicketIDBytes   = 16               // 32 位十六进制，满足小程序码 scene 长度限制
+)
+
+// 扫码登录票据状态
+const (
+	qrStatusPending   = "pending"   // 等待扫码
+	qrStatusScanned   = "scanned"   // 已扫码，等待小程序确认
+	qrStatusConfirmed = "confirmed" // 已确认，等待网页领取 token
+	qrStatusRejected  = "rejected"  // 小程序端拒绝
+	qrStatusConsumed  = "consumed"  // 网页已领取 token
+	qrStatusExpired   = "expired"   // 已过期
+)
+
+var (
+	errQRTicketNotFound = errors.New("登录二维码不存在或已过期")
+	errQRTicketState    = errors.New("登录二维码状态已变化，请刷新")
+	errQRScannedByOther = errors.New("该二维码已被其他账号扫描")
+	errQRTooManyTickets = errors.New("登录请求过多，请稍后再试")
+)
+
+// QRLoginTicket 网页申请扫码登录票据
+// @Summary 申请扫码登录票据
+// @Description 网页端申请登录票据，用 ticket 获取小程序码，用 pollToken 轮询登录结果；按来源 IP 限流，未过期票据总数达到上限时返回 503
+// @Tags auth
+// @Produce json
+// @Success 200 {object} QRTicketResponse
+// @Failure 429 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
+// @Router /api/v1/auth/qr/ticket [post]
+func (h *Handler) QRLoginTicket(c *gin.Context) {
+	t := &QRTicket{
+		ID:        randomHex(qrTicketIDBytes),
+		PollToken: randomHex(32),
+		Status:    qrStatusPending,
+		ClientIP:  c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		ExpiresAt: time.Now().Add(qrTicketTTL),
+	}
+
+	if len(t.UserAgent) > 500 {
+		t.UserAgent = t.UserAgent[:500]
+	}
+	created, err := h.qr.Store.Create(c.Request.Context(), t, h.qr.MaxTickets)
+	if err != nil {
+		log.Printf("[QR-LOGIN] 保存票据失败: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "申请登录二维码失败",
+		})
+		return
+	}
+	if !created {
+		log.Printf("[QR-LOGIN] 未过期票据已达上限 %d，拒绝申请: ip=%s", h.qr.MaxTickets, t.ClientIP)
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse{
+			Code:    http.StatusServiceUnavailable,
+			Message: errQRTooManyTickets.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "auth.qr_ticket"
+		rc.Resource = "qr_login"
+		rc.ResourceID = t.ID
+	}
+
+	c.JSON(http.StatusOK, QRTicketResponse{
+		Code:      http.StatusOK,
+		Message:   "ok",
+		Ticket:    t.ID,
+		PollToken: t.PollToken,
+		ImageURL:  "/api/v1/auth/qr/image?ticket=" + t.ID,
+		ExpiresIn: int(qrTicketTTL.Seconds()),
+	})
+}
+
+// QRLoginImage 获取扫码登录的小程序码图片
+// @Summary 扫码登录小程序码
+// @Description 返回 scene=ticket 的小程序码图片，小程序扫码后打开登录确认页；同一票据只生成一次，之后返回缓存；按来源 IP 限流
+// @Tags auth
+// @Produce image/png
+// @Param ticket query string true "登录票据"
+// @Success 200
+// @Failure 404 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /api/v1/auth/qr/image [get]
+func (h *Handler) QRLoginImage(c *gin.Context) {
+	id := c.Query("ticket")
+	ctx := c.Request.Context()
+	img, contentType, err := h.qr.Store.GetImage(ctx, id)
+	if err != nil {
+		writeQRStoreError(c, err)
+		return
+	}
+
+	if img == nil {
+		img, contentType, err = h.wechat.GetUnlimitedQRCode(ctx, wechat.UnlimitedCodeRequest{
+			Scene:      id,
+			Page:       h.qr.Page,
+			EnvVersion: h.qr.EnvVersion,
+			Width:      280,
+		})
+		if err != nil {
+			log.Printf("[QR-LOGIN] 生成小程序码失败: %v", err)
+			c.JSON(http.StatusBadGateway, ErrorResponse{
+				Code:    http.StatusBadGateway,
+				Message: "生成小程序码失败",
+			})
+			return
+		}
+		if err := h.qr.Store.SetImage(ctx, id, img, contentType); err != nil {
+			log.Printf("[QR-LOGIN] 缓存小程序码失败: ticket=%s err=%v", id, err)
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, contentType, img)
+}
+
+// QRLoginScan 小程序扫码
+// @Summary 小程序扫码
+// @Description 小程序扫码后调用，标记票据已扫描，返回发起登录的网页信息供用户核对
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body QRTicketRequest true "登录票据"
+// @Success 200 {object} QRScanResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/auth/qr/scan [post]
+func (h *Handler) QRLoginScan(c *gin.Context) {
+	var req QRTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	username := middleware.GetRequestContext(c).Username
+	ctx := c.Request.Context()
+
+	t, err := h.qr.Store.Get(ctx, req.Ticket)
+	if err == nil && t.Status == qrStatusPending {
+		var ok bool
+		ok, err = h.qr.Store.Transition(ctx, req.Ticket, username, qrStatusPending, qrStatusScanned)
+		if err == nil && !ok {
+			// 同时被其他请求改变了状态，重新读取
+			t, err = h.qr.Store.Get(ctx, req.Ticket)
+		} else if ok {
+			t.Status, t.Username = qrStatusScanned, username
+		}
+	}
+	if err != nil {
+		h.setQRAudit(c, "auth.qr_scan", req.Ticket, err)
+		writeQRStoreError(c, err)
+		return
+	}
+	switch {
+	case t.Status == qrStatusScanned && t.Username != username:
+		err = errQRScannedByOther
+	case t.Status != qrStatusScanned:
+		err = errQRTicketState
+	}
+
+	h.setQRAudit(c, "auth.qr_scan", req.Ticket, err)
+	if err != nil {
+		writeQRError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, QRScanResponse{
+		Code:      http.StatusOK,
+		Message:   "请在小程序中确认登录",
+		ClientIP:  t.ClientIP,
+		UserAgent: t.UserAgent,
+		ExpiresIn: int(time.Until(t.ExpiresAt).Seconds()),
+	})
+}
+
+// QRLoginConfirm 小程序确认或拒绝网页登录
+// @Summary 小程序确认扫码登录
+// @Description 小程序用户确认后，网页端轮询即可领取登录 token；approve=false 为拒绝
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body QRConfirmRequest true "确认参数"
+// @Success 200 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/auth/qr/confirm [post]
+func (h *Handler) QRLoginConfirm(c *gin.Context) {
+	var req QRConfirmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	username := middleware.GetRequestContext(c).Username
+	ctx := c.Request.Context()
+
+	action, to := "auth.qr_confirm", qrStatusConfirmed
+	if !req.Approve {
+		action, to = "auth.qr_reject", qrStatusRejected
+	}
+
+	t, err := h.qr.Store.Get(ctx, req.Ticket)
+	if err != nil {
+		h.setQRAudit(c, action, req.Ticket, err)
+		writeQRStoreError(c, err)
+		return
+	}
+	switch {
+	case t.Status != qrStatusScanned:
+		err = errQRTicketState
+	case t.Username != username:
+		err = errQRScannedByOther
+	default:
+		var ok bool
+		ok, err = h.qr.Store.Transition(ctx, req.Ticket, username, qrStatusScanned, to)
+		if err == nil && !ok {
+			err = errQRTicketState
+		}
+	}
+
+	h.setQRAudit(c, action, req.Ticket, err)
+	if err != nil {
+		writeQRStoreError(c, err)
+		return
+	}
+
+	message := "已确认登录"
+	if !req.Approve {
+		message = "已取消登录"
+	}
+	c.JSON(http.StatusOK, ErrorResponse{
+		Code:    http.StatusOK,
+		Message: message,
+	})
+}
+
+// QRLoginPoll 网页轮询扫码结果
+// @Summary 轮询扫码登录结果
+// @Description 长轮询：状态变化或等待超时后返回；状态为 confirmed 时返回 token，票据随即失效
+// @Tags auth
+// @Produce json
+// @Param ticket query string true "登录票据"
+// @Param pollToken query string true "申请票据时返回的轮询凭证"
+// @Param status query string false "网页已知的状态，状态与之不同时立即返回"
+// @Param wait query int false "最长等待秒数，默认 25"
+// @Success 200 {object} QRPollResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/auth/qr/poll [get]
+func (h *Handler) QRLoginPoll(c *gin.Context) {
+	id := c.Query("ticket")
+	known := c.DefaultQuery("status", qrStatusPending)
+	wait := qrPollMaxWait
+	if v, err := strconv.Atoi(c.Query("wait")); err == nil && v >= 0 && time.Duration(v)*time.Second < wait {
+		wait = time.Duration(v) * time.Second
+	}
+	ctx := c.Request.Context()
+
+	t, err := h.qr.Store.Get(ctx, id)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		writeQRStoreError(c, err)
+		return
+	}
+	if err != nil || subtle.ConstantTimeCompare([]byte(t.PollToken), []byte(c.Query("pollToken"))) != 1 {
+		c.JSON(http.StatusNotFound, QRPollResponse{
+			Code:    http.StatusNotFound,
+			Message: errQRTicketNotFound.Error(),
+			Status:  qrStatusExpired,
+		})
+		return
+	}
+
+	// 状态未变化时挂起等待，定期重新读取（扫码和确认可能由其他实例处理）
+	if t.Status == known {
+		deadline := time.NewTimer(wait)
+		ticker := time.NewTicker(qrPollCheckPeriod)
+	loop:
+		for {
+			select {
+			case <-deadline.C:
+				break loop
+			case <-ctx.Done():
+				break loop
+			case <-ticker.C:
+			}
+			latest, err := h.qr.Store.Get(ctx, id)
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				t.Status = qrStatusExpired
+				break
+			}
+			if err == nil && latest.Status != known {
+				t = latest
+				break
+			}
+		}
+		ticker.Stop()
+		deadline.Stop()
+	}
+
+	resp := QRPollResponse{Code: http.StatusOK, Message: "ok", Status: t.Status}
+	if t.Status != qrStatusConfirmed {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	// 确认后只允许领取一次
+	consumed, err := h.qr.Store.Transition(ctx, t.ID, t.Username, qrStatusConfirmed, qrStatusConsumed)
+	if err != nil {
+		writeQRStoreError(c, err)
+		return
+	}
+	if !consumed {
+		resp.Status = qrStatusConsumed
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	u, err := h.userService.GetUserByUsername(ctx, t.Username)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取用户失败",
+		})
+		return
+	}
+	if u.Status == "disabled" || u.Status == "merged" {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "账号已被禁用或已合并",
+		})
+		return
+	}
+	token, err := h.issueToken(u)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "生成Token失败",
+		})
+		return
+	}
+	_ = h.userService.UpdateLastLogin(ctx, u.ID)
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "auth.qr_login"
+		rc.Resource = "qr_login"
+		rc.ResourceID = t.ID
+		rc.Username = u.Username
+		rc.CustomerCode = u.CustomerCode
+	}
+
+	resp.Token = token
+	resp.Username = u.Username
+	c.JSON(http.StatusOK, resp)
+}
+
+// setQRAudit 设置扫码登录相关审计信息
+func (h *Handler) setQRAudit(c *gin.Context, action, ticket string, err error) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil {
+		return
+	}
+	rc.Action = action
+	rc.Resource = "qr_login"
+	rc.ResourceID = ticket
+	if err != nil {
+		rc.Detail = map[string]any{"error": err.Error()}
+	}
+}
+
+// writeQRError 扫码登录错误转换为响应
+func writeQRError(c *gin.Context, err error) {
+	status := http.StatusConflict
+	if errors.Is(err, errQRTicketNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, ErrorResponse{
+		Code:    status,
+		Message: err.Error(),
+	})
+}
+
+// writeQRStoreError 票据读写错误转换为响应：票据不存在或已过期为 404，数据库错误为 500
+func writeQRStoreError(c *gin.Context, err error) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = errQRTicketNotFound
+	}
+	if errors.Is(err, errQRTicketNotFound) || errors.Is(err, errQRTicketState) || errors.Is(err, errQRScannedByOther) {
+		writeQRError(c, err)
+		return
+	}
+	log.Printf("[QR-LOGIN] 读写票据失败: %v", err)
+	c.JSON(http.StatusInternalServerError, ErrorResponse{
+		Code:    http.StatusInternalServerError,
+		Message: "登录二维码服务暂不可用",
+	})
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// QRTicket 扫码登录票据，存数据库，多实例部署时申请、取码、扫码、轮询可落在不同实例。
+// ID 出现在二维码中，PollToken 只返回给发起登录的网页，扫码方拿不到网页的 token
+type QRTicket struct {
+	ID        string    `gorm:"type:varchar(32);primaryKey"`
+	PollToken string    `gorm:"type:varchar(64);not null"`
+	Status    string    `gorm:"type:varchar(20);not null"`
+	Username  string    `gorm:"type:varchar(64)"` // 扫码确认的用户
+	ClientIP  string    `gorm:"type:varchar(45)"` // 发起登录的网页 IP，展示给扫码方核对
+	UserAgent string    `gorm:"type:varchar(500)"`
+	Image     []byte    `gorm:"type:bytea"`       // 小程序码，首次取码后缓存，同一票据不重复调用微信接口
+	ImageType string    `gorm:"type:varchar(50)"` // 小程序码的 Content-Type
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (QRTicket) TableName() string {
+	return "qr_login_tickets"
+}
+
+// QRTicketRepository 扫码登录票据数据访问
+type QRTicketRepository interface {
+	// Create 未过期票据数低于 maxActive（0 为不限制）时写入，否则返回 false；各实例并发申请时为近似上限
+	Create(ctx context.Context, t *QRTicket, maxActive int) (bool, error)
+	// Get 取未过期的票据（不含小程序码），不存在或已过期时返回 gorm.ErrRecordNotFound
+	Get(ctx context.Context, id string) (*QRTicket, error)
+	// GetImage 取未过期票据缓存的小程序码，尚未缓存时 image 为 nil
+	GetImage(ctx context.Context, id string) (image []byte, contentType string, err error)
+	// Transition 票据未过期、状态为 from 且扫码用户为空或为 username 时改为 to 并记录扫码用户，返回是否更新
+	Transition(ctx context.Context, id, username, from, to string) (bool, error)
+	// SetImage 缓存小程序码，已缓存时不覆盖
+	SetImage(ctx context.Context, id string, image []byte, contentType string) error
+	DeleteExpired(ctx context.Context, before time.Time) error
+}
+
+type qrTicketRepository struct {
+	db *gorm.DB
+}
+
+// NewQRTicketRepository 创建扫码登录票据仓库
+func NewQRTicketRepository(db *gorm.DB) QRTicketRepository {
+	return &qrTicketRepository{db: db}
+}
+
+func (r *qrTicketRepository) Create(ctx context.Context, t *QRTicket, maxActive int) (bool, error) {
+	if maxActive > 0 {
+		var active int64
+		err := r.db.WithContext(ctx).Model(&QRTicket{}).Where("expires_at > ?", time.Now()).Count(&active).Error
+		if err != nil {
+			return false, err
+		}
+		if active >= int64(maxActive) {
+			return false, nil
+		}
+	}
+	if err := r.db.WithContext(ctx).Create(t).Error; err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *qrTicketRepository) Get(ctx context.Context, id string) (*QRTicket, error) {
+	var t QRTicket
+	err := r.db.WithContext(ctx).
+		Omit("image").
+		Where("id = ? AND expires_at > ?", id, time.Now()).
+		First(&t).Error
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *qrTicketRepository) GetImage(ctx context.Context, id string) ([]byte, string, error) {
+	var t QRTicket
+	err := r.db.WithContext(ctx).
+		Select("image", "image_type").
+		Where("id = ? AND expires_at > ?", id, time.Now()).
+		First(&t).Error
+	if err != nil {
+		return nil, "", err
+	}
+	return t.Image, t.ImageType, nil
+}
+
+func (r *qrTicketRepository) Transition(ctx context.Context, id, username, from, to string) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&QRTicket{}).
+		Where("id = ? AND status = ? AND expires_at > ? AND (username = '' OR username IS NULL OR username = ?)", id, from, time.Now(), username).
+		Updates(map[string]interface{}{
+			"status":   to,
+			"username": username,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *qrTicketRepository) SetImage(ctx context.Context, id string, image []byte, contentType string) error {
+	return r.db.WithContext(ctx).Model(&QRTicket{}).
+		Where("id = ? AND image IS NULL", id).
+		Updates(map[string]interface{}{
+			"image":      image,
+			"image_type": contentType,
+		}).Error
+}
+
+func (r *qrTicketRepository) DeleteExpired(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("expires_at < ?", before).Delete(&QRTicket{}).Error
+}
@@ -0,0 +1,205 @@
+package inquiry
+
+import (
+	"back/internal/supplier"
+	"back/internal/user"
+	"back/pkg/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 询价单标记处理器
+type Handler struct {
+	service         Service
+	userService     user.Service
+	supplierService supplier.Service
+}
+
+// NewHandler 创建询价单标记处理器
+func NewHandler(service Service, userService user.Service, supplierService supplier.Service) *Handler {
+	return &Handler{
+		service:         service,
+		userService:     userService,
+		supplierService: supplierService,
+	}
+}
+
+// ListMarks 我的询价单标记
+// @Summary 我的询价单标记
+// @Description 当前供应商下本人的关注、已读和备注记录，watched=true 只返回关注的询价单
+// @Tags inquiry
+// @Produce json
+// @Param watched query bool false "只看关注"
+// @Success 200 {object} MarkListResponse
+// @Router /api/v1/inquiries/marks [get]
+func (h *Handler) ListMarks(c *gin.Context) {
+	u, customerCode, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+	watchedOnly, _ := strconv.ParseBool(c.Query("watched"))
+	marks, err := h.service.List(c.Request.Context(), u.ID, customerCode, watchedOnly)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取标记失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "inquiry.list_marks", "")
+	c.JSON(http.StatusOK, MarkListResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    marks,
+	})
+}
+
+// Watch 关注询价单
+// @Summary 关注询价单
+// @Description 关注后截止前会收到提醒
+// @Tags inquiry
+// @Produce json
+// @Param inquiryId path string true "询价单ID"
+// @Success 200 {object} MarkResponse
+// @Router /api/v1/inquiries/{inquiryId}/watch [put]
+func (h *Handler) Watch(c *gin.Context) {
+	h.update(c, "inquiry.watch", func(u *user.User, customerCode, id string) (*Mark, error) {
+		return h.service.SetWatched(c.Request.Context(), u.ID, customerCode, id, true)
+	})
+}
+
+// Unwatch 取消关注
+// @Summary 取消关注
+// @Tags inquiry
+// @Produce json
+// @Param inquiryId path string true "询价单ID"
+// @Success 200 {object} MarkResponse
+// @Router /api/v1/inquiries/{inquiryId}/watch [delete]
+func (h *Handler) Unwatch(c *gin.Context) {
+	h.update(c, "inquiry.unwatch", func(u *user.User, customerCode, id string) (*Mark, error) {
+		return h.service.SetWatched(c.Request.Context(), u.ID, customerCode, id, false)
+	})
+}
+
+// MarkRead 标记已读
+// @Summary 标记已读
+// @Tags inquiry
+// @Produce json
+// @Param inquiryId path string true "询价单ID"
+// @Success 200 {object} MarkResponse
+// @Router /api/v1/inquiries/{inquiryId}/read [put]
+func (h *Handler) MarkRead(c *gin.Context) {
+	h.update(c, "inquiry.mark_read", func(u *user.User, customerCode, id string) (*Mark, error) {
+		return h.service.SetRead(c.Request.Context(), u.ID, customerCode, id, true)
+	})
+}
+
+// MarkUnread 标记未读
+// @Summary 标记未读
+// @Tags inquiry
+// @Produce json
+// @Param inquiryId path string true "询价单ID"
+// @Success 200 {object} MarkResponse
+// @Router /api/v1/inquiries/{inquiryId}/read [delete]
+func (h *Handler) MarkUnread(c *gin.Context) {
+	h.update(c, "inquiry.mark_unread", func(u *user.User, customerCode, id string) (*Mark, error) {
+		return h.service.SetRead(c.Request.Context(), u.ID, customerCode, id, false)
+	})
+}
+
+// SaveNote 保存私人备注
+// @Summary 保存私人备注
+// @Description 备注只对本人可见，空字符串表示清除
+// @Tags inquiry
+// @Accept json
+// @Produce json
+// @Param inquiryId path string true "询价单ID"
+// @Param request body NoteRequest true "备注"
+// @Success 200 {object} MarkResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/inquiries/{inquiryId}/note [put]
+func (h *Handler) SaveNote(c *gin.Context) {
+	var req NoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.writeError(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	h.update(c, "inquiry.save_note", func(u *user.User, customerCode, id string) (*Mark, error) {
+		return h.service.SetNote(c.Request.Context(), u.ID, customerCode, id, req.Note)
+	})
+}
+
+// update 校验成员身份后修改标记并输出结果
+func (h *Handler) update(c *gin.Context, action string, fn func(u *user.User, customerCode, inquiryID string) (*Mark, error)) {
+	u, customerCode, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+	inquiryID := c.Param("inquiryId")
+	mark, err := fn(u, customerCode, inquiryID)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidInquiryID) {
+			status = http.StatusBadRequest
+		}
+		h.writeError(c, status, err.Error())
+		return
+	}
+	h.setAuditInfo(c, action, inquiryID)
+	c.JSON(http.StatusOK, MarkResponse{
+		Code:    http.StatusOK,
+		Message: "保存成功",
+		Data:    mark,
+	})
+}
+
+// currentMember 取当前用户及当前会话所代表的供应商（取自 token），并校验成员身份，失败时已写出响应
+func (h *Handler) currentMember(c *gin.Context) (*user.User, string, bool) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		h.writeError(c, http.StatusUnauthorized, "用户未登录")
+		return nil, "", false
+	}
+	u, err := h.userService.GetUserByUsername(c.Request.Context(), rc.Username)
+	if err != nil {
+		h.writeError(c, http.StatusUnauthorized, "用户不存在")
+		return nil, "", false
+	}
+	customerCode := supplier.SessionCustomerCode(c, u)
+	if customerCode == "" {
+		h.writeError(c, http.StatusForbidden, "请先绑定供应商")
+		return nil, "", false
+	}
+	if _, err := h.supplierService.ActiveMember(c.Request.Context(), customerCode, u.CustomerCode, u.ID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, supplier.ErrNotMember) {
+			status = http.StatusForbidden
+		}
+		h.writeError(c, status, err.Error())
+		return nil, "", false
+	}
+	rc.CustomerCode = customerCode
+	return u, customerCode, true
+}
+
+func (h *Handler) writeError(c *gin.Context, status int, message string) {
+	c.JSON(status, ErrorResponse{
+		Code:    status,
+		Message: message,
+	})
+}
+
+// setAuditInfo 设置审计信息
+func (h *Handler) setAuditInfo(c *gin.Context, action, inquiryID string) {
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = action
+		rc.Resource = "inquiry"
+		if inquiryID != "" {
+			// resource_id 只有 36 位，完整ID放在 detail
+			rc.Detail = map[string]any{"inquiry_id": inquiryID}
+			if len(inquiryID) <= 36 {
+				rc.ResourceID = inquiryID
+			}
+		}
+	}
+}
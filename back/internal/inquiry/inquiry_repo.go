@@ -0,0 +1,77 @@
+package inquiry
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 询价单标记数据访问
+type Repository interface {
+	Get(ctx context.Context, userID, customerCode, inquiryID string) (*Mark, error)
+	// Upsert 不存在时创建，存在时只更新 columns 指定的字段
+	Upsert(ctx context.Context, mark *Mark, columns ...string) error
+	ListByInquiries(ctx context.Context, userID, customerCode string, inquiryIDs []string) ([]Mark, error)
+	List(ctx context.Context, userID, customerCode string, watchedOnly bool) ([]Mark, error)
+	ListWatched(ctx context.Context, customerCode string) ([]Mark, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建询价单标记仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Get(ctx context.Context, userID, customerCode, inquiryID string) (*Mark, error) {
+	var mark Mark
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND customer_code = ? AND inquiry_id = ?", userID, customerCode, inquiryID).
+		First(&mark).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mark, nil
+}
+
+func (r *repository) Upsert(ctx context.Context, mark *Mark, columns ...string) error {
+	mark.UpdatedAt = time.Now()
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "customer_code"}, {Name: "inquiry_id"}},
+		DoUpdates: clause.AssignmentColumns(append(columns, "updated_at")),
+	}).Create(mark).Error
+}
+
+func (r *repository) ListByInquiries(ctx context.Context, userID, customerCode string, inquiryIDs []string) ([]Mark, error) {
+	var marks []Mark
+	if len(inquiryIDs) == 0 {
+		return marks, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND customer_code = ? AND inquiry_id IN ?", userID, customerCode, inquiryIDs).
+		Find(&marks).Error
+	return marks, err
+}
+
+func (r *repository) List(ctx context.Context, userID, customerCode string, watchedOnly bool) ([]Mark, error) {
+	db := r.db.WithContext(ctx).Where("user_id = ? AND customer_code = ?", userID, customerCode)
+	if watchedOnly {
+		db = db.Where("watched = ?", true)
+	}
+	var marks []Mark
+	err := db.Order("updated_at DESC").Find(&marks).Error
+	return marks, err
+}
+
+// ListWatched 供应商下所有用户的关注记录
+func (r *repository) ListWatched(ctx context.Context, customerCode string) ([]Mark, error) {
+	var marks []Mark
+	err := r.db.WithContext(ctx).
+		Where("customer_code = ? AND watched = ?", customerCode, true).
+		Find(&marks).Error
+	return marks, err
+}
@@ -0,0 +1,119 @@
+package inquiry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidInquiryID = errors.New("invalid inquiry id")
+
+// Service 询价单本地标记
+type Service interface {
+	Get(ctx context.Context, userID, customerCode, inquiryID string) (*Mark, error)
+	SetWatched(ctx context.Context, userID, customerCode, inquiryID string, watched bool) (*Mark, error)
+	SetRead(ctx context.Context, userID, customerCode, inquiryID string, read bool) (*Mark, error)
+	SetNote(ctx context.Context, userID, customerCode, inquiryID, note string) (*Mark, error)
+	List(ctx context.Context, userID, customerCode string, watchedOnly bool) ([]Mark, error)
+	// Flags 批量取标记，没有记录的询价单返回零值
+	Flags(ctx context.Context, userID, customerCode string, inquiryIDs []string) (map[string]Flags, error)
+	// Watchers 供应商下每个询价单的关注用户（截止提醒用）
+	Watchers(ctx context.Context, customerCode string) (map[string][]string, error)
+}
+
+type service struct {
+	repo Repository
+}
+
+// NewService 创建询价单标记服务
+func NewService(repo Repository) Service {
+	return &service{repo: repo}
+}
+
+// Get 没有记录时返回零值标记
+func (s *service) Get(ctx context.Context, userID, customerCode, inquiryID string) (*Mark, error) {
+	mark, err := s.repo.Get(ctx, userID, customerCode, inquiryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &Mark{UserID: userID, CustomerCode: customerCode, InquiryID: inquiryID}, nil
+	}
+	return mark, err
+}
+
+func (s *service) SetWatched(ctx context.Context, userID, customerCode, inquiryID string, watched bool) (*Mark, error) {
+	if err := validInquiryID(inquiryID); err != nil {
+		return nil, err
+	}
+	mark := &Mark{UserID: userID, CustomerCode: customerCode, InquiryID: inquiryID, Watched: watched}
+	if err := s.repo.Upsert(ctx, mark, "watched"); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, customerCode, inquiryID)
+}
+
+func (s *service) SetRead(ctx context.Context, userID, customerCode, inquiryID string, read bool) (*Mark, error) {
+	if err := validInquiryID(inquiryID); err != nil {
+		return nil, err
+	}
+	mark := &Mark{UserID: userID, CustomerCode: customerCode, InquiryID: inquiryID}
+	if read {
+		now := time.Now()
+		mark.ReadAt = &now
+	}
+	if err := s.repo.Upsert(ctx, mark, "read_at"); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, customerCode, inquiryID)
+}
+
+func (s *service) SetNote(ctx context.Context, userID, customerCode, inquiryID, note string) (*Mark, error) {
+	if err := validInquiryID(inquiryID); err != nil {
+		return nil, err
+	}
+	mark := &Mark{UserID: userID, CustomerCode: customerCode, InquiryID: inquiryID, Note: strings.TrimSpace(note)}
+	if err := s.repo.Upsert(ctx, mark, "note"); err != nil {
+		return nil, err
+	}
+	return s.Get(ctx, userID, customerCode, inquiryID)
+}
+
+func (s *service) List(ctx context.Context, userID, customerCode string, watchedOnly bool) ([]Mark, error) {
+	return s.repo.List(ctx, userID, customerCode, watchedOnly)
+}
+
+func (s *service) Flags(ctx context.Context, userID, customerCode string, inquiryIDs []string) (map[string]Flags, error) {
+	marks, err := s.repo.ListByInquiries(ctx, userID, customerCode, inquiryIDs)
+	if err != nil {
+		return nil, err
+	}
+	flags := make(map[string]Flags, len(inquiryIDs))
+	for _, id := range inquiryIDs {
+		flags[id] = Flags{}
+	}
+	for i := range marks {
+		flags[marks[i].InquiryID] = marks[i].Flags()
+	}
+	return flags, nil
+}
+
+func (s *service) Watchers(ctx context.Context, customerCode string) (map[string][]string, error) {
+	marks, err := s.repo.ListWatched(ctx, customerCode)
+	if err != nil {
+		return nil, err
+	}
+	watchers := make(map[string][]string)
+	for _, m := range marks {
+		watchers[m.InquiryID] = append(watchers[m.InquiryID], m.UserID)
+	}
+	return watchers, nil
+}
+
+// validInquiryID 询价单ID由前端传入，只做长度校验，不向内部系统确认是否存在
+func validInquiryID(id string) error {
+	if id == "" || len(id) > 64 {
+		return ErrInvalidInquiryID
+	}
+	return nil
+}
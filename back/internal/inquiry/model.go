@@ -0,0 +1,61 @@
+package inquiry
+
+import "time"
+
+// Mark 用户在当前供应商下对某个询价单的本地标记（关注、已读、私人备注），只对本人可见
+type Mark struct {
+	ID           string     `json:"-" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID       string     `json:"-" gorm:"type:uuid;not null;uniqueIndex:idx_inquiry_mark"`
+	CustomerCode string     `json:"-" gorm:"type:varchar(50);not null;uniqueIndex:idx_inquiry_mark;index:idx_inquiry_mark_watched,priority:1"`
+	InquiryID    string     `json:"inquiryId" gorm:"type:varchar(64);not null;uniqueIndex:idx_inquiry_mark"`
+	Watched      bool       `json:"watched" gorm:"not null;default:false;index:idx_inquiry_mark_watched,priority:2"`
+	ReadAt       *time.Time `json:"readAt"`
+	Note         string     `json:"note" gorm:"type:varchar(1000)"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time  `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Mark) TableName() string {
+	return "inquiry_marks"
+}
+
+// Flags 附加到代理响应上的标记
+type Flags struct {
+	Watched bool   `json:"watched"`
+	Read    bool   `json:"read"`
+	Note    string `json:"note"`
+}
+
+// Flags 转换为代理响应附加的标记
+func (m *Mark) Flags() Flags {
+	return Flags{Watched: m.Watched, Read: m.ReadAt != nil, Note: m.Note}
+}
+
+// ========== Request ==========
+
+// NoteRequest 保存备注
+type NoteRequest struct {
+	Note string `json:"note" binding:"max=1000"`
+}
+
+// ========== Response ==========
+
+// MarkResponse 单个询价单标记响应
+type MarkResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *Mark  `json:"data,omitempty"`
+}
+
+// MarkListResponse 标记列表响应
+type MarkListResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    []Mark `json:"data"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
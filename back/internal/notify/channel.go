@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"back/pkg/wechat"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrPermanent 不可重试的发送错误（用户拒收、模板参数错误等），用 errors.Is 判断
+var ErrPermanent = errors.New("permanent delivery error")
+
+// Message 渠道发送的消息
+type Message struct {
+	UserID     string
+	OpenID     string
+	EventType  string
+	TemplateID string
+	Page       string
+	Data       map[string]string
+}
+
+// Channel 通知渠道
+type Channel interface {
+	Name() string
+	// NeedsSubscription 是否需要用户事先订阅模板（微信一次性订阅消息每次同意只能发一条）
+	NeedsSubscription() bool
+	Send(ctx context.Context, m *Message) error
+}
+
+// WeChatChannel 小程序订阅消息
+type WeChatChannel struct {
+	client *wechat.Client
+	state  string // miniprogram_state：developer / trial / formal
+}
+
+// NewWeChatChannel 创建小程序订阅消息渠道，state 为跳转的小程序版本
+func NewWeChatChannel(client *wechat.Client, state string) *WeChatChannel {
+	return &WeChatChannel{client: client, state: state}
+}
+
+func (c *WeChatChannel) Name() string { return "wechat" }
+
+func (c *WeChatChannel) NeedsSubscription() bool { return true }
+
+func (c *WeChatChannel) Send(ctx context.Context, m *Message) error {
+	if m.OpenID == "" {
+		return fmt.Errorf("%w: 用户没有小程序 openid", ErrPermanent)
+	}
+	data := make(map[string]wechat.SubscribeDataItem, len(m.Data))
+	for k, v := range m.Data {
+		data[k] = wechat.SubscribeDataItem{Value: v}
+	}
+	err := c.client.SendSubscribeMessage(ctx, wechat.SubscribeMessageRequest{
+		ToUser:           m.OpenID,
+		TemplateID:       m.TemplateID,
+		Page:             m.Page,
+		MiniprogramState: c.state,
+		Lang:             "zh_CN",
+		Data:             data,
+	})
+	if errors.Is(err, wechat.ErrSubscribeRefused) || errors.Is(err, wechat.ErrTemplateParam) ||
+		errors.Is(err, wechat.ErrTemplateNotExists) {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	return err
+}
+
+// LogChannel 本地开发用：把消息写到日志，或以 JSON 行追加到文件
+type LogChannel struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewLogChannel 创建日志渠道，path 为空时写标准日志
+func NewLogChannel(path string) *LogChannel {
+	return &LogChannel{path: path}
+}
+
+func (c *LogChannel) Name() string { return "log" }
+
+func (c *LogChannel) NeedsSubscription() bool { return false }
+
+func (c *LogChannel) Send(ctx context.Context, m *Message) error {
+	line, err := json.Marshal(map[string]any{
+		"time":       time.Now().Format(time.RFC3339),
+		"userId":     m.UserID,
+		"eventType":  m.EventType,
+		"templateId": m.TemplateID,
+		"page":       m.Page,
+		"data":       m.Data,
+	})
+	if err != nil {
+		return err
+	}
+	if c.path == "" {
+		log.Printf("[NOTIFY] %s", line)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, err := os.OpenFile(c.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
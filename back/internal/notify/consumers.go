@@ -0,0 +1,62 @@
+package notify
+
+import (
+	"back/internal/tracker"
+	"back/internal/webhook"
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// TrackerListener 报价跟踪产生事件时通知该供应商的成员
+func TrackerListener(s Service) tracker.Listener {
+	return func(ctx context.Context, e *tracker.QuoteEvent) {
+		err := s.NotifySupplier(ctx, e.CustomerCode, &Notification{
+			EventType: e.Type,
+			DedupKey:  "quote_event:" + e.ID,
+			Vars: map[string]string{
+				"inquiryId": e.InquiryID,
+				"quoteKey":  e.QuoteKey,
+				"before":    e.Before,
+				"after":     e.After,
+				"time":      e.CreatedAt.In(time.Local).Format("2006-01-02 15:04"),
+			},
+		})
+		if err != nil {
+			log.Printf("[NOTIFY] 报价事件 %s 通知失败: %v", e.ID, err)
+		}
+	}
+}
+
+// WebhookConsumer ERP 发布询价单时通知事件指定的供应商（带 CustomerCode 的定向邀请），
+// 模板变量为 inquiryId、time 及 payload 中的字符串字段。
+// 未指定供应商的询价单不逐个通知已绑定的供应商（订阅消息次数有限），由实时推送告知在线成员；
+// 报价结果、截止等事件经报价跟踪对比后再通知，这里不重复处理。按 EventID 去重，重复投递不会重复通知
+func WebhookConsumer(s Service) webhook.Consumer {
+	return func(ctx context.Context, e *webhook.Event) error {
+		if e.CustomerCode == "" {
+			return nil
+		}
+		vars := make(map[string]string)
+		var data map[string]any
+		if json.Unmarshal(e.Payload, &data) == nil {
+			for k, v := range data {
+				if str, ok := v.(string); ok {
+					vars[k] = str
+				}
+			}
+		}
+		at := e.CreatedAt
+		if e.OccurredAt != nil {
+			at = *e.OccurredAt
+		}
+		vars["inquiryId"] = e.InquiryID
+		vars["time"] = at.In(time.Local).Format("2006-01-02 15:04")
+		return s.NotifySupplier(ctx, e.CustomerCode, &Notification{
+			EventType: EventInquiryPublished,
+			DedupKey:  "erp_event:" + e.EventID,
+			Vars:      vars,
+		})
+	}
+}
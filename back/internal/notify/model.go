@@ -0,0 +1,148 @@
+package notify
+
+import (
+	"back/internal/tracker"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 本模块产生的通知事件类型（其余来自报价跟踪）
+const (
+	EventDeadlineReminder = "inquiry.deadline_reminder" // 询价单截止前提醒（尚未提交报价）
+	EventInquiryPublished = "inquiry.published"         // ERP 发布了邀请该供应商报价的询价单
+)
+
+// eventTypes 可通知的事件类型，偏好设置只接受这些类型
+var eventTypes = map[string]bool{
+	EventDeadlineReminder:           true,
+	EventInquiryPublished:           true,
+	tracker.EventQuoteAwarded:       true,
+	tracker.EventQuoteRejected:      true,
+	tracker.EventQuoteStatusChanged: true,
+	tracker.EventInquiryClosed:      true,
+	tracker.EventDeadlineChanged:    true,
+}
+
+// 投递状态
+const (
+	StatusPending = "pending" // 待发送或等待重试
+	StatusSent    = "sent"    // 已发送
+	StatusFailed  = "failed"  // 超过重试次数或不可重试的错误
+	StatusSkipped = "skipped" // 未发送（用户未订阅、无 openid 等）
+)
+
+// 订阅结果（wx.requestSubscribeMessage 返回值）
+const (
+	SubscribeAccept = "accept"
+	SubscribeReject = "reject"
+	SubscribeBan    = "ban"
+)
+
+// Preference 用户按事件类型的通知开关，没有记录时默认开启
+type Preference struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    string    `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_notification_pref"`
+	EventType string    `json:"eventType" gorm:"type:varchar(40);not null;uniqueIndex:idx_notification_pref"`
+	Enabled   bool      `json:"enabled" gorm:"not null"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Preference) TableName() string {
+	return "notification_preferences"
+}
+
+// TemplateSubscription 用户对订阅消息模板的授权
+// 一次性订阅消息每次用户同意只能发送一条，Remaining 记录剩余可发送次数
+type TemplateSubscription struct {
+	ID         string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID     string    `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_template_subscription"`
+	TemplateID string    `json:"templateId" gorm:"type:varchar(64);not null;uniqueIndex:idx_template_subscription"`
+	Status     string    `json:"status" gorm:"type:varchar(20);not null"` // 最近一次的订阅结果
+	Remaining  int       `json:"remaining" gorm:"not null;default:0"`
+	UpdatedAt  time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (TemplateSubscription) TableName() string {
+	return "notification_subscriptions"
+}
+
+// Delivery 一条通知的投递记录，(DedupKey, UserID, Channel) 唯一，同一事件不会重复通知
+type Delivery struct {
+	ID            string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID        string         `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_notification_dedup,priority:2;index"`
+	CustomerCode  string         `json:"customerCode" gorm:"type:varchar(50);index"`
+	EventType     string         `json:"eventType" gorm:"type:varchar(40);not null"`
+	DedupKey      string         `json:"-" gorm:"type:varchar(100);not null;uniqueIndex:idx_notification_dedup,priority:1"`
+	Channel       string         `json:"channel" gorm:"type:varchar(20);not null;uniqueIndex:idx_notification_dedup,priority:3"`
+	TemplateID    string         `json:"templateId" gorm:"type:varchar(64)"`
+	Page          string         `json:"page" gorm:"type:varchar(255)"`
+	Data          datatypes.JSON `json:"data" gorm:"type:jsonb"` // 渲染后的模板字段
+	Status        string         `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	LastError     string         `json:"lastError" gorm:"type:varchar(500)"`
+	NextAttemptAt *time.Time     `json:"nextAttemptAt" gorm:"index"`
+	SentAt        *time.Time     `json:"sentAt"`
+	CreatedAt     time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Delivery) TableName() string {
+	return "notification_deliveries"
+}
+
+// Notification 待发送的通知，Vars 用于渲染模板
+type Notification struct {
+	EventType    string
+	DedupKey     string // 同一事件的唯一标识，如 quote_event:<id>
+	CustomerCode string
+	Vars         map[string]string
+}
+
+// ========== Request ==========
+
+// SubscriptionRequest 小程序上报 wx.requestSubscribeMessage 的结果
+type SubscriptionRequest struct {
+	Results map[string]string `json:"results" binding:"required"` // templateId -> accept / reject / ban
+}
+
+// PreferencesRequest 更新通知开关
+type PreferencesRequest struct {
+	Preferences map[string]bool `json:"preferences" binding:"required"` // eventType -> 是否通知
+}
+
+// ========== Response ==========
+
+// TemplateView 小程序申请订阅时需要的模板信息
+type TemplateView struct {
+	EventType  string `json:"eventType"`
+	TemplateID string `json:"templateId"`
+	Remaining  int    `json:"remaining"` // 当前用户剩余可接收次数
+}
+
+// TemplatesResponse 模板列表响应
+type TemplatesResponse struct {
+	Code    int            `json:"code"`
+	Message string         `json:"message"`
+	Data    []TemplateView `json:"data"`
+}
+
+// PreferencesResponse 通知开关响应
+type PreferencesResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    map[string]bool `json:"data"`
+}
+
+// DeliveriesResponse 通知记录响应
+type DeliveriesResponse struct {
+	Code    int        `json:"code"`
+	Message string     `json:"message"`
+	Data    []Delivery `json:"data"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,211 @@
+package notify
+
+import (
+	"back/internal/user"
+	"back/pkg/middleware"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 通知处理器
+type Handler struct {
+	service     Service
+	userService user.Service
+}
+
+// NewHandler 创建通知处理器
+func NewHandler(service Service, userService user.Service) *Handler {
+	return &Handler{
+		service:     service,
+		userService: userService,
+	}
+}
+
+// Templates 可订阅的消息模板
+// @Summary 可订阅的消息模板
+// @Description 已配置模板的事件类型、模板ID及当前用户剩余可接收次数，小程序据此调用 wx.requestSubscribeMessage
+// @Tags notification
+// @Produce json
+// @Success 200 {object} TemplatesResponse
+// @Router /api/v1/notifications/templates [get]
+func (h *Handler) Templates(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	views, err := h.service.Templates(c.Request.Context(), u.ID)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取模板失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "notification.templates", u.ID, nil)
+	c.JSON(http.StatusOK, TemplatesResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    views,
+	})
+}
+
+// RecordSubscriptions 上报订阅结果
+// @Summary 上报订阅结果
+// @Description 小程序把 wx.requestSubscribeMessage 的结果原样上报，accept 增加一次可接收次数
+// @Tags notification
+// @Accept json
+// @Produce json
+// @Param request body SubscriptionRequest true "templateId -> accept/reject/ban"
+// @Success 200 {object} TemplatesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/notifications/subscriptions [post]
+func (h *Handler) RecordSubscriptions(c *gin.Context) {
+	var req SubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.writeError(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if err := h.service.RecordSubscriptions(c.Request.Context(), u.ID, req.Results); err != nil {
+		h.writeError(c, http.StatusInternalServerError, "记录订阅失败: "+err.Error())
+		return
+	}
+	views, err := h.service.Templates(c.Request.Context(), u.ID)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取模板失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "notification.subscribe", u.ID, map[string]any{"results": req.Results})
+	c.JSON(http.StatusOK, TemplatesResponse{
+		Code:    http.StatusOK,
+		Message: "记录成功",
+		Data:    views,
+	})
+}
+
+// GetPreferences 通知开关
+// @Summary 通知开关
+// @Description 按事件类型的通知开关，未设置的默认开启
+// @Tags notification
+// @Produce json
+// @Success 200 {object} PreferencesResponse
+// @Router /api/v1/notifications/preferences [get]
+func (h *Handler) GetPreferences(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	prefs, err := h.service.GetPreferences(c.Request.Context(), u.ID)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取通知设置失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "notification.get_preferences", u.ID, nil)
+	c.JSON(http.StatusOK, PreferencesResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    prefs,
+	})
+}
+
+// UpdatePreferences 更新通知开关
+// @Summary 更新通知开关
+// @Description 只更新请求中出现的事件类型
+// @Tags notification
+// @Accept json
+// @Produce json
+// @Param request body PreferencesRequest true "eventType -> 是否通知"
+// @Success 200 {object} PreferencesResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/notifications/preferences [put]
+func (h *Handler) UpdatePreferences(c *gin.Context) {
+	var req PreferencesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.writeError(c, http.StatusBadRequest, "请求参数错误: "+err.Error())
+		return
+	}
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	if err := h.service.UpdatePreferences(c.Request.Context(), u.ID, req.Preferences); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrUnknownEventType) {
+			status = http.StatusBadRequest
+		}
+		h.writeError(c, status, err.Error())
+		return
+	}
+	prefs, err := h.service.GetPreferences(c.Request.Context(), u.ID)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取通知设置失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "notification.update_preferences", u.ID, map[string]any{"preferences": req.Preferences})
+	c.JSON(http.StatusOK, PreferencesResponse{
+		Code:    http.StatusOK,
+		Message: "更新成功",
+		Data:    prefs,
+	})
+}
+
+// ListDeliveries 最近的通知记录
+// @Summary 最近的通知记录
+// @Description 当前用户最近 50 条通知及发送状态（pending/sent/failed/skipped）
+// @Tags notification
+// @Produce json
+// @Success 200 {object} DeliveriesResponse
+// @Router /api/v1/notifications/deliveries [get]
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), u.ID)
+	if err != nil {
+		h.writeError(c, http.StatusInternalServerError, "获取通知记录失败: "+err.Error())
+		return
+	}
+	h.setAuditInfo(c, "notification.list_deliveries", u.ID, nil)
+	c.JSON(http.StatusOK, DeliveriesResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    deliveries,
+	})
+}
+
+// currentUser 取当前登录用户，失败时已写出响应
+func (h *Handler) currentUser(c *gin.Context) (*user.User, bool) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		h.writeError(c, http.StatusUnauthorized, "用户未登录")
+		return nil, false
+	}
+	u, err := h.userService.GetUserByUsername(c.Request.Context(), rc.Username)
+	if err != nil {
+		h.writeError(c, http.StatusUnauthorized, "用户不存在")
+		return nil, false
+	}
+	return u, true
+}
+
+func (h *Handler) writeError(c *gin.Context, status int, message string) {
+	c.JSON(status, ErrorResponse{
+		Code:    status,
+		Message: message,
+	})
+}
+
+// setAuditInfo 设置审计信息
+func (h *Handler) setAuditInfo(c *gin.Context, action, userID string, detail map[string]any) {
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = action
+		rc.Resource = "notification"
+		rc.ResourceID = userID
+		if detail != nil {
+			rc.Detail = detail
+		}
+	}
+}
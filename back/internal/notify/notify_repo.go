@@ -0,0 +1,153 @@
+package notify
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 通知数据访问
+type Repository interface {
+	// 偏好
+	ListPreferences(ctx context.Context, userID string) ([]Preference, error)
+	SavePreferences(ctx context.Context, userID string, prefs map[string]bool) error
+
+	// 模板订阅
+	ListSubscriptions(ctx context.Context, userID string) ([]TemplateSubscription, error)
+	GetSubscription(ctx context.Context, userID, templateID string) (*TemplateSubscription, error)
+	RecordSubscription(ctx context.Context, userID, templateID, status string) error
+	ConsumeSubscription(ctx context.Context, userID, templateID string) error
+	ClearSubscription(ctx context.Context, userID, templateID string) error
+
+	// 投递
+	// InsertDelivery 按 (DedupKey, UserID, Channel) 去重插入，已存在时返回 inserted=false
+	InsertDelivery(ctx context.Context, d *Delivery) (inserted bool, err error)
+	ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error)
+	ListDeliveriesByUser(ctx context.Context, userID string, limit int) ([]Delivery, error)
+	MarkDelivery(ctx context.Context, id, status, lastError string, nextAttemptAt, sentAt *time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建通知仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListPreferences(ctx context.Context, userID string) ([]Preference, error) {
+	var prefs []Preference
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&prefs).Error
+	return prefs, err
+}
+
+func (r *repository) SavePreferences(ctx context.Context, userID string, prefs map[string]bool) error {
+	if len(prefs) == 0 {
+		return nil
+	}
+	rows := make([]Preference, 0, len(prefs))
+	for eventType, enabled := range prefs {
+		rows = append(rows, Preference{UserID: userID, EventType: eventType, Enabled: enabled})
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "event_type"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_at"}),
+	}).Create(&rows).Error
+}
+
+func (r *repository) ListSubscriptions(ctx context.Context, userID string) ([]TemplateSubscription, error) {
+	var subs []TemplateSubscription
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&subs).Error
+	return subs, err
+}
+
+func (r *repository) GetSubscription(ctx context.Context, userID, templateID string) (*TemplateSubscription, error) {
+	var sub TemplateSubscription
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND template_id = ?", userID, templateID).
+		First(&sub).Error
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// RecordSubscription accept 增加一次可发送次数，ban 清零，reject 只记录状态
+func (r *repository) RecordSubscription(ctx context.Context, userID, templateID, status string) error {
+	sub := &TemplateSubscription{UserID: userID, TemplateID: templateID, Status: status}
+	update := map[string]interface{}{"status": status, "updated_at": time.Now()}
+	switch status {
+	case SubscribeAccept:
+		sub.Remaining = 1
+		update["remaining"] = gorm.Expr("notification_subscriptions.remaining + 1")
+	case SubscribeBan:
+		update["remaining"] = 0
+	}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "template_id"}},
+		DoUpdates: clause.Assignments(update),
+	}).Create(sub).Error
+}
+
+func (r *repository) ConsumeSubscription(ctx context.Context, userID, templateID string) error {
+	return r.db.WithContext(ctx).Model(&TemplateSubscription{}).
+		Where("user_id = ? AND template_id = ? AND remaining > 0", userID, templateID).
+		Update("remaining", gorm.Expr("remaining - 1")).Error
+}
+
+func (r *repository) ClearSubscription(ctx context.Context, userID, templateID string) error {
+	return r.db.WithContext(ctx).Model(&TemplateSubscription{}).
+		Where("user_id = ? AND template_id = ?", userID, templateID).
+		Update("remaining", 0).Error
+}
+
+func (r *repository) InsertDelivery(ctx context.Context, d *Delivery) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "dedup_key"}, {Name: "user_id"}, {Name: "channel"}},
+			DoNothing: true,
+		}).
+		Create(d)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// ListDueDeliveries 待发送或到了重试时间的投递，按创建顺序
+func (r *repository) ListDueDeliveries(ctx context.Context, now time.Time, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)", StatusPending, now).
+		Order("created_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *repository) ListDeliveriesByUser(ctx context.Context, userID string, limit int) ([]Delivery, error) {
+	var deliveries []Delivery
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *repository) MarkDelivery(ctx context.Context, id, status, lastError string, nextAttemptAt, sentAt *time.Time) error {
+	if len(lastError) > 500 {
+		lastError = lastError[:500]
+	}
+	return r.db.WithContext(ctx).Model(&Delivery{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      strings.ToValidUTF8(lastError, ""),
+		"next_attempt_at": nextAttemptAt,
+		"sent_at":         sentAt,
+	}).Error
+}
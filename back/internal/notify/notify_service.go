@@ -0,0 +1,348 @@
+package notify
+
+import (
+	"back/internal/supplier"
+	"back/internal/user"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxAttempts 最多发送次数，超过后标记为 failed
+	maxAttempts = 5
+	// retryBase 重试退避基数（1m, 2m, 4m...）
+	retryBase = time.Minute
+	// pollInterval 后台扫描待发送通知的间隔
+	pollInterval = 15 * time.Second
+	batchSize    = 50
+	// maxDeliveries 用户查看通知记录的条数上限
+	maxDeliveries = 50
+)
+
+var ErrUnknownEventType = errors.New("unknown event type")
+
+// Service 通知：按模板渲染、按偏好过滤后落库，后台经渠道发送并重试
+type Service interface {
+	// Notify 通知单个用户，同一 DedupKey 只通知一次
+	Notify(ctx context.Context, userID string, n *Notification) error
+	// NotifySupplier 通知供应商的全部成员
+	NotifySupplier(ctx context.Context, customerCode string, n *Notification) error
+
+	Templates(ctx context.Context, userID string) ([]TemplateView, error)
+	RecordSubscriptions(ctx context.Context, userID string, results map[string]string) error
+	GetPreferences(ctx context.Context, userID string) (map[string]bool, error)
+	UpdatePreferences(ctx context.Context, userID string, prefs map[string]bool) error
+	ListDeliveries(ctx context.Context, userID string) ([]Delivery, error)
+
+	Start()
+	Stop()
+}
+
+type service struct {
+	repo            Repository
+	channel         Channel
+	templates       map[string]Template
+	userService     user.Service
+	supplierService supplier.Service
+	appID           string // 小程序 AppID，按它取用户的小程序 openid
+
+	wake     chan struct{}
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// NewService 创建通知服务，appID 为发送订阅消息的小程序 AppID
+func NewService(repo Repository, channel Channel, templates map[string]Template, userService user.Service, supplierService supplier.Service, appID string) Service {
+	return &service{
+		repo:            repo,
+		channel:         channel,
+		templates:       templates,
+		userService:     userService,
+		supplierService: supplierService,
+		appID:           appID,
+		wake:            make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+	}
+}
+
+func (s *service) Notify(ctx context.Context, userID string, n *Notification) error {
+	tmpl, ok := s.templates[n.EventType]
+	if !ok {
+		return nil // 未配置模板的事件不通知
+	}
+
+	prefs, err := s.GetPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !prefs[n.EventType] {
+		return nil
+	}
+
+	data, err := json.Marshal(tmpl.render(n.Vars))
+	if err != nil {
+		return err
+	}
+	d := &Delivery{
+		UserID:       userID,
+		CustomerCode: n.CustomerCode,
+		EventType:    n.EventType,
+		DedupKey:     n.DedupKey,
+		Channel:      s.channel.Name(),
+		TemplateID:   tmpl.TemplateID,
+		Page:         tmpl.Page,
+		Data:         datatypes.JSON(data),
+		Status:       StatusPending,
+	}
+	inserted, err := s.repo.InsertDelivery(ctx, d)
+	if err != nil {
+		return err
+	}
+	if inserted {
+		select {
+		case s.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *service) NotifySupplier(ctx context.Context, customerCode string, n *Notification) error {
+	if _, ok := s.templates[n.EventType]; !ok {
+		return nil
+	}
+	members, err := s.supplierService.ListMembers(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+	n.CustomerCode = customerCode
+	var errs []error
+	for _, m := range members {
+		if err := s.Notify(ctx, m.UserID, n); err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", m.UserID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Templates 已配置模板的事件及当前用户剩余可接收次数，小程序据此调用 wx.requestSubscribeMessage
+func (s *service) Templates(ctx context.Context, userID string) ([]TemplateView, error) {
+	subs, err := s.repo.ListSubscriptions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	remaining := make(map[string]int, len(subs))
+	for _, sub := range subs {
+		remaining[sub.TemplateID] = sub.Remaining
+	}
+
+	views := make([]TemplateView, 0, len(s.templates))
+	for eventType, t := range s.templates {
+		views = append(views, TemplateView{
+			EventType:  eventType,
+			TemplateID: t.TemplateID,
+			Remaining:  remaining[t.TemplateID],
+		})
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].EventType < views[j].EventType })
+	return views, nil
+}
+
+// RecordSubscriptions 记录订阅结果，只接受已配置的模板
+func (s *service) RecordSubscriptions(ctx context.Context, userID string, results map[string]string) error {
+	known := make(map[string]bool, len(s.templates))
+	for _, t := range s.templates {
+		known[t.TemplateID] = true
+	}
+	for templateID, status := range results {
+		if !known[templateID] {
+			continue
+		}
+		if status != SubscribeAccept && status != SubscribeReject && status != SubscribeBan {
+			continue
+		}
+		if err := s.repo.RecordSubscription(ctx, userID, templateID, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetPreferences 返回全部事件类型的开关，未设置的默认开启
+func (s *service) GetPreferences(ctx context.Context, userID string) (map[string]bool, error) {
+	rows, err := s.repo.ListPreferences(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	prefs := make(map[string]bool, len(eventTypes))
+	for eventType := range eventTypes {
+		prefs[eventType] = true
+	}
+	for _, p := range rows {
+		if eventTypes[p.EventType] {
+			prefs[p.EventType] = p.Enabled
+		}
+	}
+	return prefs, nil
+}
+
+func (s *service) UpdatePreferences(ctx context.Context, userID string, prefs map[string]bool) error {
+	for eventType := range prefs {
+		if !eventTypes[eventType] {
+			return fmt.Errorf("%w: %s", ErrUnknownEventType, eventType)
+		}
+	}
+	return s.repo.SavePreferences(ctx, userID, prefs)
+}
+
+func (s *service) ListDeliveries(ctx context.Context, userID string) ([]Delivery, error) {
+	return s.repo.ListDeliveriesByUser(ctx, userID, maxDeliveries)
+}
+
+// Start 启动后台发送，启动时会接着处理上次未发送完的通知
+func (s *service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			s.sendDue()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台发送，等待当前批次处理完
+func (s *service) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.wg.Wait()
+}
+
+// sendDue 发送到期的通知，直到没有待发送通知或收到停止信号
+func (s *service) sendDue() {
+	for {
+		deliveries, err := s.repo.ListDueDeliveries(context.Background(), time.Now(), batchSize)
+		if err != nil {
+			log.Printf("[NOTIFY] 查询待发送通知失败: %v", err)
+			return
+		}
+		for i := range deliveries {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			s.send(&deliveries[i])
+		}
+		if len(deliveries) < batchSize {
+			return
+		}
+	}
+}
+
+// send 发送一条通知并记录结果
+func (s *service) send(d *Delivery) {
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	status, lastError, next, sentAt := s.attempt(ctx, d)
+	if err := s.repo.MarkDelivery(ctx, d.ID, status, lastError, next, sentAt); err != nil {
+		log.Printf("[NOTIFY] 记录通知 %s 结果失败: %v", d.ID, err)
+	}
+}
+
+// attempt 执行一次发送，返回应记录的状态
+func (s *service) attempt(ctx context.Context, d *Delivery) (status, lastError string, next, sentAt *time.Time) {
+	if _, err := s.userService.GetUser(ctx, d.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return StatusSkipped, "用户不存在", nil, nil
+		}
+		return s.retry(d, err)
+	}
+	openID, err := s.recipient(ctx, d.UserID)
+	if err != nil {
+		return s.retry(d, err)
+	}
+	if openID == "" {
+		return StatusSkipped, "用户没有该小程序的微信身份（未在小程序登录过）", nil, nil
+	}
+
+	if s.channel.NeedsSubscription() {
+		sub, err := s.repo.GetSubscription(ctx, d.UserID, d.TemplateID)
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return s.retry(d, err)
+		}
+		if sub == nil || sub.Remaining <= 0 {
+			return StatusSkipped, "用户未订阅该模板", nil, nil
+		}
+	}
+
+	var data map[string]string
+	_ = json.Unmarshal(d.Data, &data)
+	err = s.channel.Send(ctx, &Message{
+		UserID:     d.UserID,
+		OpenID:     openID,
+		EventType:  d.EventType,
+		TemplateID: d.TemplateID,
+		Page:       d.Page,
+		Data:       data,
+	})
+	if err != nil {
+		if errors.Is(err, ErrPermanent) {
+			// 微信侧已无可用次数，本地计数同步清零
+			if s.channel.NeedsSubscription() {
+				_ = s.repo.ClearSubscription(ctx, d.UserID, d.TemplateID)
+			}
+			return StatusFailed, err.Error(), nil, nil
+		}
+		return s.retry(d, err)
+	}
+
+	if s.channel.NeedsSubscription() {
+		if err := s.repo.ConsumeSubscription(ctx, d.UserID, d.TemplateID); err != nil {
+			log.Printf("[NOTIFY] 扣减订阅次数失败: user=%s template=%s err=%v", d.UserID, d.TemplateID, err)
+		}
+	}
+	now := time.Now()
+	return StatusSent, "", nil, &now
+}
+
+// recipient 取用户在小程序下的 openid（同一用户可能还有公众号等其他应用的身份），没有时返回空
+func (s *service) recipient(ctx context.Context, userID string) (string, error) {
+	identities, err := s.userService.ListWechatIdentities(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	for _, identity := range identities {
+		if identity.AppID == s.appID {
+			return identity.OpenID, nil
+		}
+	}
+	return "", nil
+}
+
+// retry 可重试的失败：未超过次数时按退避安排下次发送
+func (s *service) retry(d *Delivery, err error) (string, string, *time.Time, *time.Time) {
+	if d.Attempts+1 >= maxAttempts {
+		log.Printf("[NOTIFY] 通知 %s(%s) 重试 %d 次仍失败，已放弃: %v", d.ID, d.EventType, maxAttempts, err)
+		return StatusFailed, err.Error(), nil, nil
+	}
+	next := time.Now().Add(retryBase << d.Attempts)
+	return StatusPending, err.Error(), &next, nil
+}
@@ -0,0 +1,70 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Template 事件类型对应的订阅消息模板
+// Data 的值可以包含 {{var}} 占位符，发送时用 Notification.Vars 替换
+type Template struct {
+	TemplateID string            `json:"templateId"`
+	Page       string            `json:"page"`
+	Data       map[string]string `json:"data"`
+}
+
+// LoadTemplates 从 JSON 文件加载模板配置：{"quote.awarded": {"templateId": "...", "page": "...", "data": {...}}}
+// path 为空时返回空配置（不发送任何通知）
+func LoadTemplates(path string) (map[string]Template, error) {
+	templates := make(map[string]Template)
+	if path == "" {
+		return templates, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取通知模板配置失败: %w", err)
+	}
+	if err := json.Unmarshal(raw, &templates); err != nil {
+		return nil, fmt.Errorf("解析通知模板配置失败: %w", err)
+	}
+	for eventType, t := range templates {
+		if !eventTypes[eventType] {
+			return nil, fmt.Errorf("通知模板配置了未知事件类型: %s", eventType)
+		}
+		if t.TemplateID == "" {
+			return nil, fmt.Errorf("事件 %s 的 templateId 为空", eventType)
+		}
+	}
+	return templates, nil
+}
+
+// fieldLimits 订阅消息各类字段的长度上限（按字符计），超出时微信会拒绝整条消息
+var fieldLimits = map[string]int{
+	"thing":            20,
+	"phrase":           5,
+	"character_string": 32,
+	"name":             10,
+}
+
+// render 用 vars 替换模板字段中的占位符，并按字段类型截断
+func (t *Template) render(vars map[string]string) map[string]string {
+	pairs := make([]string, 0, len(vars)*2)
+	for k, v := range vars {
+		pairs = append(pairs, "{{"+k+"}}", v)
+	}
+	replacer := strings.NewReplacer(pairs...)
+
+	data := make(map[string]string, len(t.Data))
+	for key, value := range t.Data {
+		value = replacer.Replace(value)
+		kind := strings.TrimRight(key, "0123456789")
+		if limit, ok := fieldLimits[kind]; ok && utf8.RuneCountInString(value) > limit {
+			value = string([]rune(value)[:limit])
+		}
+		data[key] = value
+	}
+	return data
+}
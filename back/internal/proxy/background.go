@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// 后台任务（报价跟踪、截止提醒）直接调用内部系统存储过程，不经过成员角色校验，
+// 调用前经内部 token 管理器限流，避免批量轮询挤占前端请求
+
+// QuotedInquiries 查询供应商已报价的采购单（Pur_InquiryBySupplierQuoted），返回原始数据行
+func (p *InternalProxy) QuotedInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error) {
+	return p.procedureRows(ctx, "Pur_InquiryBySupplierQuoted", map[string]interface{}{
+		"Supplier": customerCode,
+	})
+}
+
+// OpenInquiries 查询供应商当前可报价（未截止）的招标信息（Pur_InquiryQueryForSupplier），返回原始数据行
+func (p *InternalProxy) OpenInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error) {
+	return p.procedureRows(ctx, "Pur_InquiryQueryForSupplier", map[string]interface{}{
+		"Supplier":   customerCode,
+		"P_chnName":  "",
+		"BeginDate":  "",
+		"EndDate":    "",
+		"IncludeEnd": "0",
+	})
+}
+
+// procedureRows 限流后调用存储过程，解析出 data 行
+func (p *InternalProxy) procedureRows(ctx context.Context, code string, pars map[string]interface{}) ([]json.RawMessage, error) {
+	if err := p.tokenManager.Wait(ctx); err != nil {
+		return nil, err
+	}
+
+	status, body, perr := p.callInternal(code, pars, map[string]interface{}{})
+	if perr != nil {
+		return nil, errors.New(perr.Message)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("内部系统返回 HTTP %d", status)
+	}
+
+	var result procedureResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析内部响应失败: %w", err)
+	}
+	if !result.IsSucceed {
+		msg := "内部系统返回失败"
+		if result.Message != nil && *result.Message != "" {
+			msg = *result.Message
+		}
+		return nil, errors.New(msg)
+	}
+	return result.Data, nil
+}
@@ -0,0 +1,210 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// 绑定短信验证参数
+const (
+	bindCodeTTL         = 5 * time.Minute  // 验证码有效期
+	bindCodeCooldown    = time.Minute      // 同一用户重发间隔
+	bindCodeMaxAttempts = 5                // 单个验证码最多尝试次数
+	bindFailureWindow   = 15 * time.Minute // 失败计数窗口
+	bindFailureLimit    = 5                // 窗口内允许的失败次数（按用户）
+	bindLockDuration    = 30 * time.Minute // 超限后锁定时长
+)
+
+var (
+	errBindLocked   = errors.New("绑定尝试次数过多，请稍后再试")
+	errBindCooldown = errors.New("验证码发送过于频繁，请稍后再试")
+	errBindNoCode   = errors.New("请先获取验证码")
+	errBindExpired  = errors.New("验证码已过期，请重新获取")
+	errBindMismatch = errors.New("验证码错误")
+)
+
+// pendingBind 已发送验证码、等待确认的绑定
+type pendingBind struct {
+	CustomerCode string
+	Openid       string
+	Type         string
+	Mobile       string // 内部系统登记的手机号
+	Code         string
+	Attempts     int
+	ExpiresAt    time.Time
+}
+
+// bindFailure 按用户统计的失败次数
+type bindFailure struct {
+	Count       int
+	WindowStart time.Time
+	LockedUntil time.Time
+}
+
+// bindVerifier 绑定短信验证状态（内存，生产环境应使用 Redis）
+// 冷却和锁定都按用户计：按供应商编码计的话，知道编码的人就能不断触发冷却或故意输错，把真正的供应商锁在外面。
+// Openid 已校验归属当前用户，按用户计即覆盖了该用户名下的全部 openid
+type bindVerifier struct {
+	mu       sync.Mutex
+	pending  map[string]*pendingBind // userID -> 待确认绑定
+	lastSent map[string]time.Time    // userID -> 最近发送时间
+	failures map[string]*bindFailure // userID -> 失败统计
+}
+
+func newBindVerifier() *bindVerifier {
+	return &bindVerifier{
+		pending:  make(map[string]*pendingBind),
+		lastSent: make(map[string]time.Time),
+		failures: make(map[string]*bindFailure),
+	}
+}
+
+// locked 用户是否处于锁定期
+func (v *bindVerifier) locked(userID string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	f, ok := v.failures[userID]
+	return ok && now.Before(f.LockedUntil)
+}
+
+// issue 为用户生成验证码，同一用户在冷却期内不重发
+func (v *bindVerifier) issue(userID string, p pendingBind, now time.Time) (string, error) {
+	code, err := randomDigits(6)
+	if err != nil {
+		return "", err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if f, ok := v.failures[userID]; ok && now.Before(f.LockedUntil) {
+		return "", errBindLocked
+	}
+	if last, ok := v.lastSent[userID]; ok && now.Sub(last) < bindCodeCooldown {
+		return "", errBindCooldown
+	}
+
+	p.Code = code
+	p.ExpiresAt = now.Add(bindCodeTTL)
+	v.pending[userID] = &p
+	v.lastSent[userID] = now
+	return code, nil
+}
+
+// confirm 校验验证码，成功后移除待确认记录并清零失败计数
+// 失败时返回 alert=true 表示本次失败触发了用户锁定
+func (v *bindVerifier) confirm(userID, customerCode, openid, code string, now time.Time) (p *pendingBind, alert bool, err error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if f, ok := v.failures[userID]; ok && now.Before(f.LockedUntil) {
+		return nil, false, errBindLocked
+	}
+
+	p, ok := v.pending[userID]
+	if !ok || p.CustomerCode != customerCode || p.Openid != openid {
+		return nil, false, errBindNoCode
+	}
+	if now.After(p.ExpiresAt) {
+		delete(v.pending, userID)
+		return nil, false, errBindExpired
+	}
+
+	if subtle.ConstantTimeCompare([]byte(p.Code), []byte(code)) != 1 {
+		p.Attempts++
+		if p.Attempts >= bindCodeMaxAttempts {
+			delete(v.pending, userID)
+		}
+		return nil, v.recordFailure(userID, now), errBindMismatch
+	}
+
+	delete(v.pending, userID)
+	delete(v.failures, userID)
+	return p, false, nil
+}
+
+// recordFailure 记录一次失败，达到上限时锁定并返回 true（调用方需持有锁）
+func (v *bindVerifier) recordFailure(userID string, now time.Time) bool {
+	f, ok := v.failures[userID]
+	if !ok || now.Sub(f.WindowStart) > bindFailureWindow {
+		f = &bindFailure{WindowStart: now}
+		v.failures[userID] = f
+	}
+	f.Count++
+	if f.Count >= bindFailureLimit && !now.Before(f.LockedUntil) {
+		f.LockedUntil = now.Add(bindLockDuration)
+		f.Count = 0
+		f.WindowStart = now
+		return true
+	}
+	return false
+}
+
+// cleanup 清理过期的验证码和失败统计
+func (v *bindVerifier) cleanup(now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for userID, p := range v.pending {
+		if now.After(p.ExpiresAt) {
+			delete(v.pending, userID)
+		}
+	}
+	for userID, last := range v.lastSent {
+		if now.Sub(last) > bindCodeCooldown {
+			delete(v.lastSent, userID)
+		}
+	}
+	for userID, f := range v.failures {
+		if now.After(f.LockedUntil) && now.Sub(f.WindowStart) > bindFailureWindow {
+			delete(v.failures, userID)
+		}
+	}
+}
+
+// queryCustomer 调用 BC_Customer_GetByCode 查询供应商在内部系统登记的手机号
+// 返回 nil 表示供应商编码不存在；参数和返回见 GetByCodeParams / GetByCodeResponse（待 ERP 团队确认，
+// 确认前不启用短信验证，见 NewInternalProxy）
+func (p *InternalProxy) queryCustomer(customerCode string) (*supplierBinding, error) {
+	status, body, perr := p.callInternal("BC_Customer_GetByCode", map[string]interface{}{
+		"CustomerCode": customerCode,
+	}, map[string]interface{}{})
+	if perr != nil {
+		return nil, errors.New(perr.Message)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("内部系统返回 HTTP %d", status)
+	}
+
+	var result procedureResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析内部响应失败: %w", err)
+	}
+	if !result.IsSucceed || len(result.Data) == 0 {
+		return nil, nil
+	}
+
+	var row GetByCodeRow
+	if err := json.Unmarshal(result.Data[0], &row); err != nil {
+		return nil, fmt.Errorf("解析供应商信息失败: %w", err)
+	}
+	if row.CustomerCode == "" {
+		row.CustomerCode = customerCode
+	}
+	return &supplierBinding{CustomerCode: row.CustomerCode, Mobile: row.Mobile}, nil
+}
+
+// randomDigits 生成 n 位随机数字
+func randomDigits(n int) (string, error) {
+	max := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
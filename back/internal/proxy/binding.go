@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"back/internal/user"
+	"back/pkg/jwt"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// 微信端类型（BC_Customer_* 的 Type 参数）
+const (
+	wechatTypeOfficialAccount = "0" // 公众号
+	wechatTypeMiniProgram     = "1" // 小程序
+)
+
+// procedureResult 内部系统存储过程的通用响应（仅解析绑定流程用到的字段）
+type procedureResult struct {
+	IsSucceed bool              `json:"isSucceed"`
+	Message   *string           `json:"message"`
+	Data      []json.RawMessage `json:"data"`
+	OutData   struct {
+		IntRetVal  json.RawMessage `json:"intRetVal"`
+		StrMessage string          `json:"strMessage"`
+	} `json:"outData"`
+}
+
+// retValSucceeded intRetVal 为 0 表示存储过程执行成功（内部系统可能返回数字或字符串）
+func (r *procedureResult) retValSucceeded() bool {
+	raw := strings.Trim(strings.TrimSpace(string(r.OutData.IntRetVal)), `"`)
+	if raw == "" || raw == "null" {
+		return r.IsSucceed
+	}
+	v, err := strconv.Atoi(raw)
+	return err == nil && r.IsSucceed && v == 0
+}
+
+// supplierBinding 内部系统中 openid 当前绑定的供应商
+type supplierBinding struct {
+	Bound        bool
+	CustomerCode string
+	Mobile       string
+}
+
+// queryBindings 调用 BC_Customer_GetByWeChat 查询 openid 当前绑定的供应商（可能有多个）
+func (p *InternalProxy) queryBindings(openid, wxType string) ([]supplierBinding, error) {
+	status, body, perr := p.callInternal("BC_Customer_GetByWeChat", map[string]interface{}{
+		"Openid": openid,
+		"Type":   wxType,
+	}, map[string]interface{}{})
+	if perr != nil {
+		return nil, errors.New(perr.Message)
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("内部系统返回 HTTP %d", status)
+	}
+
+	var result procedureResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("解析内部响应失败: %w", err)
+	}
+	if !result.IsSucceed {
+		return nil, nil
+	}
+
+	bindings := make([]supplierBinding, 0, len(result.Data))
+	for _, raw := range result.Data {
+		var row struct {
+			Mobile       string `json:"Mobile"`
+			CustomerCode string `json:"CustomerCode"`
+		}
+		if err := json.Unmarshal(raw, &row); err != nil {
+			return nil, fmt.Errorf("解析供应商信息失败: %w", err)
+		}
+		if row.CustomerCode == "" {
+			continue
+		}
+		bindings = append(bindings, supplierBinding{Bound: true, CustomerCode: row.CustomerCode, Mobile: row.Mobile})
+	}
+	return bindings, nil
+}
+
+// ownsOpenID 校验 openid 属于当前用户，防止替他人绑定/解绑
+func (p *InternalProxy) ownsOpenID(ctx context.Context, u *user.User, openid string) bool {
+	if openid == "" {
+		return false
+	}
+	if u.OpenID == openid {
+		return true
+	}
+	identities, err := p.userService.ListWechatIdentities(ctx, u.ID)
+	if err != nil {
+		return false
+	}
+	for _, identity := range identities {
+		if identity.OpenID == openid {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshBinding 重新查询内部系统的绑定关系，同步到本地用户并签发新 token
+// 绑定时以 preferred 为当前供应商并成为其负责人，原有供应商的成员身份保留（一个用户可代表多个供应商）；
+// 解绑时（preferred 为空）退出原供应商，并切换到仍有成员身份的其他供应商
+func (p *InternalProxy) refreshBinding(ctx context.Context, u *user.User, openid, wxType, preferred string) (*user.User, string, error) {
+	bindings, err := p.queryBindings(openid, wxType)
+	if err != nil {
+		return nil, "", fmt.Errorf("查询绑定关系失败: %w", err)
+	}
+
+	var active supplierBinding
+	stillBound := false
+	for _, b := range bindings {
+		if b.CustomerCode == preferred {
+			active = b
+		}
+		if b.CustomerCode == u.CustomerCode {
+			stillBound = true
+		}
+	}
+	if !active.Bound && len(bindings) > 0 {
+		active = bindings[0]
+	}
+
+	// 解绑后退出原供应商（受邀加入的成员身份不受微信绑定影响）
+	if preferred == "" && u.CustomerCode != "" && !stillBound {
+		member, err := p.supplierService.MemberFor(ctx, u.CustomerCode, u.ID)
+		if err == nil && member.InvitedBy == "" {
+			if err := p.supplierService.LeaveSupplier(ctx, u.CustomerCode, u.ID); err != nil {
+				log.Printf("[PROXY] 退出原供应商失败: username=%s customerCode=%s err=%v", u.Username, u.CustomerCode, err)
+			}
+		}
+	}
+
+	if active.Bound {
+		if _, err := p.supplierService.EnsureOwner(ctx, active.CustomerCode, u.ID); err != nil {
+			return nil, "", fmt.Errorf("设置供应商负责人失败: %w", err)
+		}
+	} else {
+		memberships, err := p.supplierService.ListMemberships(ctx, u.ID)
+		if err != nil {
+			return nil, "", fmt.Errorf("查询供应商成员身份失败: %w", err)
+		}
+		for i, m := range memberships {
+			if i == 0 || m.CustomerCode == u.CustomerCode {
+				active.CustomerCode = m.CustomerCode
+			}
+		}
+	}
+
+	updated, err := p.userService.UpdateBinding(ctx, u.ID, active.CustomerCode, active.Mobile)
+	if err != nil {
+		return nil, "", fmt.Errorf("更新本地绑定失败: %w", err)
+	}
+
+	token, err := p.jwtService.GenerateTokenWithClaims(&jwt.Claims{
+		Username:     updated.Username,
+		CustomerCode: updated.CustomerCode,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("生成 token 失败: %w", err)
+	}
+
+	log.Printf("[PROXY] 绑定关系已同步: username=%s customerCode=%s", updated.Username, updated.CustomerCode)
+	return updated, token, nil
+}
+
+// stringField 从请求体中取字符串字段
+func stringField(body map[string]interface{}, key string) string {
+	switch v := body[key].(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
+
+// withToken 在内部系统原始响应上附加新 token 和本地绑定信息
+func withToken(respBody []byte, token string, u *user.User) map[string]interface{} {
+	resp := map[string]interface{}{}
+	_ = json.Unmarshal(respBody, &resp)
+	resp["token"] = token
+	resp["customerCode"] = u.CustomerCode
+	return resp
+}
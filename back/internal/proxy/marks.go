@@ -0,0 +1,98 @@
+package proxy
+
+import (
+	"back/pkg/middleware"
+	"bytes"
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// inquiryIDFields 询价单数据行中询价单ID的字段名
+var inquiryIDFields = []string{"InquiryId", "Id"}
+
+// forwardWithMarks 与 forwardToInternal 相同，另在每行数据上附加当前用户的本地标记（mark: 关注/已读/备注），
+// 前端列表和详情无需额外请求；detailID 非空（查看详情）时在顶层附加该询价单的标记，并记为已读（客服代查看除外）
+func (p *InternalProxy) forwardWithMarks(c *gin.Context, code string, pars map[string]interface{}, outPars map[string]interface{}, detailID string) {
+	if !p.authorize(c, code, pars) {
+		return
+	}
+	status, respBody, perr := p.callInternal(code, pars, outPars)
+	if perr != nil {
+		writeProxyError(c, perr)
+		return
+	}
+	if status != http.StatusOK || p.inquiryService == nil {
+		c.Data(status, "application/json", respBody)
+		return
+	}
+
+	rc := middleware.GetRequestContext(c)
+	u, err := p.userService.GetUserByUsername(c.Request.Context(), rc.Username)
+	if err != nil {
+		c.Data(status, "application/json", respBody)
+		return
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(respBody))
+	dec.UseNumber()
+	var resp map[string]interface{}
+	if err := dec.Decode(&resp); err != nil {
+		c.Data(status, "application/json", respBody)
+		return
+	}
+
+	if detailID != "" && !rc.Impersonation {
+		if _, err := p.inquiryService.SetRead(c.Request.Context(), u.ID, u.CustomerCode, detailID, true); err != nil {
+			log.Printf("[PROXY] 标记已读失败: username=%s inquiry=%s err=%v", u.Username, detailID, err)
+		}
+	}
+
+	rows, _ := resp["data"].([]interface{})
+	ids := make([]string, 0, len(rows)+1)
+	if detailID != "" {
+		ids = append(ids, detailID)
+	}
+	for _, r := range rows {
+		if row, ok := r.(map[string]interface{}); ok {
+			if id := rowInquiryID(row); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	flags, err := p.inquiryService.Flags(c.Request.Context(), u.ID, u.CustomerCode, ids)
+	if err != nil {
+		log.Printf("[PROXY] 查询询价单标记失败: username=%s err=%v", u.Username, err)
+		c.Data(status, "application/json", respBody)
+		return
+	}
+	for _, r := range rows {
+		if row, ok := r.(map[string]interface{}); ok {
+			if id := rowInquiryID(row); id != "" {
+				row["mark"] = flags[id]
+			}
+		}
+	}
+	if detailID != "" {
+		resp["mark"] = flags[detailID]
+	}
+	c.JSON(status, resp)
+}
+
+// rowInquiryID 取数据行的询价单ID
+func rowInquiryID(row map[string]interface{}) string {
+	for _, key := range inquiryIDFields {
+		switch v := row[key].(type) {
+		case string:
+			if v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		}
+	}
+	return ""
+}
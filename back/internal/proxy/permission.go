@@ -0,0 +1,75 @@
+package proxy
+
+import (
+	"back/internal/supplier"
+	"back/pkg/middleware"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// procedureRoles 各存储过程要求的最低成员角色，未列出的过程不做角色校验（如绑定流程）
+var procedureRoles = map[string]string{
+	"Pur_InquiryQueryForSupplier":   supplier.RoleViewer,
+	"Pur_Inquiry_DetailForSupplier": supplier.RoleViewer,
+	"Pur_InquiryBySupplierQuoted":   supplier.RoleViewer,
+	"Pur_Inquiry_QuoteSave":         supplier.RoleQuoter,
+	"Pur_Inquiry_QuoteDelete":       supplier.RoleQuoter,
+}
+
+// authorize 校验当前用户在当前会话所代表供应商（取自 token）下的角色是否允许调用该存储过程，失败时已写出响应
+// 通过后将请求的 Supplier 参数限定为该供应商，防止越权查询其他供应商的数据
+func (p *InternalProxy) authorize(c *gin.Context, code string, pars map[string]interface{}) bool {
+	required, ok := procedureRoles[code]
+
+	// 员工代查看只允许只读过程
+	if rc := middleware.GetRequestContext(c); rc != nil && rc.Impersonation && (!ok || required != supplier.RoleViewer) {
+		log.Printf("[PROXY] 代查看模式拒绝调用: staff=%s user=%s procedure=%s", rc.StaffUsername, rc.Username, code)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "客服代查看模式为只读"})
+		return false
+	}
+
+	if !ok {
+		return true
+	}
+
+	u, ok := p.currentUser(c)
+	if !ok {
+		return false
+	}
+	customerCode := supplier.SessionCustomerCode(c, u)
+	if customerCode == "" {
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": "请先绑定供应商"})
+		return false
+	}
+
+	member, err := p.supplierService.ActiveMember(c.Request.Context(), customerCode, u.CustomerCode, u.ID)
+	if err != nil {
+		if errors.Is(err, supplier.ErrNotMember) {
+			c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": err.Error()})
+			return false
+		}
+		log.Printf("[PROXY] 查询成员角色失败: username=%s err=%v", u.Username, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"code": 500, "message": "查询成员角色失败"})
+		return false
+	}
+
+	if !supplier.RoleAtLeast(member.Role, required) {
+		log.Printf("[PROXY] 角色不足: username=%s customerCode=%s role=%s required=%s procedure=%s",
+			u.Username, customerCode, member.Role, required, code)
+		c.JSON(http.StatusForbidden, gin.H{"code": 403, "message": supplier.ErrPermissionDenied.Error()})
+		return false
+	}
+
+	if requested := stringField(pars, "Supplier"); requested != "" && requested != customerCode {
+		log.Printf("[PROXY] 请求的供应商与当前供应商不一致，已改写: username=%s requested=%s active=%s procedure=%s",
+			u.Username, requested, customerCode, code)
+	}
+	pars["Supplier"] = customerCode
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.CustomerCode = customerCode
+	}
+	return true
+}
@@ -18,13 +18,32 @@ type BaseResponse struct {
 // 1. 微信端绑定供应商
 // =============================================================================
 
-type BindWeChatRequest struct {
+// BindWeChatSendCodeRequest 绑定第一步：向内部系统登记的手机号发送验证码（仅启用短信验证时提供）
+type BindWeChatSendCodeRequest struct {
 	Openid       string `json:"Openid"       example:"oXxx123abc"`
 	CustomerCode string `json:"CustomerCode" example:"PD0201"`
-	Mobile       string `json:"Mobile"       example:"13800138000"`
 	Type         string `json:"Type"         example:"1"` // 0:公众号 1:小程序
 }
 
+// BindWeChatSendCodeResponse 发送绑定验证码响应
+type BindWeChatSendCodeResponse struct {
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	MobileMasked string `json:"mobileMasked" example:"138****8000"` // 接收验证码的手机号（脱敏）
+	ExpiresIn    int    `json:"expiresIn"    example:"300"`         // 验证码有效期（秒）
+}
+
+// BindWeChatRequest 绑定供应商
+// 启用短信验证时为第二步：提交 SmsCode，手机号取内部系统登记的号码，Mobile 不使用；
+// 未启用时提交 Mobile，由内部系统校验
+type BindWeChatRequest struct {
+	Openid       string `json:"Openid"       example:"oXxx123abc"`
+	CustomerCode string `json:"CustomerCode" example:"PD0201"`
+	Mobile       string `json:"Mobile"       example:"13800138000"` // 未启用短信验证时必填
+	Type         string `json:"Type"         example:"1"`           // 0:公众号 1:小程序（启用短信验证时以发送验证码时为准）
+	SmsCode      string `json:"SmsCode"      example:"123456"`      // 启用短信验证时必填
+}
+
 type BindWeChatOutData struct {
 	IntRetVal  int    `json:"intRetVal"`
 	StrMessage string `json:"strMessage"`
@@ -37,6 +56,8 @@ type BindWeChatResponse struct {
 	StatusCode int                `json:"statusCode"`
 	Data       interface{}        `json:"data"`    // TODO: 待补充
 	OutData    BindWeChatOutData  `json:"outData"`
+	Token        string           `json:"token"`        // 绑定成功后签发的新 token（携带供应商编码）
+	CustomerCode string           `json:"customerCode"` // 本地同步后的供应商编码
 }
 
 // =============================================================================
@@ -179,3 +200,37 @@ type QuoteSaveResponse struct {
 	Data       interface{}      `json:"data"`    // TODO: 待补充
 	OutData    QuoteSaveOutData `json:"outData"`
 }
+
+// =============================================================================
+// 8. 解除微信端绑定（BC_Customer_UnBindWeChat）
+// =============================================================================
+// 未列入内部系统已提供的存储过程清单，参数与返回无从确认，暂不提供解绑接口。
+// 需与 ERP 团队确认：过程是否存在、参数与 Type 取值、解绑不存在的绑定时 intRetVal 的取值；
+// 确认后按 BC_Customer_BindWeChat 的方式接入，成功后调用 refreshBinding（preferred 传空）同步本地绑定
+
+// =============================================================================
+// 9. 按供应商编码查询供应商（BC_Customer_GetByCode）
+// =============================================================================
+// 未列入内部系统已提供的存储过程清单，绑定发送验证码前用它取内部系统登记的手机号，
+// 返回行按 BC_Customer_GetByWeChat 的字段推定，需与 ERP 团队确认过程名和字段名；
+// 确认前 BIND_SMS_VERIFY 保持关闭，绑定走原流程（BC_Customer_BindWeChat 直接校验 Mobile）
+
+// GetByCodeParams 调用参数
+type GetByCodeParams struct {
+	CustomerCode string `json:"CustomerCode" example:"PD0201"`
+}
+
+// GetByCodeRow data 数组的一行；data 为空数组表示供应商编码不存在，只读取第一行
+type GetByCodeRow struct {
+	CustomerCode string `json:"CustomerCode" example:"PD0201"`      // 为空时按请求的编码处理
+	Mobile       string `json:"Mobile"       example:"13800138000"` // 接收绑定验证码的手机号
+}
+
+type GetByCodeResponse struct {
+	IsSucceed  bool           `json:"isSucceed"`
+	Message    *string        `json:"message"`
+	Time       int            `json:"time"`
+	StatusCode int            `json:"statusCode"`
+	Data       []GetByCodeRow `json:"data"`
+	OutData    interface{}    `json:"outData"`
+}
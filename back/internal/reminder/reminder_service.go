@@ -0,0 +1,310 @@
+package reminder
+
+import (
+	"back/internal/notify"
+	"back/internal/supplier"
+	"back/internal/tracker"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 可报价招标信息（Pur_InquiryQueryForSupplier）返回行的字段名，按已知命名依次尝试
+var (
+	fieldInquiryID = []string{"InquiryId", "Id"}
+	fieldTitle     = []string{"P_chnName", "Title", "InquiryNo"}
+	fieldEndDate   = []string{"EndDate"}
+)
+
+// Fetcher 拉取供应商的可报价/已报价记录（由内部系统代理实现，调用前需限流）
+type Fetcher interface {
+	OpenInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error)
+	QuotedInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error)
+}
+
+// Watchlist 本地关注列表，返回供应商下每个询价单的关注用户
+type Watchlist interface {
+	Watchers(ctx context.Context, customerCode string) (map[string][]string, error)
+}
+
+// Config 截止提醒配置
+type Config struct {
+	Offsets  []time.Duration // 截止前多久提醒，如 24h、2h
+	Interval time.Duration   // 检查周期
+	AllOpen  bool            // true 时所有可报价询价单都提醒全体成员；false 时只提醒关注了该询价单的用户
+}
+
+// Scheduler 截止提醒：定时检查各供应商未截止的询价单，在截止前的各个时间点提醒尚未提交报价的供应商
+type Scheduler struct {
+	fetcher         Fetcher
+	supplierService supplier.Service
+	notifyService   notify.Service
+	watchlist       Watchlist
+	cfg             Config
+
+	// handled 已处理过的提醒（dedupKey -> 截止时间），减少重复查询；过了截止时间后清理
+	mu      sync.Mutex
+	handled map[string]time.Time
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewScheduler 创建截止提醒调度器，watchlist 可以为 nil
+func NewScheduler(fetcher Fetcher, supplierService supplier.Service, notifyService notify.Service, watchlist Watchlist, cfg Config) *Scheduler {
+	offsets := append([]time.Duration(nil), cfg.Offsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+	cfg.Offsets = offsets
+	return &Scheduler{
+		fetcher:         fetcher,
+		supplierService: supplierService,
+		notifyService:   notifyService,
+		watchlist:       watchlist,
+		cfg:             cfg,
+		handled:         make(map[string]time.Time),
+		stop:            make(chan struct{}),
+	}
+}
+
+// ParseOffsets 解析逗号分隔的提醒时间点，如 "24h,2h"
+func ParseOffsets(s string) ([]time.Duration, error) {
+	var offsets []time.Duration
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		d, err := time.ParseDuration(part)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("无效的提醒时间点: %s", part)
+		}
+		offsets = append(offsets, d)
+	}
+	return offsets, nil
+}
+
+// Start 启动后台检查
+func (s *Scheduler) Start() {
+	if len(s.cfg.Offsets) == 0 {
+		log.Printf("[REMINDER] 未配置提醒时间点，截止提醒不启动")
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.Interval)
+		defer ticker.Stop()
+		for {
+			s.runOnce()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台检查，等待当前供应商处理完
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// runOnce 检查所有已绑定供应商
+func (s *Scheduler) runOnce() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	codes, err := s.supplierService.ListBoundCustomerCodes(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("[REMINDER] 查询已绑定供应商失败: %v", err)
+		return
+	}
+
+	now := time.Now()
+	s.forgetExpired(now)
+	for _, code := range codes {
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if err := s.checkSupplier(code, now); err != nil {
+			log.Printf("[REMINDER] 检查供应商 %s 失败: %v", code, err)
+		}
+	}
+}
+
+// dueReminder 一条到期的提醒
+type dueReminder struct {
+	inquiryID string
+	title     string
+	deadline  time.Time
+	offset    time.Duration
+	dedupKey  string
+	watchers  []string
+}
+
+func (s *Scheduler) checkSupplier(customerCode string, now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	// 停止时取消限流等待
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	var watchers map[string][]string
+	if s.watchlist != nil {
+		w, err := s.watchlist.Watchers(ctx, customerCode)
+		if err != nil {
+			return err
+		}
+		watchers = w
+	}
+	if !s.cfg.AllOpen && len(watchers) == 0 {
+		return nil
+	}
+
+	rows, err := s.fetcher.OpenInquiries(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+
+	var due []dueReminder
+	for _, raw := range rows {
+		row, err := tracker.DecodeRow(raw)
+		if err != nil {
+			return err
+		}
+		inquiryID := tracker.RowField(row, fieldInquiryID)
+		deadline, ok := tracker.ParseEndDate(tracker.RowField(row, fieldEndDate))
+		if inquiryID == "" || !ok {
+			continue
+		}
+		if !s.cfg.AllOpen && len(watchers[inquiryID]) == 0 {
+			continue
+		}
+		offset, ok := s.dueOffset(deadline, now)
+		if !ok {
+			continue
+		}
+		key := fmt.Sprintf("deadline:%s:%s:%s", customerCode, inquiryID, offset)
+		if s.isHandled(key) {
+			continue
+		}
+		due = append(due, dueReminder{
+			inquiryID: inquiryID,
+			title:     tracker.RowField(row, fieldTitle),
+			deadline:  deadline,
+			offset:    offset,
+			dedupKey:  key,
+			watchers:  watchers[inquiryID],
+		})
+	}
+	if len(due) == 0 {
+		return nil
+	}
+
+	// 只有存在待发提醒时才查询已报价记录，跳过已提交报价的询价单
+	quoted, err := s.fetcher.QuotedInquiries(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+	submitted, err := tracker.SubmittedInquiries(quoted)
+	if err != nil {
+		return err
+	}
+
+	for _, r := range due {
+		if !submitted[r.inquiryID] {
+			if err := s.remind(ctx, customerCode, &r); err != nil {
+				log.Printf("[REMINDER] 发送提醒 %s 失败: %v", r.dedupKey, err)
+				continue
+			}
+		}
+		s.markHandled(r.dedupKey, r.deadline)
+	}
+	return nil
+}
+
+// dueOffset 当前已进入的最近一个提醒时间点（如同时进入 24h 和 2h，只发 2h 的提醒）
+func (s *Scheduler) dueOffset(deadline, now time.Time) (time.Duration, bool) {
+	if !now.Before(deadline) {
+		return 0, false
+	}
+	left := deadline.Sub(now)
+	for _, offset := range s.cfg.Offsets {
+		if left <= offset {
+			return offset, true
+		}
+	}
+	return 0, false
+}
+
+// remind 发送提醒：AllOpen 时通知全体成员，另外单独通知关注者（同一 dedupKey 每人只收到一次）
+func (s *Scheduler) remind(ctx context.Context, customerCode string, r *dueReminder) error {
+	n := func() *notify.Notification {
+		return &notify.Notification{
+			EventType:    notify.EventDeadlineReminder,
+			DedupKey:     r.dedupKey,
+			CustomerCode: customerCode,
+			Vars: map[string]string{
+				"inquiryId": r.inquiryID,
+				"title":     r.title,
+				"deadline":  r.deadline.Format("2006-01-02 15:04"),
+				"remaining": formatRemaining(r.offset),
+			},
+		}
+	}
+	if s.cfg.AllOpen {
+		if err := s.notifyService.NotifySupplier(ctx, customerCode, n()); err != nil {
+			return err
+		}
+	}
+	for _, userID := range r.watchers {
+		if err := s.notifyService.Notify(ctx, userID, n()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Scheduler) isHandled(key string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.handled[key]
+	return ok
+}
+
+func (s *Scheduler) markHandled(key string, deadline time.Time) {
+	s.mu.Lock()
+	s.handled[key] = deadline
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) forgetExpired(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, deadline := range s.handled {
+		if now.After(deadline) {
+			delete(s.handled, key)
+		}
+	}
+}
+
+// formatRemaining 提醒文案中的剩余时间，如 "24小时"、"30分钟"
+func formatRemaining(d time.Duration) string {
+	if d >= time.Hour && d%time.Hour == 0 {
+		return fmt.Sprintf("%d小时", int(d/time.Hour))
+	}
+	return fmt.Sprintf("%d分钟", int(d/time.Minute))
+}
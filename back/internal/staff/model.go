@@ -0,0 +1,97 @@
+package staff
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 员工角色
+const (
+	RoleAdmin    = "admin"    // 管理员：全部管理权限，可管理员工账号
+	RoleOperator = "operator" // 运营：日常运营操作
+	RoleAuditor  = "auditor"  // 审计：只读查看
+)
+
+// ValidRole 是否为合法员工角色
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleOperator || role == RoleAuditor
+}
+
+// Staff 员工账号（管理后台使用，与供应商用户完全分开）
+type Staff struct {
+	ID                  string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	Username            string         `json:"username" gorm:"type:varchar(64);not null;uniqueIndex"`
+	DisplayName         string         `json:"displayName" gorm:"type:varchar(64)"`
+	PasswordHash        string         `json:"-" gorm:"type:varchar(255);not null"`                      // argon2id / bcrypt
+	Role                string         `json:"role" gorm:"type:varchar(20);not null;default:'operator'"` // admin, operator, auditor
+	Status              string         `json:"status" gorm:"type:varchar(20);not null;default:'active'"` // active, disabled
+	TOTPSecretEncrypted string         `json:"-" gorm:"type:varchar(255)"`                               // TOTP 密钥加密存储
+	TOTPEnabled         bool           `json:"totpEnabled" gorm:"not null;default:false"`                // 已完成二次验证绑定
+	TOTPLastStep        int64          `json:"-"`                                                        // 最近使用的 TOTP 时间步（防重放）
+	FailedAttempts      int            `json:"-" gorm:"not null;default:0"`                              // 连续登录失败次数
+	LockedUntil         *time.Time     `json:"lockedUntil"`                                              // 锁定截止时间
+	LastLoginAt         *time.Time     `json:"lastLoginAt"`
+	PasswordChangedAt   *time.Time     `json:"passwordChangedAt"`
+	TokensRevokedAt     *time.Time     `json:"-"` // 不晚于此时签发的 token 失效（改密、停用时设置）
+	CreatedAt           time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt           time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Staff) TableName() string {
+	return "staff"
+}
+
+// ========== Request ==========
+
+// LoginRequest 员工登录请求
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	TOTPCode string `json:"totpCode"` // 已开启二次验证时必填
+}
+
+// TOTPCodeRequest 提交 TOTP 验证码
+type TOTPCodeRequest struct {
+	Code string `json:"code" binding:"required,len=6"`
+}
+
+// ChangePasswordRequest 修改密码请求
+type ChangePasswordRequest struct {
+	OldPassword string `json:"oldPassword" binding:"required"`
+	NewPassword string `json:"newPassword" binding:"required,min=10"`
+}
+
+// ========== Response ==========
+
+// LoginResponse 员工登录响应
+type LoginResponse struct {
+	Code         int       `json:"code"`
+	Message      string    `json:"message"`
+	Token        string    `json:"token,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt,omitempty"`
+	TOTPRequired bool      `json:"totpRequired,omitempty"` // 需要提交 totpCode
+	Staff        *Staff    `json:"staff,omitempty"`
+}
+
+// StaffResponse 员工信息响应
+type StaffResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *Staff `json:"data,omitempty"`
+}
+
+// TOTPSetupResponse 开始绑定二次验证响应
+type TOTPSetupResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Secret  string `json:"secret"` // 手动输入用
+	URI     string `json:"uri"`    // otpauth:// 链接，生成二维码用
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,78 @@
+package staff
+
+import (
+	"log"
+	"net/http"
+
+	"back/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 管理接口权限，新增管理接口时在此登记并分配给角色
+const (
+	PermERPLogin         = "erp.login"         // 通过后端登录内部系统
+	PermERPTokenRefresh  = "erp.token_refresh" // 强制刷新内部系统 token
+	PermUsersRead        = "users.read"        // 查看供应商用户
+	PermUsersWrite       = "users.write"       // 创建/修改/停用/删除供应商用户
+	PermUsersImpersonate = "users.impersonate" // 以供应商身份只读查看
+	PermAPIKeysManage    = "apikeys.manage"    // 创建/吊销机器调用方 API key（仅 admin）
+	PermAnnouncements    = "announcements"     // 发布系统公告（推送给在线用户）
+	PermAuditRead        = "audit.read"        // 查询/导出审计日志
+)
+
+// rolePermissions 角色 -> 权限；admin 拥有全部权限
+var rolePermissions = map[string]map[string]bool{
+	RoleOperator: {
+		PermERPLogin:         true,
+		PermERPTokenRefresh:  true,
+		PermUsersRead:        true,
+		PermUsersWrite:       true,
+		PermUsersImpersonate: true,
+		PermAnnouncements:    true,
+	},
+	RoleAuditor: {
+		PermUsersRead: true,
+		PermAuditRead: true,
+	},
+}
+
+// HasPermission 角色是否拥有指定权限
+func HasPermission(role, perm string) bool {
+	if role == RoleAdmin {
+		return true
+	}
+	return rolePermissions[role][perm]
+}
+
+// RequirePermission 管理接口权限中间件，需在 StaffAuth 之后使用。
+// 以数据库中的当前角色和状态为准，停用或降级后已签发的 token 立即失效。
+func RequirePermission(service Service, perm string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rc := middleware.GetRequestContext(c)
+		if rc == nil || rc.StaffUsername == "" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "员工未登录"})
+			c.Abort()
+			return
+		}
+
+		st, err := service.GetByUsername(c.Request.Context(), rc.StaffUsername)
+		if err != nil || st.Status != "active" {
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "员工账号不可用"})
+			c.Abort()
+			return
+		}
+		rc.StaffRole = st.Role
+
+		if !HasPermission(st.Role, perm) {
+			log.Printf("[ADMIN-AUTH] 员工 %s(%s) 缺少权限 %s, 路径: %s", st.Username, st.Role, perm, c.Request.URL.Path)
+			rc.Action = "admin.permission_denied"
+			rc.Detail = map[string]any{"permission": perm}
+			c.JSON(http.StatusForbidden, ErrorResponse{Code: http.StatusForbidden, Message: "无权执行此操作"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
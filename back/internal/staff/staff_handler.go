@@ -0,0 +1,274 @@
+package staff
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"back/pkg/jwt"
+	"back/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 员工账号处理器
+type Handler struct {
+	service     Service
+	jwtService  *jwt.JWTService
+	tokenExpiry time.Duration
+}
+
+// NewHandler 创建新的处理器实例
+func NewHandler(service Service, jwtService *jwt.JWTService, tokenExpiry time.Duration) *Handler {
+	return &Handler{
+		service:     service,
+		jwtService:  jwtService,
+		tokenExpiry: tokenExpiry,
+	}
+}
+
+// Login 员工登录
+// @Summary 员工登录
+// @Description 用户名密码登录管理后台；已开启二次验证时需同时提交 totpCode
+// @Tags staff
+// @Accept json
+// @Produce json
+// @Param request body LoginRequest true "登录信息"
+// @Success 200 {object} LoginResponse
+// @Failure 401 {object} LoginResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/staff/login [post]
+func (h *Handler) Login(c *gin.Context) {
+	var req LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	h.setAuditInfo(c, "staff.login", "", map[string]any{"username": req.Username})
+
+	st, err := h.service.Authenticate(c.Request.Context(), req.Username, req.Password, req.TOTPCode)
+	if err != nil {
+		switch {
+		case errors.Is(err, ErrTOTPRequired):
+			c.JSON(http.StatusUnauthorized, LoginResponse{
+				Code:         http.StatusUnauthorized,
+				Message:      err.Error(),
+				TOTPRequired: true,
+			})
+		case errors.Is(err, ErrInvalidCredentials), errors.Is(err, ErrInvalidTOTP):
+			// 锁定原因只写入审计，响应统一为凭据错误
+			h.setAuditInfo(c, "staff.login", "", map[string]any{"username": req.Username, "error": err.Error()})
+			msg := ErrInvalidCredentials
+			if errors.Is(err, ErrInvalidTOTP) {
+				msg = ErrInvalidTOTP
+			}
+			c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: msg.Error()})
+		case errors.Is(err, ErrDisabled):
+			c.JSON(http.StatusForbidden, ErrorResponse{Code: http.StatusForbidden, Message: err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: "登录失败: " + err.Error(),
+			})
+		}
+		return
+	}
+
+	token, expiresAt, err := h.jwtService.GenerateAdminToken(st.Username, st.Role, h.tokenExpiry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "生成token失败",
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.StaffUsername = st.Username
+		rc.StaffRole = st.Role
+		rc.ResourceID = st.ID
+	}
+
+	c.JSON(http.StatusOK, LoginResponse{
+		Code:      http.StatusOK,
+		Message:   "登录成功",
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Staff:     st,
+	})
+}
+
+// Me 获取当前员工信息
+// @Summary 当前员工信息
+// @Tags staff
+// @Produce json
+// @Success 200 {object} StaffResponse
+// @Router /api/v1/staff/me [get]
+func (h *Handler) Me(c *gin.Context) {
+	st, ok := h.currentStaff(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, StaffResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    st,
+	})
+}
+
+// TOTPSetup 开始绑定二次验证
+// @Summary 开始绑定二次验证
+// @Description 生成新的 TOTP 密钥，用验证器 App 扫描 uri 后调用 enable 确认
+// @Tags staff
+// @Produce json
+// @Success 200 {object} TOTPSetupResponse
+// @Router /api/v1/staff/totp/setup [post]
+func (h *Handler) TOTPSetup(c *gin.Context) {
+	st, ok := h.currentStaff(c)
+	if !ok {
+		return
+	}
+	h.setAuditInfo(c, "staff.totp_setup", st.ID, nil)
+
+	secret, uri, err := h.service.BeginTOTPSetup(c.Request.Context(), st.ID)
+	if err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, TOTPSetupResponse{
+		Code:    http.StatusOK,
+		Message: "请在验证器中添加后提交验证码完成绑定",
+		Secret:  secret,
+		URI:     uri,
+	})
+}
+
+// TOTPEnable 确认开启二次验证
+// @Summary 开启二次验证
+// @Tags staff
+// @Accept json
+// @Produce json
+// @Param request body TOTPCodeRequest true "验证码"
+// @Success 200 {object} ErrorResponse
+// @Router /api/v1/staff/totp/enable [post]
+func (h *Handler) TOTPEnable(c *gin.Context) {
+	h.totpToggle(c, "staff.totp_enable", "二次验证已开启", h.service.EnableTOTP)
+}
+
+// TOTPDisable 关闭二次验证
+// @Summary 关闭二次验证
+// @Tags staff
+// @Accept json
+// @Produce json
+// @Param request body TOTPCodeRequest true "验证码"
+// @Success 200 {object} ErrorResponse
+// @Router /api/v1/staff/totp/disable [post]
+func (h *Handler) TOTPDisable(c *gin.Context) {
+	h.totpToggle(c, "staff.totp_disable", "二次验证已关闭", h.service.DisableTOTP)
+}
+
+// totpToggle 校验验证码后开启/关闭二次验证
+func (h *Handler) totpToggle(c *gin.Context, action, message string, fn func(ctx context.Context, id, code string) error) {
+	var req TOTPCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	st, ok := h.currentStaff(c)
+	if !ok {
+		return
+	}
+	h.setAuditInfo(c, action, st.ID, nil)
+
+	if err := fn(c.Request.Context(), st.ID, req.Code); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ErrorResponse{Code: http.StatusOK, Message: message})
+}
+
+// ChangePassword 修改密码
+// @Summary 修改密码
+// @Description 修改成功后此前签发的 token（含当前 token）全部失效，需重新登录
+// @Tags staff
+// @Accept json
+// @Produce json
+// @Param request body ChangePasswordRequest true "新旧密码"
+// @Success 200 {object} ErrorResponse
+// @Router /api/v1/staff/password [post]
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	st, ok := h.currentStaff(c)
+	if !ok {
+		return
+	}
+	h.setAuditInfo(c, "staff.change_password", st.ID, nil)
+
+	if err := h.service.ChangePassword(c.Request.Context(), st.ID, req.OldPassword, req.NewPassword); err != nil {
+		h.writeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, ErrorResponse{Code: http.StatusOK, Message: "密码已修改，请重新登录"})
+}
+
+// currentStaff 获取当前登录员工，失败时已写入响应
+func (h *Handler) currentStaff(c *gin.Context) (*Staff, bool) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.StaffUsername == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "员工未登录"})
+		return nil, false
+	}
+	st, err := h.service.GetByUsername(c.Request.Context(), rc.StaffUsername)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Code: http.StatusUnauthorized, Message: "员工账号不存在"})
+		return nil, false
+	}
+	if st.Status != "active" {
+		c.JSON(http.StatusForbidden, ErrorResponse{Code: http.StatusForbidden, Message: ErrDisabled.Error()})
+		return nil, false
+	}
+	return st, true
+}
+
+// writeError 按业务错误类型写入响应
+func (h *Handler) writeError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrInvalidTOTP), errors.Is(err, ErrInvalidCredentials):
+		status = http.StatusUnauthorized
+	case errors.Is(err, ErrTOTPNotSetup), errors.Is(err, ErrTOTPEnabled), errors.Is(err, ErrWeakPassword):
+		status = http.StatusBadRequest
+	case errors.Is(err, ErrNotFound):
+		status = http.StatusNotFound
+	}
+	c.JSON(status, ErrorResponse{Code: status, Message: err.Error()})
+}
+
+// setAuditInfo 设置审计信息
+func (h *Handler) setAuditInfo(c *gin.Context, action, resourceID string, detail map[string]any) {
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = action
+		rc.Resource = "staff"
+		if resourceID != "" {
+			rc.ResourceID = resourceID
+		}
+		if detail != nil {
+			rc.Detail = detail
+		}
+	}
+}
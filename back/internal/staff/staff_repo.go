@@ -0,0 +1,61 @@
+package staff
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// Repository 员工数据访问
+type Repository interface {
+	Create(ctx context.Context, s *Staff) error
+	GetByID(ctx context.Context, id string) (*Staff, error)
+	GetByUsername(ctx context.Context, username string) (*Staff, error)
+	Update(ctx context.Context, s *Staff) error
+	UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error
+	Count(ctx context.Context) (int64, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建员工仓储
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Create(ctx context.Context, s *Staff) error {
+	return r.db.WithContext(ctx).Create(s).Error
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*Staff, error) {
+	var s Staff
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) GetByUsername(ctx context.Context, username string) (*Staff, error) {
+	var s Staff
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&s).Error; err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (r *repository) Update(ctx context.Context, s *Staff) error {
+	return r.db.WithContext(ctx).Save(s).Error
+}
+
+// UpdateFields 只更新指定字段，避免并发登录时覆盖失败计数等字段
+func (r *repository) UpdateFields(ctx context.Context, id string, fields map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&Staff{}).Where("id = ?", id).Updates(fields).Error
+}
+
+func (r *repository) Count(ctx context.Context) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&Staff{}).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,355 @@
+package staff
+
+import (
+	"back/pkg/crypto"
+	"back/pkg/middleware"
+	"back/pkg/password"
+	"back/pkg/totp"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 登录锁定参数
+const (
+	maxFailedAttempts = 5                // 连续失败次数上限
+	lockDuration      = 15 * time.Minute // 锁定时长
+	minPasswordLength = 10
+	totpIssuer        = "Fantasy Bounty Admin"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("用户名或密码错误")
+	ErrDisabled           = errors.New("账号已停用")
+	ErrLocked             = errors.New("登录失败次数过多，账号已临时锁定")
+	ErrTOTPRequired       = errors.New("请输入二次验证码")
+	ErrInvalidTOTP        = errors.New("二次验证码错误")
+	ErrTOTPNotSetup       = errors.New("尚未开始绑定二次验证")
+	ErrTOTPEnabled        = errors.New("二次验证已开启")
+	ErrUsernameTaken      = errors.New("用户名已存在")
+	ErrWeakPassword       = fmt.Errorf("密码长度至少 %d 位", minPasswordLength)
+	ErrInvalidRole        = errors.New("无效的员工角色")
+	ErrNotFound           = errors.New("员工不存在")
+	ErrInvalidStatus      = errors.New("无效的员工状态")
+)
+
+// Service 员工账号服务
+type Service interface {
+	Create(ctx context.Context, username, plainPassword, role string) (*Staff, error)
+	Get(ctx context.Context, id string) (*Staff, error)
+	GetByUsername(ctx context.Context, username string) (*Staff, error)
+	Authenticate(ctx context.Context, username, plainPassword, totpCode string) (*Staff, error)
+	ChangePassword(ctx context.Context, id, oldPassword, newPassword string) error
+	BeginTOTPSetup(ctx context.Context, id string) (secret, uri string, err error)
+	EnableTOTP(ctx context.Context, id, code string) error
+	DisableTOTP(ctx context.Context, id, code string) error
+	SetStatus(ctx context.Context, username, status string) (*Staff, error)
+	CheckSession(ctx context.Context, username string, issuedAt time.Time) error
+}
+
+type service struct {
+	repo   Repository
+	crypto *crypto.Crypto
+	// dummyHash 用户名不存在时也做一次哈希校验，避免通过响应时间枚举用户名
+	dummyHash string
+}
+
+// NewService 创建员工账号服务
+func NewService(repo Repository, cryptoService *crypto.Crypto) Service {
+	dummy, _ := password.Hash("dummy-password-for-timing")
+	return &service{repo: repo, crypto: cryptoService, dummyHash: dummy}
+}
+
+// Create 创建员工账号
+func (s *service) Create(ctx context.Context, username, plainPassword, role string) (*Staff, error) {
+	if username == "" {
+		return nil, errors.New("用户名不能为空")
+	}
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if len(plainPassword) < minPasswordLength {
+		return nil, ErrWeakPassword
+	}
+	if _, err := s.repo.GetByUsername(ctx, username); err == nil {
+		return nil, ErrUsernameTaken
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	hash, err := password.Hash(plainPassword)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	st := &Staff{
+		Username:          username,
+		PasswordHash:      hash,
+		Role:              role,
+		Status:            "active",
+		PasswordChangedAt: &now,
+	}
+	if err := s.repo.Create(ctx, st); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+// Get 按 ID 获取员工
+func (s *service) Get(ctx context.Context, id string) (*Staff, error) {
+	st, err := s.repo.GetByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return st, err
+}
+
+// GetByUsername 按用户名获取员工
+func (s *service) GetByUsername(ctx context.Context, username string) (*Staff, error) {
+	st, err := s.repo.GetByUsername(ctx, username)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return st, err
+}
+
+// Authenticate 校验用户名、密码和二次验证码
+// 连续失败 maxFailedAttempts 次后锁定 lockDuration；未提交二次验证码不计入失败。
+// 先校验密码再看账号状态，密码错误或账号锁定时统一返回 ErrInvalidCredentials，
+// 不向未持有密码的调用方暴露账号是否停用、锁定；锁定原因以 ErrLocked 包装在返回的错误中，供审计记录
+func (s *service) Authenticate(ctx context.Context, username, plainPassword, totpCode string) (*Staff, error) {
+	st, err := s.repo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = password.Verify(s.dummyHash, plainPassword)
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	locked := st.LockedUntil != nil && now.Before(*st.LockedUntil)
+	if err := password.Verify(st.PasswordHash, plainPassword); err != nil {
+		// 停用或锁定期间的失败不再计数，避免延长锁定
+		if st.Status != "active" || locked {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, s.recordFailure(ctx, st, now, ErrInvalidCredentials)
+	}
+	// 锁定期间即使密码正确也不区分，否则锁定期间仍可继续猜测密码
+	if locked {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCredentials, ErrLocked)
+	}
+	if st.Status != "active" {
+		return nil, ErrDisabled
+	}
+
+	var totpStep int64
+	if st.TOTPEnabled {
+		if totpCode == "" {
+			return nil, ErrTOTPRequired
+		}
+		step, ok := s.validateTOTP(st, totpCode, now)
+		if !ok {
+			return nil, s.recordFailure(ctx, st, now, ErrInvalidTOTP)
+		}
+		totpStep = step
+	}
+
+	fields := map[string]interface{}{
+		"failed_attempts": 0,
+		"locked_until":    nil,
+		"last_login_at":   now,
+	}
+	if totpStep > 0 {
+		fields["totp_last_step"] = totpStep
+	}
+	// 旧参数或 bcrypt 哈希在登录成功后升级为当前 argon2id 参数
+	if password.NeedsRehash(st.PasswordHash) {
+		if hash, err := password.Hash(plainPassword); err == nil {
+			fields["password_hash"] = hash
+		}
+	}
+	if err := s.repo.UpdateFields(ctx, st.ID, fields); err != nil {
+		return nil, err
+	}
+	st.FailedAttempts = 0
+	st.LockedUntil = nil
+	st.LastLoginAt = &now
+	return st, nil
+}
+
+// recordFailure 记录一次登录失败，达到上限时锁定账号
+// 锁定时返回的错误仍匹配 cause，同时包装 ErrLocked
+func (s *service) recordFailure(ctx context.Context, st *Staff, now time.Time, cause error) error {
+	attempts := st.FailedAttempts + 1
+	fields := map[string]interface{}{"failed_attempts": gorm.Expr("failed_attempts + 1")}
+	locked := attempts >= maxFailedAttempts
+	if locked {
+		fields["failed_attempts"] = 0
+		fields["locked_until"] = now.Add(lockDuration)
+	}
+	if err := s.repo.UpdateFields(ctx, st.ID, fields); err != nil {
+		return err
+	}
+	if locked {
+		return fmt.Errorf("%w: %w", cause, ErrLocked)
+	}
+	return cause
+}
+
+// validateTOTP 校验二次验证码，拒绝已使用过的时间步
+func (s *service) validateTOTP(st *Staff, code string, now time.Time) (int64, bool) {
+	if st.TOTPSecretEncrypted == "" {
+		return 0, false
+	}
+	secret, err := s.crypto.Decrypt(st.TOTPSecretEncrypted)
+	if err != nil {
+		return 0, false
+	}
+	step, ok := totp.Validate(secret, code, now)
+	if !ok || step <= st.TOTPLastStep {
+		return 0, false
+	}
+	return step, true
+}
+
+// ChangePassword 修改密码
+func (s *service) ChangePassword(ctx context.Context, id, oldPassword, newPassword string) error {
+	st, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := password.Verify(st.PasswordHash, oldPassword); err != nil {
+		return ErrInvalidCredentials
+	}
+	if len(newPassword) < minPasswordLength {
+		return ErrWeakPassword
+	}
+	hash, err := password.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+	// 改密后此前签发的 token 全部失效，需重新登录
+	now := time.Now()
+	return s.repo.UpdateFields(ctx, id, map[string]interface{}{
+		"password_hash":       hash,
+		"password_changed_at": now,
+		"tokens_revoked_at":   now,
+	})
+}
+
+// SetStatus 启用/停用员工账号，停用时此前签发的 token 全部失效
+func (s *service) SetStatus(ctx context.Context, username, status string) (*Staff, error) {
+	if status != "active" && status != "disabled" {
+		return nil, ErrInvalidStatus
+	}
+	st, err := s.GetByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]interface{}{"status": status}
+	if status == "disabled" {
+		now := time.Now()
+		fields["tokens_revoked_at"] = now
+		st.TokensRevokedAt = &now
+	}
+	if err := s.repo.UpdateFields(ctx, st.ID, fields); err != nil {
+		return nil, err
+	}
+	st.Status = status
+	return st, nil
+}
+
+// CheckSession 校验员工 token 是否仍然有效，供 StaffAuth 使用
+// 员工不存在、未启用，或 token 签发时间不晚于作废时间时返回 middleware.ErrSessionRevoked
+func (s *service) CheckSession(ctx context.Context, username string, issuedAt time.Time) error {
+	st, err := s.GetByUsername(ctx, username)
+	if errors.Is(err, ErrNotFound) {
+		return middleware.ErrSessionRevoked
+	}
+	if err != nil {
+		return err
+	}
+	if st.Status != "active" {
+		return middleware.ErrSessionRevoked
+	}
+	// JWT 的 iat 精确到秒，与作废时间同一秒签发的 token 也视为失效
+	if st.TokensRevokedAt != nil && !issuedAt.After(st.TokensRevokedAt.Truncate(time.Second)) {
+		return middleware.ErrSessionRevoked
+	}
+	return nil
+}
+
+// BeginTOTPSetup 生成新的 TOTP 密钥，需调用 EnableTOTP 校验一次验证码后才生效
+func (s *service) BeginTOTPSetup(ctx context.Context, id string) (string, string, error) {
+	st, err := s.Get(ctx, id)
+	if err != nil {
+		return "", "", err
+	}
+	if st.TOTPEnabled {
+		return "", "", ErrTOTPEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return "", "", err
+	}
+	encrypted, err := s.crypto.Encrypt(secret)
+	if err != nil {
+		return "", "", err
+	}
+	if err := s.repo.UpdateFields(ctx, id, map[string]interface{}{
+		"totp_secret_encrypted": encrypted,
+		"totp_last_step":        0,
+	}); err != nil {
+		return "", "", err
+	}
+	return secret, totp.URI(totpIssuer, st.Username, secret), nil
+}
+
+// EnableTOTP 校验验证码后开启二次验证
+func (s *service) EnableTOTP(ctx context.Context, id, code string) error {
+	st, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if st.TOTPEnabled {
+		return ErrTOTPEnabled
+	}
+	if st.TOTPSecretEncrypted == "" {
+		return ErrTOTPNotSetup
+	}
+	step, ok := s.validateTOTP(st, code, time.Now())
+	if !ok {
+		return ErrInvalidTOTP
+	}
+	return s.repo.UpdateFields(ctx, id, map[string]interface{}{
+		"totp_enabled":   true,
+		"totp_last_step": step,
+	})
+}
+
+// DisableTOTP 校验验证码后关闭二次验证
+func (s *service) DisableTOTP(ctx context.Context, id, code string) error {
+	st, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !st.TOTPEnabled {
+		return ErrTOTPNotSetup
+	}
+	step, ok := s.validateTOTP(st, code, time.Now())
+	if !ok {
+		return ErrInvalidTOTP
+	}
+	// 与登录一致记录已使用的时间步
+	return s.repo.UpdateFields(ctx, id, map[string]interface{}{
+		"totp_enabled":          false,
+		"totp_secret_encrypted": "",
+		"totp_last_step":        step,
+	})
+}
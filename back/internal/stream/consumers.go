@@ -0,0 +1,46 @@
+package stream
+
+import (
+	"back/internal/tracker"
+	"back/internal/user"
+	"back/internal/webhook"
+	"context"
+	"log"
+)
+
+// TrackerListener 报价跟踪产生事件时推送给该供应商的成员
+func TrackerListener(s Service) tracker.Listener {
+	return func(ctx context.Context, e *tracker.QuoteEvent) {
+		err := s.PublishSupplier(ctx, e.CustomerCode, EventQuoteStatus, QuoteStatus{
+			EventID:   e.ID,
+			Kind:      e.Type,
+			InquiryID: e.InquiryID,
+			QuoteKey:  e.QuoteKey,
+			Before:    e.Before,
+			After:     e.After,
+			Time:      e.CreatedAt,
+		}, "quote_event:"+e.ID)
+		if err != nil {
+			log.Printf("[STREAM] 报价事件 %s 推送失败: %v", e.ID, err)
+		}
+	}
+}
+
+// SessionHook 用户会话被作废时推送下线通知，在线连接收到后由服务端断开
+func SessionHook(s Service) user.SessionHook {
+	return func(ctx context.Context, u *user.User, reason string) {
+		if err := s.Publish(ctx, u.ID, EventSessionRevoked, SessionRevoked{Reason: reason}, ""); err != nil {
+			log.Printf("[STREAM] 用户 %s 下线通知推送失败: %v", u.ID, err)
+		}
+	}
+}
+
+// WebhookConsumer ERP 推送询价单发布/截止时让本实例立即刷新各供应商的询价单缓存，
+// 新询价单不必等到下一个 InquiryInterval 就推送给在线成员。
+// 事件只由一个实例分发，其他实例仍按 InquiryInterval 刷新；推送按 dedupKey 去重，重复投递无副作用
+func WebhookConsumer(s Service) webhook.Consumer {
+	return func(ctx context.Context, e *webhook.Event) error {
+		s.RefreshInquiries()
+		return nil
+	}
+}
@@ -0,0 +1,114 @@
+package stream
+
+import (
+	"back/internal/tracker"
+	"context"
+	"log"
+	"time"
+)
+
+// 可报价招标信息（Pur_InquiryQueryForSupplier）返回行的字段名，按已知命名依次尝试
+var (
+	fieldInquiryID = []string{"InquiryId", "Id"}
+	fieldTitle     = []string{"P_chnName", "Title", "InquiryNo"}
+	fieldEndDate   = []string{"EndDate"}
+)
+
+// watchInquiries 定时检查本实例在线用户所属供应商的可报价询价单，新出现的推送给该供应商的成员
+// 只检查有在线连接的供应商，前端不必再轮询；多个实例检查同一供应商时按 dedupKey 只推送一次
+func (s *service) watchInquiries() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(s.cfg.InquiryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			s.checkInquiries(false)
+		case <-s.inquiryWake:
+			s.checkInquiries(true)
+		case <-s.inquiryRefresh:
+			s.checkInquiries(false)
+		}
+	}
+}
+
+func (s *service) RefreshInquiries() {
+	select {
+	case s.inquiryRefresh <- struct{}{}:
+	default:
+	}
+}
+
+// checkInquiries onlyNew 为 true 时只记录新上线供应商的当前询价单
+func (s *service) checkInquiries(onlyNew bool) {
+	codes := s.activeCodes()
+	for code := range s.known {
+		if !codes[code] {
+			delete(s.known, code)
+		}
+	}
+	for code := range codes {
+		if onlyNew && s.known[code] != nil {
+			continue
+		}
+		select {
+		case <-s.stop:
+			return
+		default:
+		}
+		if err := s.checkSupplier(code); err != nil {
+			log.Printf("[STREAM] 检查供应商 %s 的新询价单失败: %v", code, err)
+		}
+	}
+}
+
+// checkSupplier 第一次检查只记录当前询价单，之后与上次对比推送新增的
+func (s *service) checkSupplier(customerCode string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	// 停止时取消限流等待
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	rows, err := s.fetcher.OpenInquiries(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+
+	known := s.known[customerCode]
+	current := make(map[string]bool, len(rows))
+	var fresh []InquiryNew
+	for _, raw := range rows {
+		row, err := tracker.DecodeRow(raw)
+		if err != nil {
+			return err
+		}
+		inquiryID := tracker.RowField(row, fieldInquiryID)
+		if inquiryID == "" {
+			continue
+		}
+		current[inquiryID] = true
+		if known != nil && !known[inquiryID] {
+			fresh = append(fresh, InquiryNew{
+				InquiryID: inquiryID,
+				Title:     tracker.RowField(row, fieldTitle),
+				EndDate:   tracker.RowField(row, fieldEndDate),
+			})
+		}
+	}
+	s.known[customerCode] = current
+
+	for _, n := range fresh {
+		if err := s.PublishSupplier(ctx, customerCode, EventInquiryNew, n, "inquiry.new:"+customerCode+":"+n.InquiryID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 推送事件类型（SSE 的 event 字段）
+const (
+	EventQuoteStatus    = "quote.status"    // 报价状态变化，data.kind 为报价跟踪的事件类型
+	EventInquiryNew     = "inquiry.new"     // 出现新的可报价询价单
+	EventSessionRevoked = "session.revoked" // 会话已作废（停用/删除/强制下线），客户端应退出登录，服务端随后断开
+	EventAnnouncement   = "announcement"    // 系统公告
+	EventStreamReset    = "stream.reset"    // 断点已超出事件日志保留范围，客户端应重新加载数据
+)
+
+// 公告级别
+const (
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+)
+
+// Event 推送事件日志：各实例写入、各实例轮询分发给本机连接，同时用于 Last-Event-ID 断线续传，只保留较短时间
+type Event struct {
+	ID        int64          `json:"id" gorm:"primaryKey;autoIncrement"`
+	UserID    *string        `json:"userId,omitempty" gorm:"type:uuid;index"` // 为空表示广播给所有在线用户
+	Type      string         `json:"type" gorm:"type:varchar(40);not null"`
+	Data      datatypes.JSON `json:"data" gorm:"type:jsonb"`
+	DedupKey  *string        `json:"-" gorm:"type:varchar(200);uniqueIndex"` // 多个实例/来源产生同一事件时只保留一条
+	CreatedAt time.Time      `json:"createdAt" gorm:"autoCreateTime;index"`
+}
+
+func (Event) TableName() string {
+	return "stream_events"
+}
+
+// Connection 在线的 SSE 连接，用于跨实例限制每个用户的连接数；实例定时刷新 SeenAt，崩溃残留的记录按 SeenAt 过期
+type Connection struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey"`
+	UserID    string    `json:"userId" gorm:"type:uuid;not null;index"`
+	Instance  string    `json:"instance" gorm:"type:varchar(100)"` // 所在实例（主机名:进程号）
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	SeenAt    time.Time `json:"seenAt" gorm:"not null;index"`
+}
+
+func (Connection) TableName() string {
+	return "stream_connections"
+}
+
+// ========== Event Data ==========
+
+// Announcement 系统公告内容
+type Announcement struct {
+	Title   string `json:"title"`
+	Content string `json:"content"`
+	Level   string `json:"level"`
+}
+
+// QuoteStatus 报价状态变化内容
+type QuoteStatus struct {
+	EventID   string    `json:"eventId"` // 报价跟踪事件ID，可用于查询事件流
+	Kind      string    `json:"kind"`    // quote.awarded / quote.rejected / quote.status_changed / inquiry.closed / inquiry.deadline_changed
+	InquiryID string    `json:"inquiryId"`
+	QuoteKey  string    `json:"quoteKey"`
+	Before    string    `json:"before"`
+	After     string    `json:"after"`
+	Time      time.Time `json:"time"`
+}
+
+// InquiryNew 新询价单内容
+type InquiryNew struct {
+	InquiryID string `json:"inquiryId"`
+	Title     string `json:"title"`
+	EndDate   string `json:"endDate"`
+}
+
+// SessionRevoked 会话作废内容
+type SessionRevoked struct {
+	Reason string `json:"reason"` // disabled / deleted / revoked
+}
+
+// ========== Request ==========
+
+// AnnouncementRequest 发布系统公告
+type AnnouncementRequest struct {
+	Title   string `json:"title" binding:"required,max=100"`
+	Content string `json:"content" binding:"required,max=1000"`
+	Level   string `json:"level" binding:"omitempty,oneof=info warning"` // 默认 info
+}
+
+// ========== Response ==========
+
+// AnnouncementResponse 发布公告响应
+type AnnouncementResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+	Data    *Event `json:"data,omitempty"`
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,275 @@
+package stream
+
+import (
+	"back/internal/supplier"
+	"back/internal/user"
+	"back/pkg/middleware"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// retryMillis 建议客户端断线后的重连间隔
+const retryMillis = 5000
+
+// Handler 实时推送处理器
+type Handler struct {
+	service         Service
+	userService     user.Service
+	supplierService supplier.Service
+	heartbeat       time.Duration
+	maxLifetime     time.Duration
+}
+
+// NewHandler 创建实时推送处理器
+// heartbeat 为心跳间隔（需小于反向代理的读超时）；maxLifetime 为单个连接的最长时间，到期断开让客户端带新 token 重连
+func NewHandler(service Service, userService user.Service, supplierService supplier.Service, heartbeat, maxLifetime time.Duration) *Handler {
+	return &Handler{
+		service:         service,
+		userService:     userService,
+		supplierService: supplierService,
+		heartbeat:       heartbeat,
+		maxLifetime:     maxLifetime,
+	}
+}
+
+// Stream 实时事件推送（Server-Sent Events）
+// @Summary 实时事件推送
+// @Description text/event-stream 长连接，事件类型：quote.status（报价状态变化）、inquiry.new（新的可报价询价单）、session.revoked（会话已作废，服务端随后断开）、announcement（系统公告）、stream.reset（无法续传，需重新加载）。
+// @Description 每条事件带 id，断线重连时浏览器自动携带 Last-Event-ID 请求头续传，也可用 lastEventId 参数；同一事件可能重复送达，客户端按 id 去重。
+// @Description 定时发送注释行作为心跳。EventSource 不能设置请求头，可用 access_token 参数传递 token
+// @Tags events
+// @Produce text/event-stream
+// @Param lastEventId query int false "断点事件ID，Last-Event-ID 请求头优先"
+// @Param access_token query string false "JWT，未携带 Authorization 请求头时使用"
+// @Success 200 {string} string "事件流"
+// @Failure 400 {object} ErrorResponse
+// @Failure 401 {object} ErrorResponse
+// @Failure 429 {object} ErrorResponse
+// @Router /api/v1/events/stream [get]
+func (h *Handler) Stream(c *gin.Context) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未登录",
+		})
+		return
+	}
+	ctx := c.Request.Context()
+	u, err := h.userService.GetUserByUsername(ctx, rc.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户不存在",
+		})
+		return
+	}
+
+	lastID, err := parseLastEventID(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "invalid Last-Event-ID",
+		})
+		return
+	}
+
+	// 按当前会话所代表的供应商订阅；未绑定或已不是成员时仍可连接，只是收不到供应商相关的事件
+	customerCode := supplier.SessionCustomerCode(c, u)
+	if customerCode != "" {
+		if _, err := h.supplierService.ActiveMember(ctx, customerCode, u.CustomerCode, u.ID); err != nil {
+			customerCode = ""
+		}
+	}
+
+	rc.Action = "events.stream"
+	rc.Resource = "user"
+	rc.ResourceID = u.ID
+	rc.Detail = map[string]any{"last_event_id": lastID}
+
+	sub, err := h.service.Subscribe(ctx, u.ID, customerCode)
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, ErrTooManyConnections):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, ErrStopped):
+			status = http.StatusServiceUnavailable
+		}
+		c.JSON(status, ErrorResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+	defer sub.Close()
+
+	var replay []Event
+	reset := false
+	if lastID > 0 {
+		replay, reset, err = h.service.Replay(ctx, u.ID, lastID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	w := c.Writer
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // nginx 不缓冲
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", retryMillis)
+
+	if reset {
+		writeEvent(w, &Event{Type: EventStreamReset})
+	}
+	// 补发的事件可能随后又从实时通道到达，记下来跳过
+	replayed := make(map[int64]bool, len(replay))
+	for i := range replay {
+		replayed[replay[i].ID] = true
+		// 能通过认证说明会话有效，之前的下线通知不再补发
+		if replay[i].Type == EventSessionRevoked {
+			continue
+		}
+		writeEvent(w, &replay[i])
+	}
+	w.Flush()
+
+	connectedAt := time.Now()
+	heartbeat := time.NewTicker(h.heartbeat)
+	defer heartbeat.Stop()
+	lifetime := time.NewTimer(h.maxLifetime)
+	defer lifetime.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lifetime.C:
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if replayed[e.ID] {
+				delete(replayed, e.ID)
+				continue
+			}
+			if err := writeEvent(w, e); err != nil {
+				return
+			}
+			w.Flush()
+			if e.Type == EventSessionRevoked {
+				return
+			}
+		case <-heartbeat.C:
+			// 兜底：其他途径作废了会话（如多实例缓存延迟期间）也能断开
+			if err := h.userService.CheckSession(ctx, u.Username, connectedAt); errors.Is(err, middleware.ErrSessionRevoked) {
+				writeEvent(w, &Event{Type: EventSessionRevoked})
+				w.Flush()
+				return
+			}
+			if _, err := io.WriteString(w, ": ping\n\n"); err != nil {
+				return
+			}
+			w.Flush()
+		}
+	}
+}
+
+// Announce 发布系统公告，推送给所有在线用户（断线续传范围内重连的用户也会收到）
+// @Summary 发布系统公告（管理端）
+// @Tags admin-events
+// @Accept json
+// @Produce json
+// @Param request body AnnouncementRequest true "公告内容"
+// @Success 200 {object} AnnouncementResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/admin/announcements [post]
+func (h *Handler) Announce(c *gin.Context) {
+	var req AnnouncementRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
+	if req.Level == "" {
+		req.Level = LevelInfo
+	}
+
+	e, err := h.service.Broadcast(c.Request.Context(), EventAnnouncement, Announcement{
+		Title:   strings.TrimSpace(req.Title),
+		Content: strings.TrimSpace(req.Content),
+		Level:   req.Level,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "admin.announcement"
+		rc.Resource = "announcement"
+		rc.ResourceID = strconv.FormatInt(e.ID, 10)
+		rc.Detail = map[string]any{"title": req.Title, "level": req.Level}
+	}
+
+	c.JSON(http.StatusOK, AnnouncementResponse{
+		Code:    http.StatusOK,
+		Message: "公告已发布",
+		Data:    e,
+	})
+}
+
+// parseLastEventID 读取断点事件ID，优先 Last-Event-ID 请求头
+func parseLastEventID(c *gin.Context) (int64, error) {
+	v := strings.TrimSpace(c.GetHeader("Last-Event-ID"))
+	if v == "" {
+		v = strings.TrimSpace(c.Query("lastEventId"))
+	}
+	if v == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id < 0 {
+		return 0, errors.New("invalid last event id")
+	}
+	return id, nil
+}
+
+// writeEvent 按 SSE 格式写出一条事件，ID 为 0 的事件（本地生成的控制事件）不带 id，不影响客户端的续传断点
+func writeEvent(w io.Writer, e *Event) error {
+	data := string(e.Data)
+	if data == "" {
+		data = "{}"
+	}
+	var b strings.Builder
+	if e.ID > 0 {
+		fmt.Fprintf(&b, "id: %d\n", e.ID)
+	}
+	fmt.Fprintf(&b, "event: %s\n", e.Type)
+	// jsonb 输出不含换行，保险起见按行拆分
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(&b, "data: %s\n", line)
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
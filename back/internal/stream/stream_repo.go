@@ -0,0 +1,128 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 推送事件日志与在线连接数据访问
+type Repository interface {
+	// Insert 写入事件，DedupKey 已存在的跳过，返回实际写入条数
+	Insert(ctx context.Context, events []Event) (int64, error)
+	// ListAfter 按 ID 升序列出 afterID 之后的全部事件（各实例轮询分发）
+	ListAfter(ctx context.Context, afterID int64, limit int) ([]Event, error)
+	// ListForUser 按 ID 升序列出 afterID 之后发给该用户或广播的事件（断线续传）
+	ListForUser(ctx context.Context, userID string, afterID int64, limit int) ([]Event, error)
+	// Bounds 当前保留的最小、最大事件ID，日志为空时均为 0
+	Bounds(ctx context.Context) (oldest, latest int64, err error)
+	DeleteBefore(ctx context.Context, before time.Time) (int64, error)
+
+	// AcquireConnection 用户存活连接数未达到 limit 时登记连接，同一用户的登记在各实例间串行
+	AcquireConnection(ctx context.Context, conn *Connection, limit int, aliveAfter time.Time) (bool, error)
+	TouchConnections(ctx context.Context, ids []string, at time.Time) error
+	ReleaseConnection(ctx context.Context, id string) error
+	DeleteStaleConnections(ctx context.Context, before time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建推送仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Insert(ctx context.Context, events []Event) (int64, error) {
+	if len(events) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&events)
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) ListAfter(ctx context.Context, afterID int64, limit int) ([]Event, error) {
+	var events []Event
+	err := r.db.WithContext(ctx).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *repository) ListForUser(ctx context.Context, userID string, afterID int64, limit int) ([]Event, error) {
+	var events []Event
+	err := r.db.WithContext(ctx).
+		Where("id > ? AND (user_id = ? OR user_id IS NULL)", afterID, userID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+	return events, err
+}
+
+func (r *repository) Bounds(ctx context.Context) (int64, int64, error) {
+	var row struct {
+		Oldest int64
+		Latest int64
+	}
+	err := r.db.WithContext(ctx).Model(&Event{}).
+		Select("COALESCE(MIN(id), 0) AS oldest, COALESCE(MAX(id), 0) AS latest").
+		Scan(&row).Error
+	return row.Oldest, row.Latest, err
+}
+
+func (r *repository) DeleteBefore(ctx context.Context, before time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Where("created_at < ?", before).
+		Delete(&Event{})
+	return result.RowsAffected, result.Error
+}
+
+func (r *repository) AcquireConnection(ctx context.Context, conn *Connection, limit int, aliveAfter time.Time) (bool, error) {
+	acquired := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 事务级咨询锁：同一用户同时从多个实例建连时按顺序计数
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "stream:"+conn.UserID).Error; err != nil {
+			return err
+		}
+		var alive int64
+		err := tx.Model(&Connection{}).
+			Where("user_id = ? AND seen_at > ?", conn.UserID, aliveAfter).
+			Count(&alive).Error
+		if err != nil {
+			return err
+		}
+		if limit > 0 && alive >= int64(limit) {
+			return nil
+		}
+		if err := tx.Create(conn).Error; err != nil {
+			return err
+		}
+		acquired = true
+		return nil
+	})
+	return acquired, err
+}
+
+func (r *repository) TouchConnections(ctx context.Context, ids []string, at time.Time) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&Connection{}).
+		Where("id IN ?", ids).
+		Update("seen_at", at).Error
+}
+
+func (r *repository) ReleaseConnection(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Where("id = ?", id).Delete(&Connection{}).Error
+}
+
+func (r *repository) DeleteStaleConnections(ctx context.Context, before time.Time) error {
+	return r.db.WithContext(ctx).Where("seen_at < ?", before).Delete(&Connection{}).Error
+}
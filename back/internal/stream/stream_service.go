@@ -0,0 +1,439 @@
+package stream
+
+import (
+	"back/internal/supplier"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+)
+
+const (
+	// subscriptionBuffer 每个连接待发送事件的缓冲，写满说明客户端消费过慢，断开后由客户端续传
+	subscriptionBuffer = 64
+	// replayLimit 断线续传最多补发的事件数，超过时让客户端重新加载
+	replayLimit = 500
+	pollBatch   = 500
+	// gapGrace 事件ID由序列分配，并发写入时可能晚于更大的ID提交；在此时间内重复查询游标之后的事件以免漏发
+	gapGrace = 5 * time.Second
+	// connectionTTL 连接登记超过此时间未刷新视为已断开（实例崩溃时残留的记录）
+	connectionTTL = 2 * time.Minute
+	// maintainInterval 刷新本实例连接、清理过期连接和事件日志的间隔
+	maintainInterval = 30 * time.Second
+)
+
+var (
+	ErrTooManyConnections = errors.New("too many connections")
+	ErrStopped            = errors.New("stream service stopped")
+)
+
+// Config 推送配置
+type Config struct {
+	PollInterval    time.Duration // 轮询事件日志的间隔，决定跨实例推送的延迟
+	Retention       time.Duration // 事件日志保留时长，即断线续传的最大范围
+	MaxConnections  int           // 每个用户同时在线的连接数上限（所有实例合计），0 不限
+	InquiryInterval time.Duration // 检查新询价单的间隔，0 不检查
+}
+
+// InquiryFetcher 拉取供应商的可报价询价单（由内部系统代理实现，调用前需限流）
+type InquiryFetcher interface {
+	OpenInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error)
+}
+
+// Service 实时事件推送
+// 事件先写入 Postgres 事件日志，各实例轮询日志分发给本机的连接，因此任一实例产生的事件都能推送到用户所在的实例
+type Service interface {
+	// Publish 推送给指定用户，dedupKey 非空时同一事件只写入一次
+	Publish(ctx context.Context, userID, eventType string, data any, dedupKey string) error
+	// PublishSupplier 推送给供应商的全部成员，dedupKey 按成员区分
+	PublishSupplier(ctx context.Context, customerCode, eventType string, data any, dedupKey string) error
+	// Broadcast 推送给所有在线用户
+	Broadcast(ctx context.Context, eventType string, data any) (*Event, error)
+	// Subscribe 登记一个连接，超过每个用户的连接数上限时返回 ErrTooManyConnections
+	Subscribe(ctx context.Context, userID, customerCode string) (*Subscription, error)
+	// RefreshInquiries 立即重新检查本实例在线供应商的可报价询价单（ERP 推送询价单变化时调用），不阻塞
+	RefreshInquiries()
+	// Replay 断线续传：afterID 之后发给该用户的事件；reset 为 true 表示断点之后的事件已清理或过多，客户端需重新加载
+	Replay(ctx context.Context, userID string, afterID int64) (events []Event, reset bool, err error)
+	Start()
+	// Stop 停止后台任务并断开本实例的全部连接，可重复调用
+	Stop()
+}
+
+// Subscription 一个在线连接
+type Subscription struct {
+	ID           string
+	UserID       string
+	CustomerCode string
+	// C 待发送的事件；被关闭表示服务端要求断开（服务停止或客户端消费过慢），客户端重连后续传
+	C <-chan *Event
+
+	ch      chan *Event
+	closed  bool
+	service *service
+}
+
+// Close 断开连接并注销登记
+func (sub *Subscription) Close() {
+	s := sub.service
+	s.mu.Lock()
+	delete(s.subs, sub.ID)
+	if !sub.closed {
+		sub.closed = true
+		close(sub.ch)
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := s.repo.ReleaseConnection(ctx, sub.ID); err != nil {
+		log.Printf("[STREAM] 注销连接 %s 失败: %v", sub.ID, err)
+	}
+}
+
+type service struct {
+	repo            Repository
+	supplierService supplier.Service
+	fetcher         InquiryFetcher
+	cfg             Config
+	instance        string
+
+	mu      sync.Mutex
+	subs    map[string]*Subscription
+	stopped bool
+
+	// 事件日志游标，只在 run 协程中访问：low 之前的事件都已分发，seen 为 low 之后已分发的事件及首次看到的时间
+	ready bool
+	low   int64
+	seen  map[int64]time.Time
+
+	// known 各供应商上次看到的可报价询价单，只在 watchInquiries 协程中访问
+	known map[string]map[string]bool
+
+	wake           chan struct{}
+	inquiryWake    chan struct{}
+	inquiryRefresh chan struct{}
+	stop           chan struct{}
+	stopOnce       sync.Once
+	wg             sync.WaitGroup
+}
+
+// NewService 创建推送服务，fetcher 为 nil 时不检查新询价单
+func NewService(repo Repository, supplierService supplier.Service, fetcher InquiryFetcher, cfg Config) Service {
+	instance, _ := os.Hostname()
+	return &service{
+		repo:            repo,
+		supplierService: supplierService,
+		fetcher:         fetcher,
+		cfg:             cfg,
+		instance:        fmt.Sprintf("%s:%d", instance, os.Getpid()),
+		subs:            make(map[string]*Subscription),
+		seen:            make(map[int64]time.Time),
+		known:           make(map[string]map[string]bool),
+		wake:            make(chan struct{}, 1),
+		inquiryWake:     make(chan struct{}, 1),
+		inquiryRefresh:  make(chan struct{}, 1),
+		stop:            make(chan struct{}),
+	}
+}
+
+func (s *service) Publish(ctx context.Context, userID, eventType string, data any, dedupKey string) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	e := Event{UserID: &userID, Type: eventType, Data: datatypes.JSON(payload)}
+	if dedupKey != "" {
+		e.DedupKey = &dedupKey
+	}
+	if _, err := s.repo.Insert(ctx, []Event{e}); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+func (s *service) PublishSupplier(ctx context.Context, customerCode, eventType string, data any, dedupKey string) error {
+	members, err := s.supplierService.ListMembers(ctx, customerCode)
+	if err != nil {
+		return err
+	}
+	if len(members) == 0 {
+		return nil
+	}
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	events := make([]Event, 0, len(members))
+	for _, m := range members {
+		userID := m.UserID
+		e := Event{UserID: &userID, Type: eventType, Data: datatypes.JSON(payload)}
+		if dedupKey != "" {
+			key := dedupKey + ":" + userID
+			e.DedupKey = &key
+		}
+		events = append(events, e)
+	}
+	if _, err := s.repo.Insert(ctx, events); err != nil {
+		return err
+	}
+	s.notify()
+	return nil
+}
+
+func (s *service) Broadcast(ctx context.Context, eventType string, data any) (*Event, error) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	events := []Event{{Type: eventType, Data: datatypes.JSON(payload)}}
+	if _, err := s.repo.Insert(ctx, events); err != nil {
+		return nil, err
+	}
+	s.notify()
+	return &events[0], nil
+}
+
+// notify 唤醒本实例的轮询，让本实例的连接尽快收到刚写入的事件
+func (s *service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *service) Subscribe(ctx context.Context, userID, customerCode string) (*Subscription, error) {
+	if s.isStopped() {
+		return nil, ErrStopped
+	}
+
+	now := time.Now()
+	conn := &Connection{ID: uuid.NewString(), UserID: userID, Instance: s.instance, SeenAt: now}
+	ok, err := s.repo.AcquireConnection(ctx, conn, s.cfg.MaxConnections, now.Add(-connectionTTL))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrTooManyConnections
+	}
+
+	ch := make(chan *Event, subscriptionBuffer)
+	sub := &Subscription{ID: conn.ID, UserID: userID, CustomerCode: customerCode, C: ch, ch: ch, service: s}
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		sub.Close()
+		return nil, ErrStopped
+	}
+	newCode := customerCode != "" && !s.hasCodeLocked(customerCode)
+	s.subs[sub.ID] = sub
+	s.mu.Unlock()
+
+	// 新出现的供应商尽快记下当前的询价单，之后新增的才推送
+	if newCode {
+		select {
+		case s.inquiryWake <- struct{}{}:
+		default:
+		}
+	}
+	return sub, nil
+}
+
+func (s *service) Replay(ctx context.Context, userID string, afterID int64) ([]Event, bool, error) {
+	oldest, _, err := s.repo.Bounds(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	// 断点与最早保留的事件之间可能有已清理的事件
+	if oldest > afterID+1 {
+		return nil, true, nil
+	}
+	events, err := s.repo.ListForUser(ctx, userID, afterID, replayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > replayLimit {
+		return nil, true, nil
+	}
+	return events, false, nil
+}
+
+// Start 启动事件日志轮询、日常维护和新询价单检查
+func (s *service) Start() {
+	s.wg.Add(1)
+	go s.run()
+	if s.fetcher != nil && s.cfg.InquiryInterval > 0 {
+		s.wg.Add(1)
+		go s.watchInquiries()
+	}
+}
+
+func (s *service) Stop() {
+	s.stopOnce.Do(func() {
+		s.mu.Lock()
+		s.stopped = true
+		for id, sub := range s.subs {
+			if !sub.closed {
+				sub.closed = true
+				close(sub.ch)
+			}
+			delete(s.subs, id)
+		}
+		s.mu.Unlock()
+		close(s.stop)
+		s.wg.Wait()
+	})
+}
+
+func (s *service) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func (s *service) run() {
+	defer s.wg.Done()
+	pollTicker := time.NewTicker(s.cfg.PollInterval)
+	defer pollTicker.Stop()
+	maintainTicker := time.NewTicker(maintainInterval)
+	defer maintainTicker.Stop()
+
+	s.maintain()
+	for {
+		s.poll()
+		select {
+		case <-s.stop:
+			return
+		case <-s.wake:
+		case <-pollTicker.C:
+		case <-maintainTicker.C:
+			s.maintain()
+		}
+	}
+}
+
+// poll 分发游标之后的新事件
+func (s *service) poll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// 启动时从日志末尾开始，历史事件只通过断线续传补发
+	if !s.ready {
+		_, latest, err := s.repo.Bounds(ctx)
+		if err != nil {
+			log.Printf("[STREAM] 查询事件日志失败: %v", err)
+			return
+		}
+		s.low, s.ready = latest, true
+	}
+
+	now := time.Now()
+	after := s.low
+	for {
+		events, err := s.repo.ListAfter(ctx, after, pollBatch)
+		if err != nil {
+			log.Printf("[STREAM] 查询事件日志失败: %v", err)
+			break
+		}
+		for i := range events {
+			if _, ok := s.seen[events[i].ID]; ok {
+				continue
+			}
+			s.seen[events[i].ID] = now
+			s.dispatch(&events[i])
+		}
+		if len(events) < pollBatch {
+			break
+		}
+		after = events[len(events)-1].ID
+	}
+
+	// 已分发超过 gapGrace 的事件之前不会再有新提交的事件，游标前移
+	for id, at := range s.seen {
+		if now.Sub(at) > gapGrace && id > s.low {
+			s.low = id
+		}
+	}
+	for id := range s.seen {
+		if id <= s.low {
+			delete(s.seen, id)
+		}
+	}
+}
+
+// dispatch 把事件交给本实例中匹配的连接；缓冲已满的连接直接断开，由客户端重连续传
+func (s *service) dispatch(e *Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, sub := range s.subs {
+		if sub.closed || (e.UserID != nil && *e.UserID != sub.UserID) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			log.Printf("[STREAM] 连接 %s 消费过慢，断开", id)
+			sub.closed = true
+			close(sub.ch)
+			delete(s.subs, id)
+		}
+	}
+}
+
+// maintain 刷新本实例连接的登记，清理过期连接和超出保留时长的事件
+func (s *service) maintain() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	now := time.Now()
+
+	s.mu.Lock()
+	ids := make([]string, 0, len(s.subs))
+	for id := range s.subs {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+
+	if err := s.repo.TouchConnections(ctx, ids, now); err != nil {
+		log.Printf("[STREAM] 刷新连接失败: %v", err)
+	}
+	if err := s.repo.DeleteStaleConnections(ctx, now.Add(-connectionTTL)); err != nil {
+		log.Printf("[STREAM] 清理过期连接失败: %v", err)
+	}
+	if s.cfg.Retention > 0 {
+		if _, err := s.repo.DeleteBefore(ctx, now.Add(-s.cfg.Retention)); err != nil {
+			log.Printf("[STREAM] 清理事件日志失败: %v", err)
+		}
+	}
+}
+
+// hasCodeLocked 本实例是否已有该供应商的连接，调用方需持有 mu
+func (s *service) hasCodeLocked(customerCode string) bool {
+	for _, sub := range s.subs {
+		if sub.CustomerCode == customerCode {
+			return true
+		}
+	}
+	return false
+}
+
+// activeCodes 本实例在线连接所属的供应商
+func (s *service) activeCodes() map[string]bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	codes := make(map[string]bool)
+	for _, sub := range s.subs {
+		if sub.CustomerCode != "" {
+			codes[sub.CustomerCode] = true
+		}
+	}
+	return codes
+}
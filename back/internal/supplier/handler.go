@@ -2,8 +2,10 @@ package supplier
 
 import (
 	"back/internal/user"
+	"errors"
 	"net/http"
 
+	"back/pkg/jwt"
 	"back/pkg/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -13,13 +15,17 @@ import (
 type Handler struct {
 	service     Service
 	userService user.Service
+	jwtService  *jwt.JWTService
+	inviteURL   string // 前端邀请页地址，生成邀请链接用（为空时只返回 token）
 }
 
 // NewHandler 创建新的处理器实例
-func NewHandler(service Service, userService user.Service) *Handler {
+func NewHandler(service Service, userService user.Service, jwtService *jwt.JWTService, inviteURL string) *Handler {
 	return &Handler{
 		service:     service,
 		userService: userService,
+		jwtService:  jwtService,
+		inviteURL:   inviteURL,
 	}
 }
 
@@ -201,6 +207,60 @@ func (h *Handler) GetFullInfo(c *gin.Context) {
 	})
 }
 
+// GetFullInfoByCode 按供应商编码获取完整信息（机器调用方，API key 签名认证）
+// @Summary 按编码获取供应商完整信息
+// @Description 需要 supplier:read 权限范围；限定了供应商的 key 只能查询自己的供应商
+// @Tags service
+// @Produce json
+// @Param customerCode path string true "供应商编码"
+// @Success 200 {object} SupplierFullInfoResponse
+// @Failure 403 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Router /api/v1/service/suppliers/{customerCode}/full-info [get]
+func (h *Handler) GetFullInfoByCode(c *gin.Context) {
+	customerCode := c.Param("customerCode")
+
+	// API key 中间件会把限定的供应商写入 CustomerCode
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		if rc.CustomerCode != "" && rc.CustomerCode != customerCode {
+			c.JSON(http.StatusForbidden, ErrorResponse{
+				Code:    http.StatusForbidden,
+				Message: "该 API key 无权访问此供应商",
+			})
+			return
+		}
+		rc.CustomerCode = customerCode
+	}
+
+	fullInfo, err := h.service.GetFullInfoByCustomerCode(c.Request.Context(), customerCode)
+	if err != nil {
+		if errors.Is(err, ErrNotMember) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Code:    http.StatusNotFound,
+				Message: "供应商不存在",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取供应商信息失败: " + err.Error(),
+		})
+		return
+	}
+
+	var resourceID string
+	if fullInfo.Info != nil {
+		resourceID = fullInfo.Info.ID
+	}
+	h.setAuditInfo(c, "service.supplier_full_info", resourceID)
+
+	c.JSON(http.StatusOK, SupplierFullInfoResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    fullInfo,
+	})
+}
+
 // getUserIDFromContext 从上下文中获取用户ID
 func (h *Handler) getUserIDFromContext(c *gin.Context) string {
 	// 从JWT中间件中获取username
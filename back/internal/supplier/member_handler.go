@@ -0,0 +1,448 @@
+package supplier
+
+import (
+	"back/internal/user"
+	"back/pkg/jwt"
+	"back/pkg/middleware"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListMembers 获取当前供应商的成员列表
+// @Summary 获取供应商成员
+// @Description 列出当前用户所在供应商的成员及角色
+// @Tags supplier
+// @Produce json
+// @Success 200 {object} MemberListResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/members [get]
+func (h *Handler) ListMembers(c *gin.Context) {
+	_, me, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+
+	members, err := h.service.ListMembers(c.Request.Context(), me.CustomerCode)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取成员失败: " + err.Error(),
+		})
+		return
+	}
+
+	views := make([]MemberView, 0, len(members))
+	for _, m := range members {
+		view := MemberView{
+			UserID:    m.UserID,
+			Role:      m.Role,
+			InvitedBy: m.InvitedBy,
+			CreatedAt: m.CreatedAt,
+		}
+		if mu, err := h.userService.GetUser(c.Request.Context(), m.UserID); err == nil {
+			view.Username = mu.Username
+		}
+		views = append(views, view)
+	}
+
+	h.setAuditInfo(c, "supplier.list_members", me.CustomerCode)
+
+	c.JSON(http.StatusOK, MemberListResponse{
+		Code:         http.StatusOK,
+		Message:      "获取成功",
+		CustomerCode: me.CustomerCode,
+		Role:         me.Role,
+		Data:         views,
+	})
+}
+
+// CreateInvite 创建成员邀请链接
+// @Summary 邀请成员
+// @Description 负责人生成邀请链接，同事打开后加入供应商（72小时内有效，一次性）
+// @Tags supplier
+// @Accept json
+// @Produce json
+// @Param request body CreateInviteRequest true "被邀请人角色"
+// @Success 200 {object} InviteResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/invites [post]
+func (h *Handler) CreateInvite(c *gin.Context) {
+	var req CreateInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	u, me, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+
+	invite, token, err := h.service.CreateInvite(c.Request.Context(), me.CustomerCode, u.ID, req.Role)
+	if err != nil {
+		writeMemberError(c, err)
+		return
+	}
+
+	h.setAuditInfo(c, "supplier.create_invite", invite.ID)
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Detail = map[string]any{
+			"customerCode": invite.CustomerCode,
+			"role":         invite.Role,
+		}
+	}
+
+	resp := InviteResponse{
+		Code:      http.StatusOK,
+		Message:   "邀请已创建",
+		Token:     token,
+		Role:      invite.Role,
+		ExpiresAt: invite.ExpiresAt,
+	}
+	if h.inviteURL != "" {
+		resp.URL = h.inviteURL + "?token=" + url.QueryEscape(token)
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// AcceptInvite 接受成员邀请
+// @Summary 接受邀请
+// @Description 通过邀请链接加入供应商，成功后切换到该供应商并返回新 token
+// @Tags supplier
+// @Accept json
+// @Produce json
+// @Param request body AcceptInviteRequest true "邀请 token"
+// @Success 200 {object} AcceptInviteResponse
+// @Failure 400 {object} ErrorResponse
+// @Router /api/v1/supplier/invites/accept [post]
+func (h *Handler) AcceptInvite(c *gin.Context) {
+	var req AcceptInviteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	previous := SessionCustomerCode(c, u)
+
+	member, err := h.service.AcceptInvite(c.Request.Context(), req.Token, u.ID)
+	if err != nil {
+		writeMemberError(c, err)
+		return
+	}
+
+	// 受邀成员没有内部系统登记的手机号，保留原值
+	updated, err := h.userService.UpdateBinding(c.Request.Context(), u.ID, member.CustomerCode, u.Mobile)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "更新用户供应商失败: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := h.jwtService.GenerateTokenWithClaims(&jwt.Claims{
+		Username:     updated.Username,
+		CustomerCode: updated.CustomerCode,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "生成 token 失败",
+		})
+		return
+	}
+
+	h.setAuditInfo(c, "supplier.accept_invite", member.CustomerCode)
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.CustomerCode = member.CustomerCode
+		rc.Detail = map[string]any{
+			"role":                 member.Role,
+			"previousCustomerCode": previous,
+		}
+	}
+
+	c.JSON(http.StatusOK, AcceptInviteResponse{
+		Code:         http.StatusOK,
+		Message:      "已加入供应商",
+		Token:        token,
+		CustomerCode: member.CustomerCode,
+		Role:         member.Role,
+	})
+}
+
+// UpdateMemberRole 调整成员角色
+// @Summary 调整成员角色
+// @Description 负责人调整成员角色（owner/quoter/viewer）
+// @Tags supplier
+// @Accept json
+// @Produce json
+// @Param userId path string true "成员用户ID"
+// @Param request body UpdateMemberRoleRequest true "新角色"
+// @Success 200 {object} MemberResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/members/{userId} [put]
+func (h *Handler) UpdateMemberRole(c *gin.Context) {
+	var req UpdateMemberRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	u, me, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+
+	member, err := h.service.UpdateMemberRole(c.Request.Context(), me.CustomerCode, u.ID, c.Param("userId"), req.Role)
+	if err != nil {
+		writeMemberError(c, err)
+		return
+	}
+
+	h.setAuditInfo(c, "supplier.update_member_role", member.UserID)
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Detail = map[string]any{
+			"customerCode": member.CustomerCode,
+			"role":         member.Role,
+		}
+	}
+
+	c.JSON(http.StatusOK, MemberResponse{
+		Code:    http.StatusOK,
+		Message: "角色已更新",
+		Data:    member,
+	})
+}
+
+// RemoveMember 移除成员
+// @Summary 移除成员
+// @Description 负责人将成员移出供应商
+// @Tags supplier
+// @Produce json
+// @Param userId path string true "成员用户ID"
+// @Success 200 {object} MemberResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/members/{userId} [delete]
+func (h *Handler) RemoveMember(c *gin.Context) {
+	u, me, ok := h.currentMember(c)
+	if !ok {
+		return
+	}
+
+	userID := c.Param("userId")
+	if err := h.service.RemoveMember(c.Request.Context(), me.CustomerCode, u.ID, userID); err != nil {
+		writeMemberError(c, err)
+		return
+	}
+
+	h.setAuditInfo(c, "supplier.remove_member", userID)
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Detail = map[string]any{"customerCode": me.CustomerCode}
+	}
+
+	c.JSON(http.StatusOK, MemberResponse{
+		Code:    http.StatusOK,
+		Message: "成员已移除",
+	})
+}
+
+// ListMySuppliers 获取当前用户所属的全部供应商
+// @Summary 我的供应商
+// @Description 列出当前用户所属的供应商及角色，标记当前供应商
+// @Tags supplier
+// @Produce json
+// @Success 200 {object} MembershipListResponse
+// @Router /api/v1/supplier/mine [get]
+func (h *Handler) ListMySuppliers(c *gin.Context) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	active := SessionCustomerCode(c, u)
+
+	// 补录成员表上线前的绑定关系
+	if u.CustomerCode != "" {
+		if _, err := h.service.ResolveMember(c.Request.Context(), u.CustomerCode, u.ID); err != nil && !errors.Is(err, ErrNotMember) {
+			writeMemberError(c, err)
+			return
+		}
+	}
+
+	memberships, err := h.service.ListMemberships(c.Request.Context(), u.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "获取供应商失败: " + err.Error(),
+		})
+		return
+	}
+
+	views := make([]MembershipView, 0, len(memberships))
+	for _, m := range memberships {
+		views = append(views, MembershipView{
+			CustomerCode: m.CustomerCode,
+			Role:         m.Role,
+			Active:       m.CustomerCode == active,
+			JoinedAt:     m.CreatedAt,
+		})
+	}
+
+	h.setAuditInfo(c, "supplier.list_mine", "")
+
+	c.JSON(http.StatusOK, MembershipListResponse{
+		Code:         http.StatusOK,
+		Message:      "获取成功",
+		CustomerCode: active,
+		Data:         views,
+	})
+}
+
+// SwitchSupplier 切换当前供应商
+// @Summary 切换当前供应商
+// @Description 返回携带新供应商编码的 token，只影响使用该 token 的会话（其他设备上的登录不变）；同时设为下次登录的默认供应商
+// @Tags supplier
+// @Accept json
+// @Produce json
+// @Param request body SwitchSupplierRequest true "目标供应商编码"
+// @Success 200 {object} SwitchSupplierResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/switch [post]
+func (h *Handler) SwitchSupplier(c *gin.Context) {
+	var req SwitchSupplierRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Code:    http.StatusBadRequest,
+			Message: "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	u, ok := h.currentUser(c)
+	if !ok {
+		return
+	}
+
+	member, err := h.service.MemberFor(c.Request.Context(), req.CustomerCode, u.ID)
+	if err != nil {
+		writeMemberError(c, err)
+		return
+	}
+
+	previous := SessionCustomerCode(c, u)
+
+	// 会话所代表的供应商由 token 决定，users.customer_code 只是下次登录的默认值
+	if _, err := h.userService.UpdateBinding(c.Request.Context(), u.ID, member.CustomerCode, u.Mobile); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "切换供应商失败: " + err.Error(),
+		})
+		return
+	}
+
+	token, err := h.jwtService.GenerateTokenWithClaims(&jwt.Claims{
+		Username:     u.Username,
+		CustomerCode: member.CustomerCode,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Code:    http.StatusInternalServerError,
+			Message: "生成 token 失败",
+		})
+		return
+	}
+
+	h.setAuditInfo(c, "supplier.switch_active", member.CustomerCode)
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.CustomerCode = member.CustomerCode
+		rc.Detail = map[string]any{
+			"previousCustomerCode": previous,
+			"role":                 member.Role,
+		}
+	}
+
+	c.JSON(http.StatusOK, SwitchSupplierResponse{
+		Code:         http.StatusOK,
+		Message:      "已切换供应商",
+		Token:        token,
+		CustomerCode: member.CustomerCode,
+		Role:         member.Role,
+	})
+}
+
+// currentUser 取当前登录用户，失败时已写出响应
+func (h *Handler) currentUser(c *gin.Context) (*user.User, bool) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未登录",
+		})
+		return nil, false
+	}
+	u, err := h.userService.GetUserByUsername(c.Request.Context(), rc.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未登录",
+		})
+		return nil, false
+	}
+	return u, true
+}
+
+// SessionCustomerCode 当前会话所代表的供应商：取 token 中的编码（JWTAuth 写入 RequestContext），
+// 未携带时（绑定前签发的 token）取用户的默认供应商。调用方须再用 ActiveMember 校验成员身份
+func SessionCustomerCode(c *gin.Context, u *user.User) string {
+	if rc := middleware.GetRequestContext(c); rc != nil && rc.CustomerCode != "" {
+		return rc.CustomerCode
+	}
+	return u.CustomerCode
+}
+
+// currentMember 取当前用户及其在当前会话所代表供应商下的成员身份，失败时已写出响应
+func (h *Handler) currentMember(c *gin.Context) (*user.User, *SupplierMember, bool) {
+	u, ok := h.currentUser(c)
+	if !ok {
+		return nil, nil, false
+	}
+	member, err := h.service.ActiveMember(c.Request.Context(), SessionCustomerCode(c, u), u.CustomerCode, u.ID)
+	if err != nil {
+		writeMemberError(c, err)
+		return nil, nil, false
+	}
+	return u, member, true
+}
+
+// writeMemberError 成员相关错误转换为响应
+func writeMemberError(c *gin.Context, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotMember), errors.Is(err, ErrPermissionDenied):
+		status = http.StatusForbidden
+	case errors.Is(err, ErrInviteInvalid), errors.Is(err, ErrInviteExpired), errors.Is(err, ErrLastOwner),
+		errors.Is(err, ErrInvalidRole):
+		status = http.StatusBadRequest
+	}
+	c.JSON(status, ErrorResponse{
+		Code:    status,
+		Message: err.Error(),
+	})
+}
@@ -23,6 +23,76 @@ func (SupplierProfile) TableName() string {
 	return "supplier_profiles"
 }
 
+// 供应商成员角色
+const (
+	RoleOwner  = "owner"  // 负责人：绑定供应商的用户，可邀请成员、调整角色
+	RoleQuoter = "quoter" // 报价员：可保存/提交/撤回报价
+	RoleViewer = "viewer" // 只读：仅查看询价单
+)
+
+// roleRank 角色等级，高等级包含低等级的权限
+var roleRank = map[string]int{
+	RoleViewer: 1,
+	RoleQuoter: 2,
+	RoleOwner:  3,
+}
+
+// RoleAtLeast 判断 role 是否具备 required 的权限
+func RoleAtLeast(role, required string) bool {
+	return roleRank[role] > 0 && roleRank[role] >= roleRank[required]
+}
+
+// ValidRole 是否为合法角色
+func ValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// SupplierOrg 供应商组织（按内部系统供应商编码）
+type SupplierOrg struct {
+	ID           string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CustomerCode string    `json:"customerCode" gorm:"type:varchar(50);not null;uniqueIndex"`
+	CreatedBy    string    `json:"createdBy" gorm:"type:uuid"` // 首个绑定的用户
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (SupplierOrg) TableName() string {
+	return "supplier_orgs"
+}
+
+// SupplierMember 供应商组织成员
+type SupplierMember struct {
+	ID           string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CustomerCode string    `json:"customerCode" gorm:"type:varchar(50);not null;uniqueIndex:idx_supplier_member"`
+	UserID       string    `json:"userId" gorm:"type:uuid;not null;uniqueIndex:idx_supplier_member;index"`
+	Role         string    `json:"role" gorm:"type:varchar(20);not null"` // owner, quoter, viewer
+	InvitedBy    string    `json:"invitedBy" gorm:"type:uuid"`            // 邀请人（负责人为空）
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (SupplierMember) TableName() string {
+	return "supplier_members"
+}
+
+// SupplierInvite 成员邀请（邀请链接一次有效）
+type SupplierInvite struct {
+	ID           string     `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CustomerCode string     `json:"customerCode" gorm:"type:varchar(50);not null;index"`
+	TokenHash    string     `json:"-" gorm:"type:varchar(64);not null;uniqueIndex"` // 邀请 token 的 SHA-256
+	Role         string     `json:"role" gorm:"type:varchar(20);not null"`
+	CreatedBy    string     `json:"createdBy" gorm:"type:uuid;not null"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	AcceptedBy   string     `json:"acceptedBy" gorm:"type:varchar(36)"`
+	AcceptedAt   *time.Time `json:"acceptedAt"`
+	CreatedAt    time.Time  `json:"createdAt" gorm:"autoCreateTime"`
+}
+
+func (SupplierInvite) TableName() string {
+	return "supplier_invites"
+}
+
 // ========== Request ==========
 
 // CreateSupplierRequest 创建/更新供应商请求
@@ -38,8 +108,97 @@ type UpdateCapabilitiesRequest struct {
 	Capabilities map[string]int `json:"capabilities" binding:"required"`
 }
 
+// CreateInviteRequest 创建成员邀请请求
+type CreateInviteRequest struct {
+	Role string `json:"role" binding:"required,oneof=quoter viewer"`
+}
+
+// AcceptInviteRequest 接受邀请请求
+type AcceptInviteRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// UpdateMemberRoleRequest 调整成员角色请求
+type UpdateMemberRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=owner quoter viewer"`
+}
+
+// SwitchSupplierRequest 切换当前供应商请求
+type SwitchSupplierRequest struct {
+	CustomerCode string `json:"customerCode" binding:"required"`
+}
+
 // ========== Response ==========
 
+// MembershipView 用户所属供应商
+type MembershipView struct {
+	CustomerCode string    `json:"customerCode"`
+	Role         string    `json:"role"`
+	Active       bool      `json:"active"` // 是否为当前供应商
+	JoinedAt     time.Time `json:"joinedAt"`
+}
+
+// MembershipListResponse 用户所属供应商列表响应
+type MembershipListResponse struct {
+	Code         int              `json:"code"`
+	Message      string           `json:"message"`
+	CustomerCode string           `json:"customerCode"` // 当前供应商
+	Data         []MembershipView `json:"data"`
+}
+
+// SwitchSupplierResponse 切换当前供应商响应
+type SwitchSupplierResponse struct {
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	Token        string `json:"token"` // 携带新供应商编码的 JWT
+	CustomerCode string `json:"customerCode"`
+	Role         string `json:"role"`
+}
+
+// MemberView 成员列表项
+type MemberView struct {
+	UserID    string    `json:"userId"`
+	Username  string    `json:"username"`
+	Role      string    `json:"role"`
+	InvitedBy string    `json:"invitedBy"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// MemberListResponse 成员列表响应
+type MemberListResponse struct {
+	Code         int          `json:"code"`
+	Message      string       `json:"message"`
+	CustomerCode string       `json:"customerCode"`
+	Role         string       `json:"role"` // 当前用户的角色
+	Data         []MemberView `json:"data"`
+}
+
+// InviteResponse 创建邀请响应（token 仅在创建时返回一次）
+type InviteResponse struct {
+	Code      int       `json:"code"`
+	Message   string    `json:"message"`
+	Token     string    `json:"token"`
+	URL       string    `json:"url,omitempty"`
+	Role      string    `json:"role"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// AcceptInviteResponse 接受邀请响应
+type AcceptInviteResponse struct {
+	Code         int    `json:"code"`
+	Message      string `json:"message"`
+	Token        string `json:"token"` // 携带新供应商编码的 JWT
+	CustomerCode string `json:"customerCode"`
+	Role         string `json:"role"`
+}
+
+// MemberResponse 单个成员响应
+type MemberResponse struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    *SupplierMember `json:"data,omitempty"`
+}
+
 // SupplierProfileResponse 供应商档案响应
 type SupplierProfileResponse struct {
 	Code    int              `json:"code"`
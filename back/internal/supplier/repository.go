@@ -14,6 +14,21 @@ type Repository interface {
 	CreateOrUpdateProfile(ctx context.Context, profile *SupplierProfile) error
 	GetProfileByUserID(ctx context.Context, userID string) (*SupplierProfile, error)
 	UpdateCapabilities(ctx context.Context, userID string, capabilities map[string]int) error
+
+	// 组织与成员
+	EnsureOrg(ctx context.Context, customerCode, createdBy string) error
+	GetMember(ctx context.Context, customerCode, userID string) (*SupplierMember, error)
+	ListMembers(ctx context.Context, customerCode string) ([]SupplierMember, error)
+	ListMembershipsByUser(ctx context.Context, userID string) ([]SupplierMember, error)
+	CountMembers(ctx context.Context, customerCode, role string) (int64, error)
+	ListCustomerCodes(ctx context.Context) ([]string, error)
+	SaveMember(ctx context.Context, member *SupplierMember) error
+	DeleteMember(ctx context.Context, customerCode, userID string) error
+
+	// 邀请
+	CreateInvite(ctx context.Context, invite *SupplierInvite) error
+	GetInviteByTokenHash(ctx context.Context, tokenHash string) (*SupplierInvite, error)
+	Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 }
 
 type repository struct {
@@ -81,4 +96,133 @@ func (r *repository) UpdateCapabilities(ctx context.Context, userID string, capa
 		UpdatedAt:   time.Now(),
 	}
 	return r.db.WithContext(ctx).Create(profile).Error
-}
\ No newline at end of file
+}
+
+func (r *repository) EnsureOrg(ctx context.Context, customerCode, createdBy string) error {
+	org := &SupplierOrg{CustomerCode: customerCode, CreatedBy: createdBy}
+	return r.db.WithContext(ctx).
+		Where(SupplierOrg{CustomerCode: customerCode}).
+		FirstOrCreate(org).Error
+}
+
+func (r *repository) GetMember(ctx context.Context, customerCode, userID string) (*SupplierMember, error) {
+	var member SupplierMember
+	err := r.db.WithContext(ctx).
+		Where("customer_code = ? AND user_id = ?", customerCode, userID).
+		First(&member).Error
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *repository) ListMembers(ctx context.Context, customerCode string) ([]SupplierMember, error) {
+	var members []SupplierMember
+	err := r.db.WithContext(ctx).
+		Where("customer_code = ?", customerCode).
+		Order("created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *repository) ListMembershipsByUser(ctx context.Context, userID string) ([]SupplierMember, error) {
+	var members []SupplierMember
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at ASC").
+		Find(&members).Error
+	return members, err
+}
+
+// CountMembers 统计成员数，role 为空时统计全部
+func (r *repository) CountMembers(ctx context.Context, customerCode, role string) (int64, error) {
+	var count int64
+	q := r.db.WithContext(ctx).Model(&SupplierMember{}).Where("customer_code = ?", customerCode)
+	if role != "" {
+		q = q.Where("role = ?", role)
+	}
+	err := q.Count(&count).Error
+	return count, err
+}
+
+// ListCustomerCodes 有成员（仍在绑定中）的全部供应商编码
+func (r *repository) ListCustomerCodes(ctx context.Context) ([]string, error) {
+	var codes []string
+	err := r.db.WithContext(ctx).
+		Model(&SupplierMember{}).
+		Distinct("customer_code").
+		Order("customer_code ASC").
+		Pluck("customer_code", &codes).Error
+	return codes, err
+}
+
+func (r *repository) SaveMember(ctx context.Context, member *SupplierMember) error {
+	return r.db.WithContext(ctx).Save(member).Error
+}
+
+func (r *repository) DeleteMember(ctx context.Context, customerCode, userID string) error {
+	return r.db.WithContext(ctx).
+		Where("customer_code = ? AND user_id = ?", customerCode, userID).
+		Delete(&SupplierMember{}).Error
+}
+
+func (r *repository) CreateInvite(ctx context.Context, invite *SupplierInvite) error {
+	return r.db.WithContext(ctx).Create(invite).Error
+}
+
+func (r *repository) GetInviteByTokenHash(ctx context.Context, tokenHash string) (*SupplierInvite, error) {
+	var invite SupplierInvite
+	err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&invite).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
+
+func (r *repository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// MergeProfiles 账号合并时迁移供应商档案（在调用方事务内执行）
+// 目标用户没有档案时直接转移，已有档案时保留目标用户的档案
+func MergeProfiles(tx *gorm.DB, fromUserID, toUserID string) error {
+	var count int64
+	if err := tx.Model(&SupplierProfile{}).Where("user_id = ?", toUserID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return tx.Where("user_id = ?", fromUserID).Delete(&SupplierProfile{}).Error
+	}
+	return tx.Model(&SupplierProfile{}).Where("user_id = ?", fromUserID).Update("user_id", toUserID).Error
+}
+
+// MergeMembers 账号合并时迁移供应商成员关系（在调用方事务内执行）
+// 目标用户已是同一供应商成员时保留较高的角色
+func MergeMembers(tx *gorm.DB, fromUserID, toUserID string) error {
+	var members []SupplierMember
+	if err := tx.Where("user_id = ?", fromUserID).Find(&members).Error; err != nil {
+		return err
+	}
+	for _, m := range members {
+		var existing SupplierMember
+		err := tx.Where("customer_code = ? AND user_id = ?", m.CustomerCode, toUserID).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			if err := tx.Model(&SupplierMember{}).Where("id = ?", m.ID).Update("user_id", toUserID).Error; err != nil {
+				return err
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if roleRank[m.Role] > roleRank[existing.Role] {
+			if err := tx.Model(&existing).Update("role", m.Role).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&SupplierMember{}, "id = ?", m.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
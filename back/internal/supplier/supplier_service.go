@@ -2,12 +2,29 @@ package supplier
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"time"
 
 	"gorm.io/gorm"
 )
 
+// inviteTTL 邀请链接有效期
+const inviteTTL = 72 * time.Hour
+
+// 成员与邀请相关错误
+var (
+	ErrNotMember        = errors.New("不是该供应商的成员")
+	ErrPermissionDenied = errors.New("当前角色无权执行该操作")
+	ErrInviteInvalid    = errors.New("邀请链接无效或已使用")
+	ErrInviteExpired    = errors.New("邀请链接已过期")
+	ErrLastOwner        = errors.New("供应商至少需要保留一名负责人")
+	ErrInvalidRole      = errors.New("无效的角色")
+)
+
 type Service interface {
 	// 创建/更新供应商档案
 	CreateOrUpdateProfile(ctx context.Context, userID string, req *CreateSupplierRequest) (*SupplierProfile, error)
@@ -17,9 +34,27 @@ type Service interface {
 	
 	// 获取供应商完整信息
 	GetSupplierFullInfo(ctx context.Context, userID string) (*SupplierFullInfo, error)
+	// 按供应商编码获取完整信息（取最早的负责人的档案，供机器调用方使用）
+	GetFullInfoByCustomerCode(ctx context.Context, customerCode string) (*SupplierFullInfo, error)
 	
 	// 更新机器能力
 	UpdateCapabilities(ctx context.Context, userID string, capabilities map[string]int) error
+
+	// 成员与角色
+	EnsureOwner(ctx context.Context, customerCode, userID string) (*SupplierMember, error)
+	MemberFor(ctx context.Context, customerCode, userID string) (*SupplierMember, error)
+	ResolveMember(ctx context.Context, customerCode, userID string) (*SupplierMember, error)
+	ActiveMember(ctx context.Context, customerCode, defaultCode, userID string) (*SupplierMember, error)
+	ListMembers(ctx context.Context, customerCode string) ([]SupplierMember, error)
+	ListMemberships(ctx context.Context, userID string) ([]SupplierMember, error)
+	ListBoundCustomerCodes(ctx context.Context) ([]string, error)
+	UpdateMemberRole(ctx context.Context, customerCode, operatorID, userID, role string) (*SupplierMember, error)
+	RemoveMember(ctx context.Context, customerCode, operatorID, userID string) error
+	LeaveSupplier(ctx context.Context, customerCode, userID string) error
+
+	// 邀请
+	CreateInvite(ctx context.Context, customerCode, operatorID, role string) (*SupplierInvite, string, error)
+	AcceptInvite(ctx context.Context, token, userID string) (*SupplierMember, error)
 }
 
 type service struct {
@@ -107,6 +142,20 @@ func (s *service) GetSupplierFullInfo(ctx context.Context, userID string) (*Supp
 	return fullInfo, nil
 }
 
+// GetFullInfoByCustomerCode 按供应商编码获取完整信息，供应商没有负责人时返回 ErrNotMember
+func (s *service) GetFullInfoByCustomerCode(ctx context.Context, customerCode string) (*SupplierFullInfo, error) {
+	members, err := s.repo.ListMembers(ctx, customerCode)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.Role == RoleOwner {
+			return s.GetSupplierFullInfo(ctx, m.UserID)
+		}
+	}
+	return nil, ErrNotMember
+}
+
 // UpdateCapabilities 更新机器能力（增量更新）
 func (s *service) UpdateCapabilities(ctx context.Context, userID string, newCapabilities map[string]int) error {
 	// 获取现有capabilities
@@ -129,3 +178,244 @@ func (s *service) UpdateCapabilities(ctx context.Context, userID string, newCapa
 
 	return s.repo.UpdateCapabilities(ctx, userID, existingCapabilities)
 }
+
+// EnsureOwner 将完成绑定的用户设为供应商负责人（组织不存在时创建）
+func (s *service) EnsureOwner(ctx context.Context, customerCode, userID string) (*SupplierMember, error) {
+	if err := s.repo.EnsureOrg(ctx, customerCode, userID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.repo.GetMember(ctx, customerCode, userID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if member == nil {
+		member = &SupplierMember{CustomerCode: customerCode, UserID: userID}
+	}
+	member.Role = RoleOwner
+	if err := s.repo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// MemberFor 查询用户在供应商下的成员身份
+func (s *service) MemberFor(ctx context.Context, customerCode, userID string) (*SupplierMember, error) {
+	if customerCode == "" {
+		return nil, ErrNotMember
+	}
+	member, err := s.repo.GetMember(ctx, customerCode, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotMember
+		}
+		return nil, err
+	}
+	return member, nil
+}
+
+// ResolveMember 查询用户绑定供应商下的成员身份（customerCode 须为用户当前绑定的编码）
+// 成员表上线前完成绑定的用户没有成员记录，供应商还没有任何成员时将其补录为负责人
+func (s *service) ResolveMember(ctx context.Context, customerCode, userID string) (*SupplierMember, error) {
+	member, err := s.MemberFor(ctx, customerCode, userID)
+	if !errors.Is(err, ErrNotMember) || customerCode == "" {
+		return member, err
+	}
+
+	count, err := s.repo.CountMembers(ctx, customerCode, "")
+	if err != nil {
+		return nil, err
+	}
+	if count > 0 {
+		return nil, ErrNotMember
+	}
+	return s.EnsureOwner(ctx, customerCode, userID)
+}
+
+// ActiveMember 查询用户在当前会话所代表供应商下的成员身份（customerCode 取自 token）
+// 与用户的默认供应商 defaultCode 一致时按 ResolveMember 补录旧绑定，否则须已是成员
+func (s *service) ActiveMember(ctx context.Context, customerCode, defaultCode, userID string) (*SupplierMember, error) {
+	if customerCode == defaultCode {
+		return s.ResolveMember(ctx, customerCode, userID)
+	}
+	return s.MemberFor(ctx, customerCode, userID)
+}
+
+// ListMembers 列出供应商的全部成员
+func (s *service) ListMembers(ctx context.Context, customerCode string) ([]SupplierMember, error) {
+	return s.repo.ListMembers(ctx, customerCode)
+}
+
+// ListMemberships 列出用户所属的全部供应商
+func (s *service) ListMemberships(ctx context.Context, userID string) ([]SupplierMember, error) {
+	return s.repo.ListMembershipsByUser(ctx, userID)
+}
+
+// ListBoundCustomerCodes 列出有成员的全部供应商编码（后台轮询用）
+func (s *service) ListBoundCustomerCodes(ctx context.Context) ([]string, error) {
+	return s.repo.ListCustomerCodes(ctx)
+}
+
+// UpdateMemberRole 负责人调整成员角色
+func (s *service) UpdateMemberRole(ctx context.Context, customerCode, operatorID, userID, role string) (*SupplierMember, error) {
+	if !ValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if err := s.requireOwner(ctx, customerCode, operatorID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.MemberFor(ctx, customerCode, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == RoleOwner && role != RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, customerCode); err != nil {
+			return nil, err
+		}
+	}
+
+	member.Role = role
+	if err := s.repo.SaveMember(ctx, member); err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+// RemoveMember 负责人移除成员
+func (s *service) RemoveMember(ctx context.Context, customerCode, operatorID, userID string) error {
+	if err := s.requireOwner(ctx, customerCode, operatorID); err != nil {
+		return err
+	}
+	member, err := s.MemberFor(ctx, customerCode, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == RoleOwner {
+		if err := s.ensureAnotherOwner(ctx, customerCode); err != nil {
+			return err
+		}
+	}
+	return s.repo.DeleteMember(ctx, customerCode, userID)
+}
+
+// LeaveSupplier 用户解绑或改绑时退出原供应商，不做负责人数量校验
+func (s *service) LeaveSupplier(ctx context.Context, customerCode, userID string) error {
+	if customerCode == "" {
+		return nil
+	}
+	return s.repo.DeleteMember(ctx, customerCode, userID)
+}
+
+// CreateInvite 负责人创建邀请，返回邀请记录和明文 token（只返回一次）
+func (s *service) CreateInvite(ctx context.Context, customerCode, operatorID, role string) (*SupplierInvite, string, error) {
+	if role != RoleQuoter && role != RoleViewer {
+		return nil, "", ErrInvalidRole
+	}
+	if err := s.requireOwner(ctx, customerCode, operatorID); err != nil {
+		return nil, "", err
+	}
+
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return nil, "", err
+	}
+	token := hex.EncodeToString(b)
+
+	invite := &SupplierInvite{
+		CustomerCode: customerCode,
+		TokenHash:    hashInviteToken(token),
+		Role:         role,
+		CreatedBy:    operatorID,
+		ExpiresAt:    time.Now().Add(inviteTTL),
+	}
+	if err := s.repo.CreateInvite(ctx, invite); err != nil {
+		return nil, "", err
+	}
+	return invite, token, nil
+}
+
+// AcceptInvite 接受邀请成为供应商成员，已是成员时保留较高的角色
+func (s *service) AcceptInvite(ctx context.Context, token, userID string) (*SupplierMember, error) {
+	invite, err := s.repo.GetInviteByTokenHash(ctx, hashInviteToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInviteInvalid
+		}
+		return nil, err
+	}
+	if invite.AcceptedAt != nil {
+		return nil, ErrInviteInvalid
+	}
+	if time.Now().After(invite.ExpiresAt) {
+		return nil, ErrInviteExpired
+	}
+
+	var member SupplierMember
+	err = s.repo.Transaction(ctx, func(tx *gorm.DB) error {
+		now := time.Now()
+		// 条件更新保证邀请只被使用一次
+		result := tx.Model(&SupplierInvite{}).
+			Where("id = ? AND accepted_at IS NULL", invite.ID).
+			Updates(map[string]interface{}{"accepted_by": userID, "accepted_at": now})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrInviteInvalid
+		}
+
+		err := tx.Where("customer_code = ? AND user_id = ?", invite.CustomerCode, userID).First(&member).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			member = SupplierMember{
+				CustomerCode: invite.CustomerCode,
+				UserID:       userID,
+				Role:         invite.Role,
+				InvitedBy:    invite.CreatedBy,
+			}
+			return tx.Create(&member).Error
+		}
+		if roleRank[invite.Role] > roleRank[member.Role] {
+			member.Role = invite.Role
+			return tx.Save(&member).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
+
+// requireOwner 校验操作人是供应商负责人
+func (s *service) requireOwner(ctx context.Context, customerCode, operatorID string) error {
+	operator, err := s.MemberFor(ctx, customerCode, operatorID)
+	if err != nil {
+		return err
+	}
+	if operator.Role != RoleOwner {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// ensureAnotherOwner 移除或降级负责人前，确认还有其他负责人
+func (s *service) ensureAnotherOwner(ctx context.Context, customerCode string) error {
+	owners, err := s.repo.CountMembers(ctx, customerCode, RoleOwner)
+	if err != nil {
+		return err
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
+
+// hashInviteToken 邀请 token 只存哈希，数据库泄露时无法直接使用
+func hashInviteToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
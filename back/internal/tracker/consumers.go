@@ -0,0 +1,15 @@
+package tracker
+
+import (
+	"back/internal/webhook"
+	"context"
+)
+
+// WebhookConsumer 收到 ERP 推送的报价/询价单事件时立即重新拉取该供应商，
+// 让快照和事件流尽快与 ERP 一致（推送只是提示，变化仍以快照对比为准）
+func WebhookConsumer(s Service) webhook.Consumer {
+	return func(ctx context.Context, e *webhook.Event) error {
+		s.Trigger(e.CustomerCode)
+		return nil
+	}
+}
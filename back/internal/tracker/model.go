@@ -0,0 +1,92 @@
+package tracker
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// 报价跟踪事件类型
+const (
+	EventQuoteAwarded       = "quote.awarded"            // 报价中标
+	EventQuoteRejected      = "quote.rejected"           // 报价未中标
+	EventQuoteStatusChanged = "quote.status_changed"     // 报价状态变化（提交/撤回等）
+	EventInquiryClosed      = "inquiry.closed"           // 询价单已截止
+	EventDeadlineChanged    = "inquiry.deadline_changed" // 采购方调整了截止时间
+)
+
+// validEventTypes 事件流可筛选的类型
+var validEventTypes = map[string]bool{
+	EventQuoteAwarded:       true,
+	EventQuoteRejected:      true,
+	EventQuoteStatusChanged: true,
+	EventInquiryClosed:      true,
+	EventDeadlineChanged:    true,
+}
+
+// 中标状态（由 ERP 行的中标字段归一化而来）
+const (
+	AwardPending  = ""         // 未定标
+	AwardAwarded  = "awarded"  // 中标
+	AwardRejected = "rejected" // 未中标
+)
+
+// QuoteSnapshot 供应商每条已报价记录的最近一次轮询快照，按 (CustomerCode, QuoteKey) 唯一
+type QuoteSnapshot struct {
+	ID           string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CustomerCode string         `json:"customerCode" gorm:"type:varchar(50);not null;uniqueIndex:idx_quote_snapshot"`
+	QuoteKey     string         `json:"quoteKey" gorm:"type:varchar(64);not null;uniqueIndex:idx_quote_snapshot"` // 报价ID，缺失时为询价单ID
+	InquiryID    string         `json:"inquiryId" gorm:"type:varchar(64);index"`
+	QuoteStatus  string         `json:"quoteStatus" gorm:"type:varchar(20)"` // QStatus 原值
+	AwardStatus  string         `json:"awardStatus" gorm:"type:varchar(20)"` // awarded / rejected / 空
+	EndDate      string         `json:"endDate" gorm:"type:varchar(40)"`     // 截止时间原值
+	Closed       bool           `json:"closed" gorm:"not null;default:false"`
+	Raw          datatypes.JSON `json:"raw" gorm:"type:jsonb"` // ERP 原始行
+	CreatedAt    time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt    time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (QuoteSnapshot) TableName() string {
+	return "quote_snapshots"
+}
+
+// QuoteEvent 快照对比得到的变化事件，按供应商提供事件流
+type QuoteEvent struct {
+	ID           string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	CustomerCode string    `json:"customerCode" gorm:"type:varchar(50);not null;index:idx_quote_event_feed,priority:1"`
+	QuoteKey     string    `json:"quoteKey" gorm:"type:varchar(64);not null"`
+	InquiryID    string    `json:"inquiryId" gorm:"type:varchar(64);index"`
+	Type         string    `json:"type" gorm:"type:varchar(40);not null;index"`
+	Before       string    `json:"before" gorm:"type:varchar(100)"` // 变化前的值（状态/截止时间）
+	After        string    `json:"after" gorm:"type:varchar(100)"`  // 变化后的值
+	CreatedAt    time.Time `json:"createdAt" gorm:"autoCreateTime;index:idx_quote_event_feed,priority:2"`
+}
+
+func (QuoteEvent) TableName() string {
+	return "quote_events"
+}
+
+// ========== Request ==========
+
+// EventQuery 事件流查询条件
+type EventQuery struct {
+	Type   string
+	Cursor string
+	Limit  int
+}
+
+// ========== Response ==========
+
+// EventListResponse 事件流响应
+type EventListResponse struct {
+	Code       int          `json:"code"`
+	Message    string       `json:"message"`
+	Data       []QuoteEvent `json:"data"`
+	NextCursor string       `json:"nextCursor,omitempty"` // 更早一页的游标，为空表示没有更多
+}
+
+// ErrorResponse 错误响应
+type ErrorResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
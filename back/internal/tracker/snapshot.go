@@ -0,0 +1,176 @@
+package tracker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Pur_InquiryBySupplierQuoted 返回行的字段名（内部系统未给出完整字段定义，按已知命名依次尝试）
+var (
+	fieldQuoteID     = []string{"QuoteId", "Id"}
+	fieldInquiryID   = []string{"InquiryId"}
+	fieldQuoteStatus = []string{"QStatus"}
+	fieldAward       = []string{"WinStatus", "IsWin"}
+	fieldEndDate     = []string{"EndDate"}
+)
+
+// awardValues 中标字段取值：1 中标，2 未中标，其余视为未定标
+var awardValues = map[string]string{
+	"1": AwardAwarded,
+	"2": AwardRejected,
+}
+
+// endDateLayouts 截止时间的可能格式（内部系统按本地时间返回）
+var endDateLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	time.RFC3339,
+	"2006-01-02",
+}
+
+// QuoteStatusSubmitted QStatus 已提交（0 草稿，1 已撤回）
+const QuoteStatusSubmitted = "2"
+
+// parseSnapshot 把 ERP 行解析为快照，缺少报价ID和询价单ID的行返回 nil
+func parseSnapshot(customerCode string, raw json.RawMessage, now time.Time) (*QuoteSnapshot, error) {
+	row, err := DecodeRow(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	inquiryID := RowField(row, fieldInquiryID)
+	key := RowField(row, fieldQuoteID)
+	if key == "" {
+		key = inquiryID
+	}
+	if key == "" {
+		return nil, nil
+	}
+
+	s := &QuoteSnapshot{
+		CustomerCode: customerCode,
+		QuoteKey:     truncate(key, 64),
+		InquiryID:    truncate(inquiryID, 64),
+		QuoteStatus:  truncate(RowField(row, fieldQuoteStatus), 20),
+		AwardStatus:  awardValues[RowField(row, fieldAward)],
+		EndDate:      truncate(RowField(row, fieldEndDate), 40),
+		Raw:          datatypes.JSON(raw),
+	}
+	if end, ok := ParseEndDate(s.EndDate); ok {
+		s.Closed = !now.Before(end)
+	}
+	return s, nil
+}
+
+// diff 对比同一报价的前后快照，生成变化事件；首次出现的报价只建立基线，不产生事件
+func diff(prev, next *QuoteSnapshot) []QuoteEvent {
+	if prev == nil {
+		return nil
+	}
+
+	var events []QuoteEvent
+	add := func(typ, before, after string) {
+		events = append(events, QuoteEvent{
+			CustomerCode: next.CustomerCode,
+			QuoteKey:     next.QuoteKey,
+			InquiryID:    next.InquiryID,
+			Type:         typ,
+			Before:       truncate(before, 100),
+			After:        truncate(after, 100),
+		})
+	}
+
+	if prev.EndDate != "" && next.EndDate != "" && prev.EndDate != next.EndDate {
+		add(EventDeadlineChanged, prev.EndDate, next.EndDate)
+	}
+	if !prev.Closed && next.Closed {
+		add(EventInquiryClosed, prev.EndDate, next.EndDate)
+	}
+	if prev.AwardStatus != next.AwardStatus {
+		switch next.AwardStatus {
+		case AwardAwarded:
+			add(EventQuoteAwarded, prev.AwardStatus, next.AwardStatus)
+		case AwardRejected:
+			add(EventQuoteRejected, prev.AwardStatus, next.AwardStatus)
+		}
+	}
+	if prev.QuoteStatus != next.QuoteStatus {
+		add(EventQuoteStatusChanged, prev.QuoteStatus, next.QuoteStatus)
+	}
+	return events
+}
+
+// SubmittedInquiries 已报价记录中报价已提交的询价单ID
+func SubmittedInquiries(rows []json.RawMessage) (map[string]bool, error) {
+	submitted := make(map[string]bool, len(rows))
+	for _, raw := range rows {
+		row, err := DecodeRow(raw)
+		if err != nil {
+			return nil, err
+		}
+		if id := RowField(row, fieldInquiryID); id != "" && RowField(row, fieldQuoteStatus) == QuoteStatusSubmitted {
+			submitted[id] = true
+		}
+	}
+	return submitted, nil
+}
+
+// DecodeRow 解析内部系统返回的一行数据，数字保留为 json.Number
+func DecodeRow(raw json.RawMessage) (map[string]interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var row map[string]interface{}
+	if err := dec.Decode(&row); err != nil {
+		return nil, fmt.Errorf("解析内部系统数据行失败: %w", err)
+	}
+	return row, nil
+}
+
+// RowField 按候选字段名取第一个非空值，数字统一转为字符串
+func RowField(row map[string]interface{}, names []string) string {
+	for _, name := range names {
+		switch v := row[name].(type) {
+		case string:
+			if v = strings.TrimSpace(v); v != "" {
+				return v
+			}
+		case json.Number:
+			return v.String()
+		case bool:
+			if v {
+				return "1"
+			}
+			return "0"
+		}
+	}
+	return ""
+}
+
+func ParseEndDate(v string) (time.Time, bool) {
+	if v == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range endDateLayouts {
+		if t, err := time.ParseInLocation(layout, v, time.Local); err == nil {
+			if layout == "2006-01-02" {
+				t = t.AddDate(0, 0, 1) // 只有日期时按当天结束计
+			}
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// truncate 截断到数据库字段长度
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
@@ -0,0 +1,130 @@
+package tracker
+
+import (
+	"back/internal/supplier"
+	"back/internal/user"
+	"back/pkg/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 报价跟踪处理器
+type Handler struct {
+	service         Service
+	userService     user.Service
+	supplierService supplier.Service
+}
+
+// NewHandler 创建报价跟踪处理器
+func NewHandler(service Service, userService user.Service, supplierService supplier.Service) *Handler {
+	return &Handler{
+		service:         service,
+		userService:     userService,
+		supplierService: supplierService,
+	}
+}
+
+// ListEvents 当前供应商的报价变化事件流
+// @Summary 报价变化事件流
+// @Description 后台轮询已报价记录对比得到的变化（中标、未中标、截止、截止时间调整、报价状态变化），按时间倒序，游标分页
+// @Tags supplier
+// @Produce json
+// @Param type query string false "事件类型" Enums(quote.awarded, quote.rejected, quote.status_changed, inquiry.closed, inquiry.deadline_changed)
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Param limit query int false "每页条数，默认20，最大100"
+// @Success 200 {object} EventListResponse
+// @Failure 400 {object} ErrorResponse
+// @Failure 403 {object} ErrorResponse
+// @Router /api/v1/supplier/quote-events [get]
+func (h *Handler) ListEvents(c *gin.Context) {
+	customerCode, ok := h.currentSupplier(c)
+	if !ok {
+		return
+	}
+
+	q := &EventQuery{
+		Type:   strings.TrimSpace(c.Query("type")),
+		Cursor: c.Query("cursor"),
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 0 {
+			c.JSON(http.StatusBadRequest, ErrorResponse{
+				Code:    http.StatusBadRequest,
+				Message: "invalid limit",
+			})
+			return
+		}
+		q.Limit = limit
+	}
+
+	events, nextCursor, err := h.service.ListEvents(c.Request.Context(), customerCode, q)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrInvalidType) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, ErrorResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if rc := middleware.GetRequestContext(c); rc != nil {
+		rc.Action = "supplier.quote_events"
+		rc.Resource = "supplier"
+		rc.ResourceID = customerCode
+	}
+
+	c.JSON(http.StatusOK, EventListResponse{
+		Code:       http.StatusOK,
+		Message:    "获取成功",
+		Data:       events,
+		NextCursor: nextCursor,
+	})
+}
+
+// currentSupplier 取当前会话所代表的供应商编码（取自 token）并校验成员身份，失败时已写出响应
+func (h *Handler) currentSupplier(c *gin.Context) (string, bool) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户未登录",
+		})
+		return "", false
+	}
+	u, err := h.userService.GetUserByUsername(c.Request.Context(), rc.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{
+			Code:    http.StatusUnauthorized,
+			Message: "用户不存在",
+		})
+		return "", false
+	}
+	customerCode := supplier.SessionCustomerCode(c, u)
+	if customerCode == "" {
+		c.JSON(http.StatusForbidden, ErrorResponse{
+			Code:    http.StatusForbidden,
+			Message: "请先绑定供应商",
+		})
+		return "", false
+	}
+	if _, err := h.supplierService.ActiveMember(c.Request.Context(), customerCode, u.CustomerCode, u.ID); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, supplier.ErrNotMember) {
+			status = http.StatusForbidden
+		}
+		c.JSON(status, ErrorResponse{
+			Code:    status,
+			Message: err.Error(),
+		})
+		return "", false
+	}
+	return customerCode, true
+}
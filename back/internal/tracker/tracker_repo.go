@@ -0,0 +1,99 @@
+package tracker
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 报价快照与事件数据访问
+type Repository interface {
+	ListSnapshots(ctx context.Context, customerCode string) ([]QuoteSnapshot, error)
+	// Apply 在一个事务中保存本轮快照并写入变化事件
+	Apply(ctx context.Context, snapshots []QuoteSnapshot, events []QuoteEvent) error
+	ListEvents(ctx context.Context, customerCode, eventType string, before *eventCursor, limit int) ([]QuoteEvent, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建报价跟踪仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) ListSnapshots(ctx context.Context, customerCode string) ([]QuoteSnapshot, error) {
+	var snapshots []QuoteSnapshot
+	err := r.db.WithContext(ctx).
+		Where("customer_code = ?", customerCode).
+		Find(&snapshots).Error
+	return snapshots, err
+}
+
+func (r *repository) Apply(ctx context.Context, snapshots []QuoteSnapshot, events []QuoteEvent) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if len(snapshots) > 0 {
+			err := tx.Clauses(clause.OnConflict{
+				Columns: []clause.Column{{Name: "customer_code"}, {Name: "quote_key"}},
+				DoUpdates: clause.AssignmentColumns([]string{
+					"inquiry_id", "quote_status", "award_status", "end_date", "closed", "raw", "updated_at",
+				}),
+			}).Create(&snapshots).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(events) > 0 {
+			if err := tx.Create(&events).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ListEvents 按时间倒序列出供应商的事件，before 为上一页最后一条
+func (r *repository) ListEvents(ctx context.Context, customerCode, eventType string, before *eventCursor, limit int) ([]QuoteEvent, error) {
+	db := r.db.WithContext(ctx).Where("customer_code = ?", customerCode)
+	if eventType != "" {
+		db = db.Where("type = ?", eventType)
+	}
+	if before != nil {
+		db = db.Where("(created_at, id) < (?, ?)", before.CreatedAt, before.ID)
+	}
+	var events []QuoteEvent
+	err := db.Order("created_at DESC, id DESC").Limit(limit).Find(&events).Error
+	return events, err
+}
+
+// eventCursor 事件流游标（created_at, id）
+type eventCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func encodeEventCursor(e *QuoteEvent) string {
+	raw := e.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + e.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeEventCursor(cursor string) (*eventCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &eventCursor{CreatedAt: t, ID: parts[1]}, nil
+}
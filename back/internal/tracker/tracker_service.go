@@ -0,0 +1,279 @@
+package tracker
+
+import (
+	"back/internal/supplier"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+	// pollTimeout 单个供应商一次轮询的超时（含限流等待）
+	pollTimeout = 2 * time.Minute
+	// triggerBuffer 待立即重新拉取的供应商队列长度，满了丢弃（下一轮定时轮询兜底）
+	triggerBuffer = 256
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidType   = errors.New("invalid event type")
+)
+
+// Fetcher 拉取供应商已报价记录（由内部系统代理实现，调用前需限流）
+type Fetcher interface {
+	QuotedInquiries(ctx context.Context, customerCode string) ([]json.RawMessage, error)
+}
+
+// Listener 事件落库后的回调（通知等模块接入），不应阻塞
+type Listener func(ctx context.Context, e *QuoteEvent)
+
+// Service 报价结果跟踪：定时轮询已报价记录，对比快照生成事件
+type Service interface {
+	// PollSupplier 立即轮询一个供应商，返回新产生的事件
+	PollSupplier(ctx context.Context, customerCode string) ([]QuoteEvent, error)
+	// Trigger 安排尽快重新轮询该供应商（如收到 ERP 推送时）
+	Trigger(customerCode string)
+	ListEvents(ctx context.Context, customerCode string, q *EventQuery) ([]QuoteEvent, string, error)
+	Subscribe(name string, listener Listener)
+	Start()
+	Stop()
+}
+
+type namedListener struct {
+	name     string
+	listener Listener
+}
+
+type service struct {
+	repo            Repository
+	fetcher         Fetcher
+	supplierService supplier.Service
+	interval        time.Duration
+
+	mu        sync.RWMutex
+	listeners []namedListener
+
+	trigger chan string
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewService 创建报价跟踪服务，interval 为全量轮询周期
+func NewService(repo Repository, fetcher Fetcher, supplierService supplier.Service, interval time.Duration) Service {
+	return &service{
+		repo:            repo,
+		fetcher:         fetcher,
+		supplierService: supplierService,
+		interval:        interval,
+		trigger:         make(chan string, triggerBuffer),
+		stop:            make(chan struct{}),
+	}
+}
+
+func (s *service) Subscribe(name string, listener Listener) {
+	s.mu.Lock()
+	s.listeners = append(s.listeners, namedListener{name: name, listener: listener})
+	s.mu.Unlock()
+}
+
+func (s *service) PollSupplier(ctx context.Context, customerCode string) ([]QuoteEvent, error) {
+	rows, err := s.fetcher.QuotedInquiries(ctx, customerCode)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := s.repo.ListSnapshots(ctx, customerCode)
+	if err != nil {
+		return nil, err
+	}
+	prevByKey := make(map[string]*QuoteSnapshot, len(existing))
+	for i := range existing {
+		prevByKey[existing[i].QuoteKey] = &existing[i]
+	}
+
+	now := time.Now()
+	var changed []QuoteSnapshot
+	var events []QuoteEvent
+	seen := make(map[string]bool, len(rows))
+	for _, raw := range rows {
+		next, err := parseSnapshot(customerCode, raw, now)
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || seen[next.QuoteKey] {
+			continue
+		}
+		seen[next.QuoteKey] = true
+
+		prev := prevByKey[next.QuoteKey]
+		if prev != nil && sameSnapshot(prev, next) {
+			continue
+		}
+		changed = append(changed, *next)
+		events = append(events, diff(prev, next)...)
+	}
+
+	if err := s.repo.Apply(ctx, changed, events); err != nil {
+		return nil, err
+	}
+
+	if len(events) > 0 {
+		log.Printf("[QUOTE-TRACKER] 供应商 %s 产生 %d 条事件", customerCode, len(events))
+		s.notify(ctx, events)
+	}
+	return events, nil
+}
+
+// sameSnapshot 快照无变化时跳过写库
+func sameSnapshot(prev, next *QuoteSnapshot) bool {
+	return prev.InquiryID == next.InquiryID &&
+		prev.QuoteStatus == next.QuoteStatus &&
+		prev.AwardStatus == next.AwardStatus &&
+		prev.EndDate == next.EndDate &&
+		prev.Closed == next.Closed &&
+		bytes.Equal(canonicalJSON(prev.Raw), canonicalJSON(next.Raw))
+}
+
+// canonicalJSON jsonb 读回后键顺序和空白会变，比较前重新序列化（map 键有序）
+func canonicalJSON(raw []byte) []byte {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return raw
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+func (s *service) notify(ctx context.Context, events []QuoteEvent) {
+	s.mu.RLock()
+	listeners := append([]namedListener(nil), s.listeners...)
+	s.mu.RUnlock()
+
+	for i := range events {
+		for _, l := range listeners {
+			l.listener(ctx, &events[i])
+		}
+	}
+}
+
+func (s *service) Trigger(customerCode string) {
+	if customerCode == "" {
+		return
+	}
+	select {
+	case s.trigger <- customerCode:
+	default:
+		log.Printf("[QUOTE-TRACKER] 重新拉取队列已满，丢弃: %s", customerCode)
+	}
+}
+
+func (s *service) ListEvents(ctx context.Context, customerCode string, q *EventQuery) ([]QuoteEvent, string, error) {
+	if q.Type != "" && !validEventTypes[q.Type] {
+		return nil, "", ErrInvalidType
+	}
+	var before *eventCursor
+	if q.Cursor != "" {
+		c, err := decodeEventCursor(q.Cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		before = c
+	}
+	limit := q.Limit
+	if limit <= 0 {
+		limit = defaultPageSize
+	}
+	if limit > maxPageSize {
+		limit = maxPageSize
+	}
+
+	// 多取一条判断是否还有下一页
+	events, err := s.repo.ListEvents(ctx, customerCode, q.Type, before, limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+	var nextCursor string
+	if len(events) > limit {
+		events = events[:limit]
+		nextCursor = encodeEventCursor(&events[limit-1])
+	}
+	return events, nextCursor, nil
+}
+
+// Start 启动后台轮询：启动后立即跑一轮，之后按 interval 全量轮询，期间处理 Trigger 的单个供应商
+func (s *service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.pollAll()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.pollAll()
+			case code := <-s.trigger:
+				s.pollOne(code)
+			}
+		}
+	}()
+}
+
+// Stop 停止后台轮询，等待当前供应商处理完
+func (s *service) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// pollAll 逐个轮询所有已绑定供应商，收到停止信号时中断；中途插入 Trigger 的供应商优先处理
+func (s *service) pollAll() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	codes, err := s.supplierService.ListBoundCustomerCodes(ctx)
+	cancel()
+	if err != nil {
+		log.Printf("[QUOTE-TRACKER] 查询已绑定供应商失败: %v", err)
+		return
+	}
+
+	start := time.Now()
+	for _, code := range codes {
+		select {
+		case <-s.stop:
+			return
+		case triggered := <-s.trigger:
+			s.pollOne(triggered)
+		default:
+		}
+		s.pollOne(code)
+	}
+	log.Printf("[QUOTE-TRACKER] 完成一轮轮询: %d 个供应商, 耗时 %v", len(codes), time.Since(start).Round(time.Second))
+}
+
+func (s *service) pollOne(customerCode string) {
+	ctx, cancel := context.WithTimeout(context.Background(), pollTimeout)
+	defer cancel()
+	// 停止时取消限流等待
+	go func() {
+		select {
+		case <-s.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	if _, err := s.PollSupplier(ctx, customerCode); err != nil {
+		log.Printf("[QUOTE-TRACKER] 轮询供应商 %s 失败: %v", customerCode, err)
+	}
+}
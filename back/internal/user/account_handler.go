@@ -0,0 +1,145 @@
+package user
+
+import (
+	"back/internal/audit"
+	"back/pkg/middleware"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AccountHandler 用户自助查看账号相关记录
+type AccountHandler struct {
+	auditService audit.Service
+}
+
+// NewAccountHandler 创建新的处理器实例
+func NewAccountHandler(auditService audit.Service) *AccountHandler {
+	return &AccountHandler{auditService: auditService}
+}
+
+// StaffAccess 查看客服/运营人员对本账号的访问记录
+// @Summary 客服访问记录
+// @Description 列出员工通过管理后台操作本账号、或以本账号身份代查看的记录
+// @Tags account
+// @Produce json
+// @Param limit query int false "条数，默认 50，最大 200"
+// @Success 200 {object} audit.StaffAccessResponse
+// @Router /api/v1/account/staff-access [get]
+func (h *AccountHandler) StaffAccess(c *gin.Context) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "user not authenticated"})
+		return
+	}
+	rc.Action = "account.staff_access"
+	rc.Resource = "user"
+
+	limit, _ := strconv.Atoi(c.Query("limit"))
+	logs, err := h.auditService.ListStaffAccess(c.Request.Context(), rc.Username, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, UserResponse{Code: http.StatusInternalServerError, Message: "查询访问记录失败"})
+		return
+	}
+
+	views := make([]audit.StaffAccessView, 0, len(logs))
+	for _, l := range logs {
+		views = append(views, audit.StaffAccessView{
+			Time:          l.CreatedAt,
+			Staff:         l.StaffUsername,
+			Impersonation: l.Impersonation,
+			Action:        l.Action,
+			Method:        l.Method,
+			Path:          l.Path,
+			StatusCode:    l.StatusCode,
+		})
+	}
+	c.JSON(http.StatusOK, audit.StaffAccessResponse{
+		Code:    http.StatusOK,
+		Message: "获取成功",
+		Data:    views,
+	})
+}
+
+// Activity 查看本账号的操作记录（登录、绑定、报价等），员工代操作的记录会标出员工
+// @Summary 本账号操作记录
+// @Description 按时间倒序，游标分页；action 以 * 结尾时按前缀匹配（如 proxy.*）
+// @Tags account
+// @Produce json
+// @Param action query string false "操作"
+// @Param from query string false "时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param to query string false "时间止（不含）"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Param limit query int false "每页条数，默认 50，最大 100"
+// @Success 200 {object} audit.ActivityResponse
+// @Router /api/v1/account/activity [get]
+func (h *AccountHandler) Activity(c *gin.Context) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.Username == "" {
+		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "user not authenticated"})
+		return
+	}
+	rc.Action = "account.activity"
+	rc.Resource = "user"
+
+	// 只能查自己的记录，username 不接受参数
+	q := &audit.Query{
+		Username: rc.Username,
+		Action:   strings.TrimSpace(c.Query("action")),
+		Cursor:   c.Query("cursor"),
+	}
+	q.Limit, _ = strconv.Atoi(c.Query("limit"))
+	if q.Limit > 100 {
+		q.Limit = 100
+	}
+	for key, dst := range map[string]**time.Time{
+		"from": &q.From,
+		"to":   &q.To,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, UserResponse{Code: http.StatusBadRequest, Message: "invalid " + key})
+			return
+		}
+		*dst = &t
+	}
+
+	logs, nextCursor, err := h.auditService.Search(c.Request.Context(), q)
+	if err != nil {
+		if errors.Is(err, audit.ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, UserResponse{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, UserResponse{Code: http.StatusInternalServerError, Message: "查询操作记录失败"})
+		return
+	}
+
+	views := make([]audit.ActivityView, 0, len(logs))
+	for _, l := range logs {
+		views = append(views, audit.ActivityView{
+			Time:          l.CreatedAt,
+			Action:        l.Action,
+			Method:        l.Method,
+			Path:          l.Path,
+			StatusCode:    l.StatusCode,
+			ClientIP:      l.ClientIP,
+			UserAgent:     l.UserAgent,
+			Staff:         l.StaffUsername,
+			Impersonation: l.Impersonation,
+		})
+	}
+	c.JSON(http.StatusOK, audit.ActivityResponse{
+		Code:       http.StatusOK,
+		Message:    "获取成功",
+		Data:       views,
+		NextCursor: nextCursor,
+	})
+}
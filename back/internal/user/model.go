@@ -11,14 +11,19 @@ type User struct {
 	ID           string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
 	Username     string         `json:"username" gorm:"type:varchar(20);uniqueIndex"`            // 唯一用户名（自动生成6位）
 	OpenID       string         `json:"-" gorm:"type:varchar(64);uniqueIndex"`                  // 微信openid
-	UnionID      string         `json:"-" gorm:"type:varchar(64)"`                              // 微信unionid
+	UnionID      string         `json:"-" gorm:"type:varchar(64);index"`                        // 微信unionid
 	Mobile       string         `json:"mobile" gorm:"type:varchar(20)"`                         // 手机号
-	CustomerCode string         `json:"customerCode" gorm:"type:varchar(50)"`                   // 供应商编码
+	CustomerCode string         `json:"customerCode" gorm:"type:varchar(50)"`                   // 默认供应商编码：登录时写入 token，之后各会话以 token 中的编码为准
 	PhoneHash    string         `json:"-" gorm:"type:varchar(64)"`                              // 手机号哈希（用于查询索引）
 	PhoneEncrypted string       `json:"-" gorm:"type:varchar(255)"`                             // 手机号加密存储（用于解密显示）
 	Phone        string         `json:"phone" gorm:"-"`                                         // 解密后的手机号（不存数据库）
 	PhoneMasked  string         `json:"phoneMasked" gorm:"-"`                                   // 脱敏手机号（不存数据库）
-	Status       string         `json:"status" gorm:"type:varchar(20);not null;default:'active'"` // active, disabled
+	Status       string         `json:"status" gorm:"type:varchar(20);not null;default:'active'"` // active, disabled, merged
+	PhoneVerifiedAt     *time.Time `json:"phoneVerifiedAt"`                            // 手机号经微信解密校验的时间
+	SessionKeyEncrypted string     `json:"-" gorm:"type:varchar(255)"`                 // 微信 session_key 加密存储
+	SessionKeyExpiresAt *time.Time `json:"-"`                                          // session_key 本地有效期
+	MergedInto          string     `json:"-" gorm:"type:varchar(36)"`                  // 已合并到的用户ID（status=merged）
+	SessionsRevokedAt   *time.Time `json:"sessionsRevokedAt"`                          // 此时间之前签发的 token 一律失效
 	CreatedAt    time.Time      `json:"createdAt" gorm:"autoCreateTime"`
 	UpdatedAt    time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -29,6 +34,40 @@ func (User) TableName() string {
 	return "users"
 }
 
+// WechatIdentity 用户的微信身份
+// 同一用户可通过小程序、公众号等多个入口登录，每个入口一个 openid，通过 unionid 关联
+type WechatIdentity struct {
+	ID        string    `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	UserID    string    `json:"userId" gorm:"type:uuid;not null;index"`
+	AppID     string    `json:"appId" gorm:"type:varchar(64);not null;uniqueIndex:idx_wechat_identity_app_openid"`
+	OpenID    string    `json:"openId" gorm:"type:varchar(64);not null;uniqueIndex:idx_wechat_identity_app_openid"`
+	UnionID   string    `json:"-" gorm:"type:varchar(64);index"`
+	Type      string    `json:"type" gorm:"type:varchar(2)"` // 0:公众号 1:小程序
+	CreatedAt time.Time `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt time.Time `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (WechatIdentity) TableName() string {
+	return "user_wechat_identities"
+}
+
+// WechatIdentityRequest 按微信身份查找/关联/创建用户的请求
+type WechatIdentityRequest struct {
+	AppID        string
+	OpenID       string
+	UnionID      string
+	Type         string // 0:公众号 1:小程序
+	Mobile       string // 新建用户时使用（从内部系统获取）
+	CustomerCode string // 新建用户时使用
+}
+
+// WechatResolveResult 按微信身份解析用户的结果
+type WechatResolveResult struct {
+	User    *User
+	Created bool // 新建了本地用户
+	Linked  bool // 通过 unionid 将新 openid 关联到已有用户
+}
+
 // CreateUserRequest 创建用户请求
 type CreateUserRequest struct {
 	Phone       string `json:"phone"`        // 手机号（可选）
@@ -43,6 +82,45 @@ type UpdateUserRequest struct {
 	Status string `json:"status" binding:"omitempty,oneof=active disabled"`
 }
 
+// UserQuery 管理端用户列表查询条件（游标分页，按创建时间倒序）
+type UserQuery struct {
+	Status        string     // active, disabled, merged
+	Bound         *bool      // 是否已绑定供应商
+	CustomerCode  string     // 供应商编码（精确匹配）
+	PhoneHash     string     // 手机号哈希（精确匹配，由明文手机号经 Crypto.Hash 得到）
+	CreatedFrom   *time.Time // 注册时间范围
+	CreatedTo     *time.Time
+	LastLoginFrom *time.Time // 最近登录时间范围
+	LastLoginTo   *time.Time
+	Cursor        string // 上一页返回的 nextCursor
+	Limit         int
+}
+
+// UserStatusRequest 停用/启用用户请求
+type UserStatusRequest struct {
+	Reason string `json:"reason" binding:"max=200"`
+}
+
+// ImpersonateRequest 代查看用户请求
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=200"` // 代查看原因（如工单号），记入审计
+}
+
+// MergeUserRequest 管理端合并用户请求，路径中的用户并入 TargetID
+type MergeUserRequest struct {
+	TargetID string `json:"targetId" binding:"required"`      // 保留的账号ID
+	Reason   string `json:"reason" binding:"required,max=200"` // 合并原因（如工单号），记入审计
+}
+
+// ImpersonateResponse 代查看 token 响应
+type ImpersonateResponse struct {
+	Code      int       `json:"code"`
+	Message   string    `json:"message"`
+	Token     string    `json:"token,omitempty"`
+	ExpiresAt time.Time `json:"expiresAt,omitempty"`
+	Data      *User     `json:"data,omitempty"`
+}
+
 // UserResponse 单个用户响应
 type UserResponse struct {
 	Code    int    `json:"code"`
@@ -52,8 +130,9 @@ type UserResponse struct {
 
 // UserListResponse 用户列表响应
 type UserListResponse struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Data    []User `json:"data"`
-	Total   int64  `json:"total"`
+	Code       int    `json:"code"`
+	Message    string `json:"message"`
+	Data       []User `json:"data"`
+	Total      int64  `json:"total"`
+	NextCursor string `json:"nextCursor,omitempty"` // 为空表示没有更多
 }
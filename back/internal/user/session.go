@@ -0,0 +1,92 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"back/pkg/middleware"
+
+	"gorm.io/gorm"
+)
+
+// sessionCacheTTL 会话状态缓存时长
+// 本实例停用/启用用户时立即失效缓存；多实例部署时其他实例最多延迟 sessionCacheTTL 生效，
+// 管理端停用、删除、强制下线接口的文档中写明了这一时长，修改时同步更新
+const sessionCacheTTL = 30 * time.Second
+
+// sessionState 用户会话相关状态的缓存
+type sessionState struct {
+	missing   bool // 用户不存在或已删除
+	status    string
+	revokedAt *time.Time
+	fetchedAt time.Time
+}
+
+// sessionCache 按 username 缓存会话状态，避免每个请求都查库
+type sessionCache struct {
+	mu      sync.Mutex
+	entries map[string]*sessionState
+}
+
+func (c *sessionCache) get(username string, now time.Time) *sessionState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st, ok := c.entries[username]
+	if !ok || now.Sub(st.fetchedAt) > sessionCacheTTL {
+		return nil
+	}
+	return st
+}
+
+func (c *sessionCache) put(username string, st *sessionState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]*sessionState)
+	}
+	// 简单控制缓存大小：超过上限时清理过期项
+	if len(c.entries) > 10000 {
+		for k, v := range c.entries {
+			if st.fetchedAt.Sub(v.fetchedAt) > sessionCacheTTL {
+				delete(c.entries, k)
+			}
+		}
+	}
+	c.entries[username] = st
+}
+
+func (c *sessionCache) invalidate(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, username)
+}
+
+// CheckSession 校验 token 对应的会话是否仍然有效，供 JWTAuth 和刷新 token 使用
+// 用户不存在、已停用、已合并，或 token 签发时间不晚于会话作废时间时返回 middleware.ErrSessionRevoked
+func (s *service) CheckSession(ctx context.Context, username string, issuedAt time.Time) error {
+	now := time.Now()
+	st := s.sessions.get(username, now)
+	if st == nil {
+		u, err := s.repo.GetByUsername(ctx, username)
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			st = &sessionState{missing: true, fetchedAt: now}
+		case err != nil:
+			return err
+		default:
+			st = &sessionState{status: u.Status, revokedAt: u.SessionsRevokedAt, fetchedAt: now}
+		}
+		s.sessions.put(username, st)
+	}
+
+	if st.missing || st.status == "disabled" || st.status == "merged" {
+		return middleware.ErrSessionRevoked
+	}
+	// JWT 的 iat 精确到秒，与作废时间同一秒签发的 token 也视为失效
+	if st.revokedAt != nil && !issuedAt.After(st.revokedAt.Truncate(time.Second)) {
+		return middleware.ErrSessionRevoked
+	}
+	return nil
+}
@@ -1,22 +1,40 @@
 package user
 
 import (
+	"back/pkg/jwt"
 	"back/pkg/middleware"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// Handler 管理端用户接口，挂在 /api/v1/admin/users 下，调用方为员工
 type Handler struct {
-	service Service
+	service          Service
+	jwtService       *jwt.JWTService
+	impersonationTTL time.Duration // 代查看 token 有效期
 }
 
-func NewHandler(service Service) *Handler {
-	return &Handler{service: service}
+func NewHandler(service Service, jwtService *jwt.JWTService, impersonationTTL time.Duration) *Handler {
+	return &Handler{
+		service:          service,
+		jwtService:       jwtService,
+		impersonationTTL: impersonationTTL,
+	}
 }
 
 // CreateUser 创建用户
+// @Summary 创建用户（管理端）
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param request body CreateUserRequest true "用户信息"
+// @Success 201 {object} UserResponse
+// @Router /api/v1/admin/users [post]
 func (h *Handler) CreateUser(c *gin.Context) {
 	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -36,6 +54,8 @@ func (h *Handler) CreateUser(c *gin.Context) {
 		return
 	}
 
+	h.setAuditInfo(c, "admin.user_create", user, nil)
+
 	c.JSON(http.StatusCreated, UserResponse{
 		Code:    http.StatusCreated,
 		Message: "User created successfully",
@@ -43,86 +63,162 @@ func (h *Handler) CreateUser(c *gin.Context) {
 	})
 }
 
-// GetUser 获取用户（只能查看自己的）
+// GetUser 获取用户
+// @Summary 获取用户详情（管理端）
+// @Tags admin-user
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id} [get]
 func (h *Handler) GetUser(c *gin.Context) {
-	rc := middleware.GetRequestContext(c)
-	if rc == nil || rc.Username == "" {
-		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "user not authenticated"})
+	user, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
 
-	id := c.Param("id")
+	h.setAuditInfo(c, "admin.user_get", user, nil)
 
-	// 先获取用户信息
-	user, err := h.service.GetUser(c.Request.Context(), id)
-	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "user not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, UserResponse{
-			Code:    statusCode,
+	c.JSON(http.StatusOK, UserResponse{
+		Code:    http.StatusOK,
+		Message: "Success",
+		Data:    user,
+	})
+}
+
+// UpdateUser 更新用户
+// @Summary 更新用户（管理端）
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UpdateUserRequest true "更新内容"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id} [put]
+func (h *Handler) UpdateUser(c *gin.Context) {
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 检查是否是自己的账号
-	if user.Username != rc.Username {
-		c.JSON(http.StatusForbidden, UserResponse{Code: http.StatusForbidden, Message: "只能查看自己的账号"})
+	existingUser, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	h.setAuditInfo(c, "admin.user_update", existingUser, map[string]any{
+		"from_status": existingUser.Status,
+		"to_status":   req.Status,
+	})
+
+	user, err := h.service.UpdateUser(c.Request.Context(), existingUser.ID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, UserResponse{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, UserResponse{
 		Code:    http.StatusOK,
-		Message: "Success",
+		Message: "User updated successfully",
 		Data:    user,
 	})
 }
 
-// UpdateUser 更新用户（只能更新自己的）
-func (h *Handler) UpdateUser(c *gin.Context) {
-	rc := middleware.GetRequestContext(c)
-	if rc == nil || rc.Username == "" {
-		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "user not authenticated"})
+// DeleteUser 删除用户（软删除，已签发的 token 随之失效）
+// @Summary 删除用户（管理端）
+// @Description 用户此前签发的 token 全部失效，处理请求的实例立即生效；多实例部署时其他实例缓存了会话状态，最多 30 秒后生效
+// @Tags admin-user
+// @Produce json
+// @Param id path string true "用户ID"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id} [delete]
+func (h *Handler) DeleteUser(c *gin.Context) {
+	existingUser, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
+	h.setAuditInfo(c, "admin.user_delete", existingUser, nil)
 
-	id := c.Param("id")
-
-	// 先获取用户信息
-	existingUser, err := h.service.GetUser(c.Request.Context(), id)
-	if err != nil {
-		statusCode := http.StatusInternalServerError
-		if err.Error() == "user not found" {
-			statusCode = http.StatusNotFound
-		}
-		c.JSON(statusCode, UserResponse{
-			Code:    statusCode,
+	if err := h.service.DeleteUser(c.Request.Context(), existingUser.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, UserResponse{
+			Code:    http.StatusInternalServerError,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 检查是否是自己的账号
-	if existingUser.Username != rc.Username {
-		c.JSON(http.StatusForbidden, UserResponse{Code: http.StatusForbidden, Message: "只能更新自己的账号"})
-		return
+	c.JSON(http.StatusOK, UserResponse{
+		Code:    http.StatusOK,
+		Message: "User deleted successfully",
+	})
+}
+
+// DisableUser 停用用户并作废其全部会话
+// @Summary 停用用户（管理端）
+// @Description 用户此前签发的 token 全部失效，处理请求的实例立即生效；多实例部署时其他实例缓存了会话状态，最多 30 秒后生效
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UserStatusRequest false "停用原因"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id}/disable [post]
+func (h *Handler) DisableUser(c *gin.Context) {
+	h.changeStatus(c, "disabled", "admin.user_disable", "用户已停用，现有登录已失效")
+}
+
+// EnableUser 启用用户
+// @Summary 启用用户（管理端）
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UserStatusRequest false "启用原因"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id}/enable [post]
+func (h *Handler) EnableUser(c *gin.Context) {
+	h.changeStatus(c, "active", "admin.user_enable", "用户已启用")
+}
+
+// ForceLogout 强制下线：作废用户全部会话，在线的 SSE 连接会收到下线通知
+// @Summary 强制下线用户（管理端）
+// @Description 作废用户此前签发的全部 token，账号状态不变，处理请求的实例立即生效；多实例部署时其他实例缓存了会话状态，最多 30 秒后生效
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body UserStatusRequest false "下线原因"
+// @Success 200 {object} UserResponse
+// @Router /api/v1/admin/users/{id}/logout [post]
+func (h *Handler) ForceLogout(c *gin.Context) {
+	var req UserStatusRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, UserResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
 	}
 
-	var req UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, UserResponse{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
+	existingUser, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
+	h.setAuditInfo(c, "admin.user_logout", existingUser, map[string]any{
+		"reason": req.Reason,
+	})
 
-	user, err := h.service.UpdateUser(c.Request.Context(), id, &req)
+	user, err := h.service.RevokeSessions(c.Request.Context(), existingUser.ID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, UserResponse{
-			Code:    http.StatusInternalServerError,
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
@@ -130,23 +226,39 @@ func (h *Handler) UpdateUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, UserResponse{
 		Code:    http.StatusOK,
-		Message: "User updated successfully",
+		Message: "用户已强制下线",
 		Data:    user,
 	})
 }
 
-// DeleteUser 删除用户（只能删除自己的）
-func (h *Handler) DeleteUser(c *gin.Context) {
-	rc := middleware.GetRequestContext(c)
-	if rc == nil || rc.Username == "" {
-		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "user not authenticated"})
+// MergeUser 将路径中的用户合并到 targetId 指定的用户，用于用户无法自助合并的场景
+// @Summary 合并用户（管理端）
+// @Description 微信身份、手机号及各模块数据迁移到目标账号，路径中的账号标记为 merged 且其 token 全部失效；处理请求的实例立即生效，多实例部署时其他实例缓存了会话状态，最多 30 秒后生效
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "被合并的用户ID"
+// @Param request body MergeUserRequest true "目标账号及合并原因"
+// @Success 200 {object} UserResponse
+// @Failure 400 {object} UserResponse
+// @Failure 404 {object} UserResponse
+// @Router /api/v1/admin/users/{id}/merge [post]
+func (h *Handler) MergeUser(c *gin.Context) {
+	var req MergeUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
 		return
 	}
 
-	id := c.Param("id")
-
-	// 先获取用户信息
-	existingUser, err := h.service.GetUser(c.Request.Context(), id)
+	source, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	target, err := h.service.GetUser(ctx, req.TargetID)
 	if err != nil {
 		statusCode := http.StatusInternalServerError
 		if err.Error() == "user not found" {
@@ -154,21 +266,119 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 		}
 		c.JSON(statusCode, UserResponse{
 			Code:    statusCode,
+			Message: "目标账号: " + err.Error(),
+		})
+		return
+	}
+	h.setAuditInfo(c, "admin.user_merge", source, map[string]any{
+		"target_id":       target.ID,
+		"target_username": target.Username,
+		"reason":          req.Reason,
+		"initiator":       "staff",
+	})
+
+	merged, err := h.service.MergeUsers(ctx, source.ID, target.ID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
+			Message: "合并失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, UserResponse{
+		Code:    http.StatusOK,
+		Message: "账号合并成功",
+		Data:    merged,
+	})
+}
+
+// Impersonate 签发以该用户身份只读查看的短期 token（"以供应商视角查看"）
+// @Summary 代查看用户（管理端）
+// @Description token 只读、不可刷新；期间的每个请求都以员工和用户双重身份记入审计，用户可在客服访问记录中看到
+// @Tags admin-user
+// @Accept json
+// @Produce json
+// @Param id path string true "用户ID"
+// @Param request body ImpersonateRequest true "代查看原因"
+// @Success 200 {object} ImpersonateResponse
+// @Router /api/v1/admin/users/{id}/impersonate [post]
+func (h *Handler) Impersonate(c *gin.Context) {
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
 	}
 
-	// 检查是否是自己的账号
-	if existingUser.Username != rc.Username {
-		c.JSON(http.StatusForbidden, UserResponse{Code: http.StatusForbidden, Message: "只能删除自己的账号"})
+	target, ok := h.loadUser(c)
+	if !ok {
 		return
 	}
+	rc := middleware.GetRequestContext(c)
+	if rc == nil || rc.StaffUsername == "" {
+		c.JSON(http.StatusUnauthorized, UserResponse{Code: http.StatusUnauthorized, Message: "staff not authenticated"})
+		return
+	}
+	h.setAuditInfo(c, "admin.user_impersonate", target, map[string]any{
+		"reason":      req.Reason,
+		"ttl_seconds": int(h.impersonationTTL.Seconds()),
+	})
 
-	err = h.service.DeleteUser(c.Request.Context(), id)
+	if target.Status != "active" {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
+			Message: "只能代查看正常状态的用户",
+		})
+		return
+	}
+
+	token, expiresAt, err := h.jwtService.GenerateImpersonationToken(target.Username, target.CustomerCode, rc.StaffUsername, h.impersonationTTL)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, UserResponse{
 			Code:    http.StatusInternalServerError,
+			Message: "生成token失败",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, ImpersonateResponse{
+		Code:      http.StatusOK,
+		Message:   "代查看token已签发（只读）",
+		Token:     token,
+		ExpiresAt: expiresAt,
+		Data:      target,
+	})
+}
+
+// changeStatus 停用/启用用户的公共流程
+func (h *Handler) changeStatus(c *gin.Context, status, action, message string) {
+	var req UserStatusRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, UserResponse{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+			})
+			return
+		}
+	}
+
+	existingUser, ok := h.loadUser(c)
+	if !ok {
+		return
+	}
+	h.setAuditInfo(c, action, existingUser, map[string]any{
+		"from_status": existingUser.Status,
+		"reason":      req.Reason,
+	})
+
+	user, err := h.service.SetStatus(c.Request.Context(), existingUser.ID, status)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UserResponse{
+			Code:    http.StatusBadRequest,
 			Message: err.Error(),
 		})
 		return
@@ -176,28 +386,163 @@ func (h *Handler) DeleteUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, UserResponse{
 		Code:    http.StatusOK,
-		Message: "User deleted successfully",
+		Message: message,
+		Data:    user,
 	})
 }
 
 // ListUsers 获取用户列表
+// @Summary 用户列表（管理端）
+// @Description 游标分页，按注册时间倒序；phone 按明文手机号精确匹配（经哈希查询）
+// @Tags admin-user
+// @Produce json
+// @Param status query string false "状态 active/disabled/merged"
+// @Param bound query bool false "是否已绑定供应商"
+// @Param customer_code query string false "供应商编码"
+// @Param phone query string false "手机号（精确）"
+// @Param created_from query string false "注册时间起（RFC3339 或 YYYY-MM-DD）"
+// @Param created_to query string false "注册时间止（不含）"
+// @Param last_login_from query string false "最近登录起"
+// @Param last_login_to query string false "最近登录止（不含）"
+// @Param cursor query string false "上一页返回的 nextCursor"
+// @Param limit query int false "每页条数，默认 20，最大 100"
+// @Success 200 {object} UserListResponse
+// @Router /api/v1/admin/users [get]
 func (h *Handler) ListUsers(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	q, err := h.parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, UserListResponse{
+			Code:    http.StatusBadRequest,
+			Message: err.Error(),
+		})
+		return
+	}
 
-	users, total, err := h.service.ListUsers(c.Request.Context(), page, pageSize)
+	users, total, nextCursor, err := h.service.ListUsers(c.Request.Context(), q)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, UserListResponse{
-			Code:    http.StatusInternalServerError,
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrInvalidCursor) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, UserListResponse{
+			Code:    status,
 			Message: err.Error(),
 		})
 		return
 	}
 
+	// 审计只记录筛选条件，手机号不落明文
+	filters := map[string]any{}
+	for _, key := range []string{"status", "bound", "customer_code", "created_from", "created_to", "last_login_from", "last_login_to"} {
+		if v := c.Query(key); v != "" {
+			filters[key] = v
+		}
+	}
+	if c.Query("phone") != "" {
+		filters["phone"] = "***"
+	}
+	h.setAuditInfo(c, "admin.user_list", nil, map[string]any{"filters": filters, "count": len(users)})
+
 	c.JSON(http.StatusOK, UserListResponse{
-		Code:    http.StatusOK,
-		Message: "Success",
-		Data:    users,
-		Total:   total,
+		Code:       http.StatusOK,
+		Message:    "Success",
+		Data:       users,
+		Total:      total,
+		NextCursor: nextCursor,
 	})
 }
+
+// parseQuery 解析列表查询参数
+func (h *Handler) parseQuery(c *gin.Context) (*UserQuery, error) {
+	q := &UserQuery{
+		Status:       c.Query("status"),
+		CustomerCode: strings.TrimSpace(c.Query("customer_code")),
+		Cursor:       c.Query("cursor"),
+	}
+	if q.Status != "" && q.Status != "active" && q.Status != "disabled" && q.Status != "merged" {
+		return nil, errors.New("invalid status")
+	}
+	if v := c.Query("bound"); v != "" {
+		bound, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, errors.New("invalid bound")
+		}
+		q.Bound = &bound
+	}
+	if phone := strings.TrimSpace(c.Query("phone")); phone != "" {
+		q.PhoneHash = h.service.HashPhone(phone)
+	}
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New("invalid limit")
+		}
+		q.Limit = limit
+	}
+
+	for key, dst := range map[string]**time.Time{
+		"created_from":    &q.CreatedFrom,
+		"created_to":      &q.CreatedTo,
+		"last_login_from": &q.LastLoginFrom,
+		"last_login_to":   &q.LastLoginTo,
+	} {
+		v := c.Query(key)
+		if v == "" {
+			continue
+		}
+		t, err := parseTimeParam(v)
+		if err != nil {
+			return nil, errors.New("invalid " + key)
+		}
+		*dst = &t
+	}
+	return q, nil
+}
+
+// parseTimeParam 解析时间参数，支持 RFC3339 和 YYYY-MM-DD（按本地时区）
+func parseTimeParam(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.ParseInLocation("2006-01-02", v, time.Local)
+}
+
+// loadUser 按路径参数 id 获取用户，失败时已写入响应
+func (h *Handler) loadUser(c *gin.Context) (*User, bool) {
+	user, err := h.service.GetUser(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		statusCode := http.StatusInternalServerError
+		if err.Error() == "user not found" {
+			statusCode = http.StatusNotFound
+		}
+		c.JSON(statusCode, UserResponse{
+			Code:    statusCode,
+			Message: err.Error(),
+		})
+		return nil, false
+	}
+	return user, true
+}
+
+// setAuditInfo 设置审计信息，员工身份由 StaffAuth 中间件写入
+func (h *Handler) setAuditInfo(c *gin.Context, action string, target *User, detail map[string]any) {
+	rc := middleware.GetRequestContext(c)
+	if rc == nil {
+		return
+	}
+	rc.Action = action
+	rc.Resource = "user"
+	if target != nil {
+		// 记到目标用户名下，用户可在客服访问记录中看到
+		rc.Username = target.Username
+		rc.ResourceID = target.ID
+		rc.CustomerCode = target.CustomerCode
+		if detail == nil {
+			detail = map[string]any{}
+		}
+		detail["username"] = target.Username
+	}
+	if detail != nil {
+		rc.Detail = detail
+	}
+}
@@ -2,6 +2,8 @@ package user
 
 import (
 	"context"
+	"encoding/base64"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -15,8 +17,21 @@ type Repository interface {
 	GetByOpenID(ctx context.Context, openid string) (*User, error)
 	Update(ctx context.Context, user *User) error
 	Delete(ctx context.Context, id string) error
-	List(ctx context.Context, offset, limit int) ([]User, int64, error)
+	Search(ctx context.Context, q *UserQuery, after *userCursor) ([]User, int64, error)
+	UpdateStatus(ctx context.Context, id, status string, revokeAt *time.Time) error
 	UpdateLastLogin(ctx context.Context, id string) error
+	UpdateSessionKey(ctx context.Context, id, sessionKeyEncrypted string, expiresAt time.Time) error
+
+	// 微信身份
+	GetByUnionID(ctx context.Context, unionid string) (*User, error)
+	GetIdentity(ctx context.Context, appid, openid string) (*WechatIdentity, error)
+	GetIdentityByUnionID(ctx context.Context, unionid string) (*WechatIdentity, error)
+	ListIdentities(ctx context.Context, userID string) ([]WechatIdentity, error)
+	CreateIdentity(ctx context.Context, identity *WechatIdentity) error
+	UpdateIdentityUnionID(ctx context.Context, id, unionid string) error
+
+	// Transaction 在事务中执行 fn（账号合并等需要跨表一致的操作）
+	Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error
 }
 
 type repository struct {
@@ -75,28 +90,143 @@ func (r *repository) Delete(ctx context.Context, id string) error {
 	return r.db.WithContext(ctx).Delete(&User{}, "id = ?", id).Error
 }
 
-func (r *repository) List(ctx context.Context, offset, limit int) ([]User, int64, error) {
-	var users []User
-	var total int64
+// Search 按条件查询用户，after 不为空时只返回游标之后（更早创建）的记录；total 为满足条件的总数
+func (r *repository) Search(ctx context.Context, q *UserQuery, after *userCursor) ([]User, int64, error) {
+	db := r.db.WithContext(ctx).Model(&User{})
+	if q.Status != "" {
+		db = db.Where("status = ?", q.Status)
+	}
+	if q.Bound != nil {
+		if *q.Bound {
+			db = db.Where("customer_code IS NOT NULL AND customer_code <> ''")
+		} else {
+			db = db.Where("(customer_code IS NULL OR customer_code = '')")
+		}
+	}
+	if q.CustomerCode != "" {
+		db = db.Where("customer_code = ?", q.CustomerCode)
+	}
+	if q.PhoneHash != "" {
+		db = db.Where("phone_hash = ?", q.PhoneHash)
+	}
+	if q.CreatedFrom != nil {
+		db = db.Where("created_at >= ?", *q.CreatedFrom)
+	}
+	if q.CreatedTo != nil {
+		db = db.Where("created_at < ?", *q.CreatedTo)
+	}
+	if q.LastLoginFrom != nil {
+		db = db.Where("last_login_at >= ?", *q.LastLoginFrom)
+	}
+	if q.LastLoginTo != nil {
+		db = db.Where("last_login_at < ?", *q.LastLoginTo)
+	}
 
-	if err := r.db.WithContext(ctx).Model(&User{}).Count(&total).Error; err != nil {
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	err := r.db.WithContext(ctx).
-		Offset(offset).
-		Limit(limit).
-		Order("created_at DESC").
-		Find(&users).Error
-
+	if after != nil {
+		db = db.Where("(created_at, id) < (?, ?)", after.CreatedAt, after.ID)
+	}
+	var users []User
+	err := db.Order("created_at DESC, id DESC").Limit(q.Limit).Find(&users).Error
 	if err != nil {
 		return nil, 0, err
 	}
-
 	return users, total, nil
 }
 
+// UpdateStatus 更新用户状态，revokeAt 不为空时同时作废此前签发的 token
+func (r *repository) UpdateStatus(ctx context.Context, id, status string, revokeAt *time.Time) error {
+	fields := map[string]interface{}{"status": status}
+	if revokeAt != nil {
+		fields["sessions_revoked_at"] = *revokeAt
+	}
+	return r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(fields).Error
+}
+
 func (r *repository) UpdateLastLogin(ctx context.Context, id string) error {
 	now := time.Now()
 	return r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Update("last_login_at", now).Error
 }
+
+func (r *repository) UpdateSessionKey(ctx context.Context, id, sessionKeyEncrypted string, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"session_key_encrypted":  sessionKeyEncrypted,
+		"session_key_expires_at": expiresAt,
+	}).Error
+}
+
+func (r *repository) GetByUnionID(ctx context.Context, unionid string) (*User, error) {
+	var user User
+	err := r.db.WithContext(ctx).First(&user, "union_id = ?", unionid).Error
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (r *repository) GetIdentity(ctx context.Context, appid, openid string) (*WechatIdentity, error) {
+	var identity WechatIdentity
+	err := r.db.WithContext(ctx).First(&identity, "app_id = ? AND open_id = ?", appid, openid).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *repository) GetIdentityByUnionID(ctx context.Context, unionid string) (*WechatIdentity, error) {
+	var identity WechatIdentity
+	err := r.db.WithContext(ctx).Order("created_at ASC").First(&identity, "union_id = ?", unionid).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *repository) ListIdentities(ctx context.Context, userID string) ([]WechatIdentity, error) {
+	var identities []WechatIdentity
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error
+	return identities, err
+}
+
+func (r *repository) CreateIdentity(ctx context.Context, identity *WechatIdentity) error {
+	return r.db.WithContext(ctx).Create(identity).Error
+}
+
+func (r *repository) UpdateIdentityUnionID(ctx context.Context, id, unionid string) error {
+	return r.db.WithContext(ctx).Model(&WechatIdentity{}).Where("id = ?", id).Update("union_id", unionid).Error
+}
+
+func (r *repository) Transaction(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.db.WithContext(ctx).Transaction(fn)
+}
+
+// userCursor 用户列表游标（created_at, id）
+type userCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+func encodeUserCursor(u *User) string {
+	raw := u.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + u.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeUserCursor(cursor string) (*userCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+	t, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &userCursor{CreatedAt: t, ID: parts[1]}, nil
+}
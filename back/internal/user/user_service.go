@@ -6,6 +6,7 @@ import (
 	"errors"
 	"math/rand"
 	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -40,13 +41,48 @@ type Service interface {
 	GetUserByOpenID(ctx context.Context, openid string) (*User, error)
 	UpdateUser(ctx context.Context, id string, req *UpdateUserRequest) (*User, error)
 	DeleteUser(ctx context.Context, id string) error
-	ListUsers(ctx context.Context, page, pageSize int) ([]User, int64, error)
+	ListUsers(ctx context.Context, q *UserQuery) ([]User, int64, string, error)
+	SetStatus(ctx context.Context, id, status string) (*User, error)
+	RevokeSessions(ctx context.Context, id string) (*User, error)
+	CheckSession(ctx context.Context, username string, issuedAt time.Time) error
+	HashPhone(phone string) string
 	UpdateLastLogin(ctx context.Context, id string) error
+	SaveSessionKey(ctx context.Context, id, sessionKey string, ttl time.Duration) error
+	GetSessionKey(ctx context.Context, id string) (string, error)
+	UpdateVerifiedPhone(ctx context.Context, id, phone string) (*User, error)
+	UpdateBinding(ctx context.Context, id, customerCode, mobile string) (*User, error)
+
+	// 微信身份与账号合并
+	ResolveWechatUser(ctx context.Context, req *WechatIdentityRequest) (*WechatResolveResult, error)
+	ListWechatIdentities(ctx context.Context, userID string) ([]WechatIdentity, error)
+	MergeUsers(ctx context.Context, sourceID, targetID string) (*User, error)
+	RegisterMergeHook(hook MergeHook)
+	RegisterSessionHook(hook SessionHook)
 }
 
+// MergeHook 账号合并时由其他模块迁移各自的数据（审计、档案等），在合并事务内执行
+type MergeHook func(tx *gorm.DB, source, target *User) error
+
+// 会话作废原因，随 SessionHook 传给其他模块
+const (
+	SessionRevokedDisabled = "disabled" // 用户被停用
+	SessionRevokedDeleted  = "deleted"  // 用户被删除
+	SessionRevokedByAdmin  = "revoked"  // 管理端强制下线
+	SessionRevokedMerged   = "merged"   // 账号被合并到其他账号
+)
+
+// SessionHook 用户的会话被作废后调用（如推送强制下线），在事务之外执行，不影响作废结果
+type SessionHook func(ctx context.Context, user *User, reason string)
+
+// ErrInvalidCursor 分页游标无法解析
+var ErrInvalidCursor = errors.New("invalid cursor")
+
 type service struct {
-	repo   Repository
-	crypto *crypto.Crypto
+	repo         Repository
+	crypto       *crypto.Crypto
+	mergeHooks   []MergeHook
+	sessionHooks []SessionHook
+	sessions     sessionCache
 }
 
 func NewService(repo Repository, cryptoService *crypto.Crypto) Service {
@@ -187,19 +223,18 @@ func (s *service) UpdateUser(ctx context.Context, id string, req *UpdateUserRequ
 		return nil, err
 	}
 
-	if req.Status != "" {
-		user.Status = req.Status
-	}
-
-	if err := s.repo.Update(ctx, user); err != nil {
-		return nil, err
+	// 状态变更统一走 SetStatus，保证停用时作废会话
+	if req.Status != "" && req.Status != user.Status {
+		return s.SetStatus(ctx, id, req.Status)
 	}
 
+	_ = s.decryptUser(user)
 	return user, nil
 }
 
+// DeleteUser 软删除用户，已签发的 token 随之失效
 func (s *service) DeleteUser(ctx context.Context, id string) error {
-	_, err := s.repo.GetByID(ctx, id)
+	user, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("user not found")
@@ -207,21 +242,42 @@ func (s *service) DeleteUser(ctx context.Context, id string) error {
 		return err
 	}
 
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.sessions.invalidate(user.Username)
+	s.sessionRevoked(ctx, user, SessionRevokedDeleted)
+	return nil
 }
 
-func (s *service) ListUsers(ctx context.Context, page, pageSize int) ([]User, int64, error) {
-	if page < 1 {
-		page = 1
+// ListUsers 按条件查询用户列表（游标分页），返回当前页、满足条件的总数和下一页游标
+func (s *service) ListUsers(ctx context.Context, q *UserQuery) ([]User, int64, string, error) {
+	if q.Limit < 1 || q.Limit > 100 {
+		q.Limit = 20
 	}
-	if pageSize < 1 || pageSize > 100 {
-		pageSize = 10
+
+	var after *userCursor
+	if q.Cursor != "" {
+		c, err := decodeUserCursor(q.Cursor)
+		if err != nil {
+			return nil, 0, "", err
+		}
+		after = c
 	}
 
-	offset := (page - 1) * pageSize
-	users, total, err := s.repo.List(ctx, offset, pageSize)
+	// 多取一条判断是否还有下一页
+	limit := q.Limit
+	q.Limit = limit + 1
+	users, total, err := s.repo.Search(ctx, q, after)
+	q.Limit = limit
 	if err != nil {
-		return nil, 0, err
+		return nil, 0, "", err
+	}
+
+	nextCursor := ""
+	if len(users) > limit {
+		users = users[:limit]
+		nextCursor = encodeUserCursor(&users[limit-1])
 	}
 
 	// 解密所有用户的手机号
@@ -229,9 +285,389 @@ func (s *service) ListUsers(ctx context.Context, page, pageSize int) ([]User, in
 		_ = s.decryptUser(&users[i]) // 忽略单个解密错误
 	}
 
-	return users, total, nil
+	return users, total, nextCursor, nil
+}
+
+// SetStatus 停用或启用用户；停用时同时作废该用户已签发的全部 token
+func (s *service) SetStatus(ctx context.Context, id, status string) (*User, error) {
+	if status != "active" && status != "disabled" {
+		return nil, errors.New("invalid status")
+	}
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if user.Status == "merged" {
+		return nil, errors.New("账号已合并，不能修改状态")
+	}
+
+	var revokeAt *time.Time
+	if status == "disabled" {
+		now := time.Now()
+		revokeAt = &now
+		user.SessionsRevokedAt = &now
+	}
+	if err := s.repo.UpdateStatus(ctx, id, status, revokeAt); err != nil {
+		return nil, err
+	}
+	user.Status = status
+	s.sessions.invalidate(user.Username)
+	if revokeAt != nil {
+		s.sessionRevoked(ctx, user, SessionRevokedDisabled)
+	}
+
+	_ = s.decryptUser(user)
+	return user, nil
+}
+
+// RevokeSessions 强制下线：作废用户此前签发的全部 token，账号状态不变
+func (s *service) RevokeSessions(ctx context.Context, id string) (*User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	if user.Status == "merged" {
+		return nil, errors.New("账号已合并，不能强制下线")
+	}
+
+	now := time.Now()
+	if err := s.repo.UpdateStatus(ctx, id, user.Status, &now); err != nil {
+		return nil, err
+	}
+	user.SessionsRevokedAt = &now
+	s.sessions.invalidate(user.Username)
+	s.sessionRevoked(ctx, user, SessionRevokedByAdmin)
+
+	_ = s.decryptUser(user)
+	return user, nil
+}
+
+// sessionRevoked 通知各模块用户会话已作废
+func (s *service) sessionRevoked(ctx context.Context, user *User, reason string) {
+	for _, hook := range s.sessionHooks {
+		hook(ctx, user, reason)
+	}
+}
+
+// HashPhone 计算手机号哈希，用于按手机号精确查询
+func (s *service) HashPhone(phone string) string {
+	return s.crypto.Hash(phone)
 }
 
 func (s *service) UpdateLastLogin(ctx context.Context, id string) error {
 	return s.repo.UpdateLastLogin(ctx, id)
 }
+
+// SaveSessionKey 加密保存微信 session_key，ttl 后视为失效
+func (s *service) SaveSessionKey(ctx context.Context, id, sessionKey string, ttl time.Duration) error {
+	if sessionKey == "" {
+		return nil
+	}
+	encrypted, err := s.crypto.Encrypt(sessionKey)
+	if err != nil {
+		return errors.New("session_key 加密失败: " + err.Error())
+	}
+	return s.repo.UpdateSessionKey(ctx, id, encrypted, time.Now().Add(ttl))
+}
+
+// GetSessionKey 获取并解密用户当前有效的 session_key
+func (s *service) GetSessionKey(ctx context.Context, id string) (string, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", errors.New("user not found")
+		}
+		return "", err
+	}
+
+	if user.SessionKeyEncrypted == "" || user.SessionKeyExpiresAt == nil {
+		return "", errors.New("session key not found")
+	}
+	if time.Now().After(*user.SessionKeyExpiresAt) {
+		return "", errors.New("session key expired")
+	}
+
+	sessionKey, err := s.crypto.Decrypt(user.SessionKeyEncrypted)
+	if err != nil {
+		return "", errors.New("session_key 解密失败: " + err.Error())
+	}
+	return sessionKey, nil
+}
+
+// UpdateVerifiedPhone 保存经微信校验的手机号（哈希 + 加密存储）
+func (s *service) UpdateVerifiedPhone(ctx context.Context, id, phone string) (*User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	phoneEncrypted, err := s.crypto.Encrypt(phone)
+	if err != nil {
+		return nil, errors.New("手机号加密失败: " + err.Error())
+	}
+	now := time.Now()
+	user.PhoneHash = s.crypto.Hash(phone)
+	user.PhoneEncrypted = phoneEncrypted
+	user.PhoneVerifiedAt = &now
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	user.Phone = phone
+	user.PhoneMasked = crypto.MaskPhone(phone)
+	return user, nil
+}
+
+// UpdateBinding 更新用户绑定的供应商编码和内部系统手机号（解绑时传空）
+func (s *service) UpdateBinding(ctx context.Context, id, customerCode, mobile string) (*User, error) {
+	user, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+
+	user.CustomerCode = customerCode
+	user.Mobile = mobile
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if err := s.decryptUser(user); err != nil {
+		return nil, errors.New("手机号解密失败: " + err.Error())
+	}
+	return user, nil
+}
+
+// ResolveWechatUser 按微信身份查找用户
+// 查找顺序：已登记的 (appid, openid) → 历史 users.open_id → 同一 unionid 的已有用户 → 新建用户
+// 未登记的 openid 会登记为该用户的微信身份
+func (s *service) ResolveWechatUser(ctx context.Context, req *WechatIdentityRequest) (*WechatResolveResult, error) {
+	identity, err := s.repo.GetIdentity(ctx, req.AppID, req.OpenID)
+	if err == nil {
+		if req.UnionID != "" && identity.UnionID == "" {
+			_ = s.repo.UpdateIdentityUnionID(ctx, identity.ID, req.UnionID)
+		}
+		user, err := s.GetUser(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return &WechatResolveResult{User: user}, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	result := &WechatResolveResult{}
+
+	// 历史用户：openid 只记录在 users 表
+	user, err := s.repo.GetByOpenID(ctx, req.OpenID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	if user != nil {
+		if user, err = s.followMerged(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// 同一开放平台主体下其他入口已登录过
+	if user == nil && req.UnionID != "" {
+		if user, err = s.findByUnionID(ctx, req.UnionID); err != nil {
+			return nil, err
+		}
+		result.Linked = user != nil
+	}
+
+	if user == nil {
+		user, err = s.CreateUser(ctx, &CreateUserRequest{
+			OpenID:       req.OpenID,
+			UnionID:      req.UnionID,
+			Mobile:       req.Mobile,
+			CustomerCode: req.CustomerCode,
+		})
+		if err != nil {
+			return nil, err
+		}
+		result.Created = true
+	}
+
+	// 登记微信身份（并发登录时可能已被登记）
+	if err := s.repo.CreateIdentity(ctx, &WechatIdentity{
+		UserID:  user.ID,
+		AppID:   req.AppID,
+		OpenID:  req.OpenID,
+		UnionID: req.UnionID,
+		Type:    req.Type,
+	}); err != nil && !isUniqueConstraintError(err) {
+		return nil, err
+	}
+
+	if user.UnionID == "" && req.UnionID != "" {
+		user.UnionID = req.UnionID
+		if err := s.repo.Update(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.decryptUser(user); err != nil {
+		return nil, errors.New("手机号解密失败: " + err.Error())
+	}
+	result.User = user
+	return result, nil
+}
+
+// findByUnionID 按 unionid 查找已有用户，未找到返回 nil
+func (s *service) findByUnionID(ctx context.Context, unionid string) (*User, error) {
+	identity, err := s.repo.GetIdentityByUnionID(ctx, unionid)
+	if err == nil {
+		user, err := s.repo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, err
+		}
+		return s.followMerged(ctx, user)
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	user, err := s.repo.GetByUnionID(ctx, unionid)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return s.followMerged(ctx, user)
+}
+
+// followMerged 已合并的用户返回合并后的目标用户
+func (s *service) followMerged(ctx context.Context, user *User) (*User, error) {
+	for i := 0; user.Status == "merged" && user.MergedInto != "" && i < 5; i++ {
+		next, err := s.repo.GetByID(ctx, user.MergedInto)
+		if err != nil {
+			return nil, err
+		}
+		user = next
+	}
+	return user, nil
+}
+
+func (s *service) ListWechatIdentities(ctx context.Context, userID string) ([]WechatIdentity, error) {
+	return s.repo.ListIdentities(ctx, userID)
+}
+
+// RegisterMergeHook 注册账号合并钩子
+func (s *service) RegisterMergeHook(hook MergeHook) {
+	s.mergeHooks = append(s.mergeHooks, hook)
+}
+
+// RegisterSessionHook 注册会话作废钩子
+func (s *service) RegisterSessionHook(hook SessionHook) {
+	s.sessionHooks = append(s.sessionHooks, hook)
+}
+
+// MergeUsers 将 source 账号合并到 target 账号
+// 微信身份、手机号、session_key 以及各模块通过 MergeHook 迁移的数据都归属到 target，
+// source 标记为 merged 并记录 MergedInto，之后用 source 的 openid 登录会落到 target
+func (s *service) MergeUsers(ctx context.Context, sourceID, targetID string) (*User, error) {
+	if sourceID == targetID {
+		return nil, errors.New("不能合并同一个账号")
+	}
+
+	var merged, mergedSource *User
+	err := s.repo.Transaction(ctx, func(tx *gorm.DB) error {
+		var source, target User
+		if err := tx.First(&source, "id = ?", sourceID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("source user not found")
+			}
+			return err
+		}
+		if err := tx.First(&target, "id = ?", targetID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("target user not found")
+			}
+			return err
+		}
+		if source.Status == "merged" || target.Status == "merged" {
+			return errors.New("账号已被合并")
+		}
+		if source.CustomerCode != "" && target.CustomerCode != "" && source.CustomerCode != target.CustomerCode {
+			return errors.New("两个账号绑定了不同的供应商，无法合并")
+		}
+
+		// 微信身份
+		if err := tx.Model(&WechatIdentity{}).Where("user_id = ?", source.ID).Update("user_id", target.ID).Error; err != nil {
+			return err
+		}
+
+		// 目标账号缺失的字段从 source 补齐
+		if target.UnionID == "" {
+			target.UnionID = source.UnionID
+		}
+		if target.Mobile == "" {
+			target.Mobile = source.Mobile
+		}
+		if target.CustomerCode == "" {
+			target.CustomerCode = source.CustomerCode
+		}
+		if target.PhoneHash == "" && source.PhoneHash != "" {
+			target.PhoneHash = source.PhoneHash
+			target.PhoneEncrypted = source.PhoneEncrypted
+			target.PhoneVerifiedAt = source.PhoneVerifiedAt
+		}
+		now := time.Now()
+		if source.SessionKeyExpiresAt != nil && source.SessionKeyExpiresAt.After(now) &&
+			(target.SessionKeyExpiresAt == nil || target.SessionKeyExpiresAt.Before(*source.SessionKeyExpiresAt)) {
+			target.SessionKeyEncrypted = source.SessionKeyEncrypted
+			target.SessionKeyExpiresAt = source.SessionKeyExpiresAt
+		}
+
+		for _, hook := range s.mergeHooks {
+			if err := hook(tx, &source, &target); err != nil {
+				return err
+			}
+		}
+
+		source.Status = "merged"
+		source.MergedInto = target.ID
+		source.SessionsRevokedAt = &now
+		source.UnionID = ""
+		source.PhoneHash = ""
+		source.PhoneEncrypted = ""
+		source.SessionKeyEncrypted = ""
+		source.SessionKeyExpiresAt = nil
+		if err := tx.Save(&source).Error; err != nil {
+			return err
+		}
+		if err := tx.Save(&target).Error; err != nil {
+			return err
+		}
+
+		merged, mergedSource = &target, &source
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	// 被合并账号此前签发的 token 全部作废
+	s.sessions.invalidate(mergedSource.Username)
+	s.sessionRevoked(ctx, mergedSource, SessionRevokedMerged)
+
+	if err := s.decryptUser(merged); err != nil {
+		return nil, errors.New("手机号解密失败: " + err.Error())
+	}
+	return merged, nil
+}
@@ -0,0 +1,46 @@
+package webhook
+
+import (
+	"back/internal/audit"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+)
+
+// auditIDNamespace 由 EventID 生成审计记录 ID 的命名空间
+var auditIDNamespace = uuid.MustParse("6f1c2a8e-3b7d-4e52-9a0f-5d8e7c4b1a39")
+
+// AuditConsumer 把 ERP 事件写入审计日志（action 为 erp.<type>），供应商维度可按 CustomerCode 查询。
+// 记录 ID 由 EventID 确定、时间取事件接收时间，同一事件重复投递时按主键去重，只留一条
+func AuditConsumer(auditService audit.Service) Consumer {
+	return func(ctx context.Context, e *Event) error {
+		detail, _ := json.Marshal(map[string]any{
+			"event_id":    e.EventID,
+			"occurred_at": e.OccurredAt,
+			"payload":     json.RawMessage(e.Payload),
+		})
+		auditService.Log(&audit.AuditLog{
+			ID:           uuid.NewSHA1(auditIDNamespace, []byte(e.EventID)).String(),
+			RequestID:    e.ID,
+			CustomerCode: e.CustomerCode,
+			Action:       "erp." + e.Type,
+			Resource:     "inquiry",
+			ResourceID:   truncate(e.InquiryID, 36),
+			Method:       "EVENT",
+			Path:         "/webhooks/erp",
+			StatusCode:   200,
+			Detail:       string(detail),
+			CreatedAt:    e.CreatedAt,
+		})
+		return nil
+	}
+}
+
+// truncate 截断到数据库字段长度
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
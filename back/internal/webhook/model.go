@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// ERP 事件类型
+const (
+	EventInquiryPublished = "inquiry.published" // 询价单发布
+	EventInquiryClosed    = "inquiry.closed"    // 询价单截止/关闭
+	EventQuoteAwarded     = "quote.awarded"     // 报价中标
+	EventQuoteRejected    = "quote.rejected"    // 报价未中标
+)
+
+// validTypes 接受的事件类型
+var validTypes = map[string]bool{
+	EventInquiryPublished: true,
+	EventInquiryClosed:    true,
+	EventQuoteAwarded:     true,
+	EventQuoteRejected:    true,
+}
+
+// 事件处理状态
+const (
+	StatusPending   = "pending"   // 已落库，待分发
+	StatusProcessed = "processed" // 所有消费者处理成功
+	StatusFailed    = "failed"    // 超过重试次数仍失败
+)
+
+// Event ERP 推送的事件，先落库再分发，EventID 唯一用于去重
+type Event struct {
+	ID            string         `json:"id" gorm:"type:uuid;primaryKey;default:gen_random_uuid()"`
+	EventID       string         `json:"eventId" gorm:"type:varchar(64);not null;uniqueIndex"` // ERP 侧事件ID
+	Type          string         `json:"type" gorm:"type:varchar(40);not null;index"`
+	InquiryID     string         `json:"inquiryId" gorm:"type:varchar(64);index"`
+	CustomerCode  string         `json:"customerCode" gorm:"type:varchar(50);index"` // quote.* 事件的供应商
+	OccurredAt    *time.Time     `json:"occurredAt"`
+	Payload       datatypes.JSON `json:"payload" gorm:"type:jsonb"`
+	Status        string         `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	LastError     string         `json:"lastError" gorm:"type:varchar(500)"`
+	Delivered     string         `json:"delivered" gorm:"type:varchar(500)"` // 已处理成功的消费者，逗号分隔，重试时跳过
+	NextAttemptAt *time.Time     `json:"nextAttemptAt" gorm:"index"`
+	ProcessedAt   *time.Time     `json:"processedAt"`
+	CreatedAt     time.Time      `json:"createdAt" gorm:"autoCreateTime"`
+	UpdatedAt     time.Time      `json:"updatedAt" gorm:"autoUpdateTime"`
+}
+
+func (Event) TableName() string {
+	return "erp_events"
+}
+
+// ========== Request ==========
+
+// EventRequest ERP 推送的事件体
+type EventRequest struct {
+	ID           string          `json:"id" binding:"required,max=64"`
+	Type         string          `json:"type" binding:"required"`
+	OccurredAt   *time.Time      `json:"occurredAt"`
+	InquiryID    string          `json:"inquiryId"`
+	CustomerCode string          `json:"customerCode"`
+	Data         json.RawMessage `json:"data"`
+}
+
+// ========== Response ==========
+
+// EventResponse 接收结果
+type EventResponse struct {
+	Code      int    `json:"code"`
+	Message   string `json:"message"`
+	Duplicate bool   `json:"duplicate,omitempty"` // 事件已接收过
+}
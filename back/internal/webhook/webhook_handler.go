@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"back/pkg/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// 请求头
+	HeaderTimestamp = "X-ERP-Timestamp"
+	HeaderSignature = "X-ERP-Signature"
+
+	maxBodyBytes = 1 << 20
+)
+
+// Handler ERP 事件接收处理器
+type Handler struct {
+	service Service
+	secret  string // 与 ERP 约定的签名密钥，为空时拒绝所有推送
+}
+
+// NewHandler 创建新的处理器实例
+func NewHandler(service Service, secret string) *Handler {
+	return &Handler{service: service, secret: secret}
+}
+
+// Receive 接收 ERP 推送的事件
+// @Summary ERP 事件推送
+// @Description 签名：X-ERP-Signature = hex(HMAC-SHA256(secret, X-ERP-Timestamp + "." + body))；按事件 id 去重，先落库再异步分发
+// @Tags webhook
+// @Accept json
+// @Produce json
+// @Param request body EventRequest true "事件"
+// @Success 200 {object} EventResponse "重复事件"
+// @Success 202 {object} EventResponse
+// @Failure 401 {object} EventResponse
+// @Router /api/v1/webhooks/erp [post]
+func (h *Handler) Receive(c *gin.Context) {
+	rc := middleware.GetRequestContext(c)
+	if rc != nil {
+		rc.Action = "erp.webhook_receive"
+		rc.Resource = "erp_event"
+	}
+
+	if h.secret == "" {
+		c.JSON(http.StatusServiceUnavailable, EventResponse{Code: http.StatusServiceUnavailable, Message: "webhook not configured"})
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodyBytes+1))
+	if err != nil || len(body) > maxBodyBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, EventResponse{Code: http.StatusRequestEntityTooLarge, Message: "request body too large"})
+		return
+	}
+
+	if err := VerifySignature(h.secret, c.GetHeader(HeaderTimestamp), c.GetHeader(HeaderSignature), body, time.Now()); err != nil {
+		log.Printf("[ERP-WEBHOOK] 签名校验失败: %v, 来源IP: %s", err, c.ClientIP())
+		if rc != nil {
+			rc.Action = "erp.webhook_rejected"
+			rc.Detail = map[string]any{"error": err.Error()}
+		}
+		c.JSON(http.StatusUnauthorized, EventResponse{Code: http.StatusUnauthorized, Message: err.Error()})
+		return
+	}
+
+	var req EventRequest
+	if err := json.Unmarshal(body, &req); err != nil || req.ID == "" || req.Type == "" || len(req.ID) > 64 {
+		c.JSON(http.StatusBadRequest, EventResponse{Code: http.StatusBadRequest, Message: "invalid event body"})
+		return
+	}
+	if rc != nil {
+		rc.ResourceID = req.ID
+		rc.CustomerCode = req.CustomerCode
+		rc.Detail = map[string]any{"type": req.Type, "inquiry_id": req.InquiryID}
+	}
+
+	_, duplicate, err := h.service.Receive(c.Request.Context(), &req)
+	if err != nil {
+		if errors.Is(err, ErrUnknownType) {
+			c.JSON(http.StatusBadRequest, EventResponse{Code: http.StatusBadRequest, Message: err.Error()})
+			return
+		}
+		log.Printf("[ERP-WEBHOOK] 保存事件 %s 失败: %v", req.ID, err)
+		c.JSON(http.StatusInternalServerError, EventResponse{Code: http.StatusInternalServerError, Message: "failed to store event"})
+		return
+	}
+
+	if duplicate {
+		c.JSON(http.StatusOK, EventResponse{Code: http.StatusOK, Message: "duplicate event ignored", Duplicate: true})
+		return
+	}
+	c.JSON(http.StatusAccepted, EventResponse{Code: http.StatusAccepted, Message: "accepted"})
+}
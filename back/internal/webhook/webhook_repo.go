@@ -0,0 +1,94 @@
+package webhook
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository ERP 事件数据访问
+type Repository interface {
+	// Insert 按 EventID 去重插入，已存在时返回 inserted=false
+	Insert(ctx context.Context, e *Event) (inserted bool, err error)
+	GetByID(ctx context.Context, id string) (*Event, error)
+	// ClaimDue 领取待分发或到了重试时间的事件，领取期间（至 leaseUntil）其他实例不会再领取
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Event, error)
+	MarkProcessed(ctx context.Context, id, delivered string, at time.Time) error
+	MarkAttemptFailed(ctx context.Context, id, status, lastError, delivered string, nextAttemptAt *time.Time) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 ERP 事件仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+func (r *repository) Insert(ctx context.Context, e *Event) (bool, error) {
+	res := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "event_id"}}, DoNothing: true}).
+		Create(e)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+func (r *repository) GetByID(ctx context.Context, id string) (*Event, error) {
+	var e Event
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&e).Error; err != nil {
+		return nil, err
+	}
+	return &e, nil
+}
+
+// ClaimDue 用 FOR UPDATE SKIP LOCKED 选出到期事件，并把 next_attempt_at 推迟到 leaseUntil 作为租约：
+// 多实例同时扫描时各领各的；领取后进程退出的，租约到期后由其他实例重新领取。按接收顺序返回
+func (r *repository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Event, error) {
+	var events []Event
+	err := r.db.WithContext(ctx).Raw(`
+		UPDATE erp_events SET next_attempt_at = ?, updated_at = ?
+		WHERE id IN (
+			SELECT id FROM erp_events
+			WHERE status = ? AND (next_attempt_at IS NULL OR next_attempt_at <= ?)
+			ORDER BY created_at ASC
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, leaseUntil, now, StatusPending, now, limit).Scan(&events).Error
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].CreatedAt.Before(events[j].CreatedAt) })
+	return events, nil
+}
+
+func (r *repository) MarkProcessed(ctx context.Context, id, delivered string, at time.Time) error {
+	return r.db.WithContext(ctx).Model(&Event{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          StatusProcessed,
+		"delivered":       delivered,
+		"processed_at":    at,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      "",
+		"next_attempt_at": nil,
+	}).Error
+}
+
+func (r *repository) MarkAttemptFailed(ctx context.Context, id, status, lastError, delivered string, nextAttemptAt *time.Time) error {
+	if len(lastError) > 500 {
+		lastError = lastError[:500]
+	}
+	return r.db.WithContext(ctx).Model(&Event{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":          status,
+		"attempts":        gorm.Expr("attempts + 1"),
+		"last_error":      strings.ToValidUTF8(lastError, ""),
+		"delivered":       delivered,
+		"next_attempt_at": nextAttemptAt,
+	}).Error
+}
@@ -0,0 +1,253 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+const (
+	// signatureWindow 签名时间戳允许的偏差
+	signatureWindow = 5 * time.Minute
+	// maxAttempts 分发最多尝试次数，超过后标记为 failed
+	maxAttempts = 5
+	// retryBase 重试退避基数（30s, 1m, 2m, 4m...）
+	retryBase = 30 * time.Second
+	// pollInterval 后台扫描待分发事件的间隔
+	pollInterval = 15 * time.Second
+	batchSize    = 10
+	// dispatchTimeout 单个事件分发给全部消费者的超时
+	dispatchTimeout = 30 * time.Second
+	// claimLease 领取一批事件的租约，覆盖整批逐个分发的最长耗时
+	claimLease = batchSize*dispatchTimeout + time.Minute
+)
+
+var (
+	ErrBadSignature = errors.New("invalid webhook signature")
+	ErrBadTimestamp = errors.New("webhook timestamp outside allowed window")
+	ErrUnknownType  = errors.New("unknown event type")
+)
+
+// VerifySignature 校验 ERP 签名：hex(HMAC-SHA256(secret, timestamp + "." + body))
+func VerifySignature(secret, timestamp, signature string, body []byte, now time.Time) error {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrBadTimestamp
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > signatureWindow || skew < -signatureWindow {
+		return ErrBadTimestamp
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+	given, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac.Sum(nil), given) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// Consumer 事件消费者
+// 事件至少投递一次：任一消费者失败时整条事件会重试，只重新投递给尚未成功的消费者（按 name 记录在事件上）；
+// 记录成功状态前进程退出时仍可能重复收到，消费者应能容忍同一 EventID 重复投递
+type Consumer func(ctx context.Context, e *Event) error
+
+// Service ERP 事件接收与分发
+type Service interface {
+	// Subscribe 注册消费者，name 须唯一（用于记录投递结果），types 为空时接收全部类型
+	Subscribe(name string, consumer Consumer, types ...string)
+	// Receive 落库（按 EventID 去重）并唤醒分发，duplicate=true 表示事件已接收过
+	Receive(ctx context.Context, req *EventRequest) (e *Event, duplicate bool, err error)
+	Start()
+	Stop()
+}
+
+type subscription struct {
+	name     string
+	consumer Consumer
+	types    map[string]bool
+}
+
+type service struct {
+	repo Repository
+
+	mu   sync.RWMutex
+	subs []subscription
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewService 创建 ERP 事件服务
+func NewService(repo Repository) Service {
+	return &service{
+		repo: repo,
+		wake: make(chan struct{}, 1),
+		stop: make(chan struct{}),
+	}
+}
+
+func (s *service) Subscribe(name string, consumer Consumer, types ...string) {
+	sub := subscription{name: name, consumer: consumer}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+	s.mu.Lock()
+	s.subs = append(s.subs, sub)
+	s.mu.Unlock()
+}
+
+func (s *service) Receive(ctx context.Context, req *EventRequest) (*Event, bool, error) {
+	if !validTypes[req.Type] {
+		return nil, false, fmt.Errorf("%w: %s", ErrUnknownType, req.Type)
+	}
+
+	payload := datatypes.JSON(req.Data)
+	if len(payload) == 0 {
+		payload = datatypes.JSON("null")
+	}
+	e := &Event{
+		EventID:      req.ID,
+		Type:         req.Type,
+		InquiryID:    req.InquiryID,
+		CustomerCode: req.CustomerCode,
+		OccurredAt:   req.OccurredAt,
+		Payload:      payload,
+		Status:       StatusPending,
+	}
+	inserted, err := s.repo.Insert(ctx, e)
+	if err != nil {
+		return nil, false, err
+	}
+	if !inserted {
+		return nil, true, nil
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return e, false, nil
+}
+
+// Start 启动后台分发，启动时会接着处理上次未分发完的事件
+func (s *service) Start() {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(pollInterval)
+		defer ticker.Stop()
+		for {
+			s.dispatchDue()
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+			case <-s.wake:
+			}
+		}
+	}()
+}
+
+// Stop 停止后台分发，等待当前批次处理完
+func (s *service) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// dispatchDue 领取并分发到期的事件，直到没有待处理事件或收到停止信号。
+// 停止时未分发的事件保留租约，到期后由其他实例（或重启后）重新领取
+func (s *service) dispatchDue() {
+	for {
+		now := time.Now()
+		events, err := s.repo.ClaimDue(context.Background(), now, now.Add(claimLease), batchSize)
+		if err != nil {
+			log.Printf("[ERP-WEBHOOK] 查询待分发事件失败: %v", err)
+			return
+		}
+		for i := range events {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+			s.dispatch(&events[i])
+		}
+		if len(events) < batchSize {
+			return
+		}
+	}
+}
+
+// dispatch 把事件交给所有匹配的消费者，并记录结果
+func (s *service) dispatch(e *Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), dispatchTimeout)
+	defer cancel()
+
+	s.mu.RLock()
+	subs := append([]subscription(nil), s.subs...)
+	s.mu.RUnlock()
+
+	// 上次尝试已成功的消费者不再投递
+	var delivered []string
+	done := make(map[string]bool)
+	for _, name := range strings.Split(e.Delivered, ",") {
+		if name != "" && !done[name] {
+			done[name] = true
+			delivered = append(delivered, name)
+		}
+	}
+
+	var failed []string
+	for _, sub := range subs {
+		if sub.types != nil && !sub.types[e.Type] {
+			continue
+		}
+		if done[sub.name] {
+			continue
+		}
+		if err := sub.consumer(ctx, e); err != nil {
+			log.Printf("[ERP-WEBHOOK] 消费者 %s 处理事件 %s 失败: %v", sub.name, e.EventID, err)
+			failed = append(failed, fmt.Sprintf("%s: %v", sub.name, err))
+			continue
+		}
+		done[sub.name] = true
+		delivered = append(delivered, sub.name)
+	}
+
+	now := time.Now()
+	if len(failed) == 0 {
+		if err := s.repo.MarkProcessed(ctx, e.ID, strings.Join(delivered, ","), now); err != nil {
+			log.Printf("[ERP-WEBHOOK] 标记事件 %s 已处理失败: %v", e.EventID, err)
+		}
+		return
+	}
+
+	status := StatusPending
+	var next *time.Time
+	if e.Attempts+1 >= maxAttempts {
+		status = StatusFailed
+		log.Printf("[ALERT] ERP 事件 %s(%s) 重试 %d 次仍失败，已放弃", e.EventID, e.Type, maxAttempts)
+	} else {
+		t := now.Add(retryBase << e.Attempts)
+		next = &t
+	}
+	if err := s.repo.MarkAttemptFailed(ctx, e.ID, status, fmt.Sprint(failed), strings.Join(delivered, ","), next); err != nil {
+		log.Printf("[ERP-WEBHOOK] 记录事件 %s 失败状态出错: %v", e.EventID, err)
+	}
+}
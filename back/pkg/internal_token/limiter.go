@@ -0,0 +1,78 @@
+package internal_token
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// limiter 令牌桶，限制后台任务调用内部系统的频率，避免批量轮询挤占交互请求
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64 // 每秒补充的令牌数
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// SetRateLimit 设置后台调用内部系统的限流：每秒 perSecond 次，允许突发 burst 次；perSecond<=0 时不限流
+func (m *Manager) SetRateLimit(perSecond float64, burst int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if perSecond <= 0 {
+		m.limiter = nil
+		return
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	m.limiter = &limiter{
+		rate:   perSecond,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait 等待一个调用配额，未设置限流时立即返回；ctx 取消时返回 ctx.Err()
+// 只有后台任务（轮询等）需要调用，前端触发的代理请求不受限
+func (m *Manager) Wait(ctx context.Context) error {
+	m.mu.RLock()
+	l := m.limiter
+	m.mu.RUnlock()
+	if l == nil {
+		return nil
+	}
+
+	for {
+		delay := l.reserve(time.Now())
+		if delay == 0 {
+			return nil
+		}
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// reserve 有令牌时取走一个并返回 0，否则返回还需等待的时间
+func (l *limiter) reserve(now time.Time) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+	return time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+}
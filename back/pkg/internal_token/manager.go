@@ -26,6 +26,7 @@ type Manager struct {
 	tokenLifetime time.Duration // 内部 token 有效期（用于计算刷新周期）
 
 	httpClient *http.Client
+	limiter    *limiter // 后台调用限流，nil 表示不限流
 }
 
 // NewManager 创建内部 Token 管理器
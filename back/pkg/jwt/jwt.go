@@ -12,12 +12,28 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// AudienceAdmin 员工（管理后台）token 的 audience，供应商接口不接受此类 token
+const AudienceAdmin = "admin"
+
 // Claims JWT Claims
 type Claims struct {
-	Username string `json:"username"`
+	Username       string `json:"username"`
+	CustomerCode   string `json:"customerCode,omitempty"`   // 签发时绑定的供应商编码
+	Role           string `json:"role,omitempty"`           // 员工角色（仅 admin audience）
+	ImpersonatedBy string `json:"impersonatedBy,omitempty"` // 代查看 token 的签发员工（Username 为被查看的用户）
 	jwt.RegisteredClaims
 }
 
+// IsAdmin 是否为员工 token
+func (c *Claims) IsAdmin() bool {
+	for _, aud := range c.Audience {
+		if aud == AudienceAdmin {
+			return true
+		}
+	}
+	return false
+}
+
 // JWTService JWT服务
 type JWTService struct {
 	secretKey []byte
@@ -36,22 +52,89 @@ func NewJWTService(secretKey, issuer string, expiry time.Duration) *JWTService {
 
 // GenerateToken 生成JWT token
 func (s *JWTService) GenerateToken(username string) (string, error) {
+	return s.GenerateTokenWithClaims(&Claims{Username: username})
+}
+
+// GenerateTokenWithClaims 按给定 claims 生成JWT token
+// 未设置的 Issuer/IssuedAt/ExpiresAt 使用服务默认值
+func (s *JWTService) GenerateTokenWithClaims(claims *Claims) (string, error) {
 	now := time.Now()
-	claims := Claims{
-		Username: username,
-		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    s.issuer,
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(s.expiry)),
-		},
+	if claims.Issuer == "" {
+		claims.Issuer = s.issuer
+	}
+	if claims.IssuedAt == nil {
+		claims.IssuedAt = jwt.NewNumericDate(now)
+	}
+	if claims.ExpiresAt == nil {
+		claims.ExpiresAt = jwt.NewNumericDate(now.Add(s.expiry))
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.secretKey)
 }
 
-// ValidateToken 验证JWT token
+// IsImpersonation 是否为员工代查看用户的 token
+func (c *Claims) IsImpersonation() bool {
+	return c.ImpersonatedBy != ""
+}
+
+// GenerateImpersonationToken 生成员工以用户身份只读查看的短期 token
+// 与普通用户 token 同 audience，由 ImpersonatedBy 区分，不能刷新
+func (s *JWTService) GenerateImpersonationToken(username, customerCode, staffUsername string, expiry time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(expiry)
+	token, err := s.GenerateTokenWithClaims(&Claims{
+		Username:       username,
+		CustomerCode:   customerCode,
+		ImpersonatedBy: staffUsername,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	return token, expiresAt, err
+}
+
+// GenerateAdminToken 生成员工 token（audience=admin）
+func (s *JWTService) GenerateAdminToken(username, role string, expiry time.Duration) (string, time.Time, error) {
+	expiresAt := time.Now().Add(expiry)
+	token, err := s.GenerateTokenWithClaims(&Claims{
+		Username: username,
+		Role:     role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{AudienceAdmin},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	})
+	return token, expiresAt, err
+}
+
+// ValidateToken 验证供应商（外部）JWT token，员工 token 视为无效
 func (s *JWTService) ValidateToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if claims.IsAdmin() {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ValidateAdminToken 验证员工 token，要求 audience=admin
+func (s *JWTService) ValidateAdminToken(tokenString string) (*Claims, error) {
+	claims, err := s.parse(tokenString)
+	if err != nil {
+		return nil, err
+	}
+	if !claims.IsAdmin() {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// parse 校验签名和有效期并解析 claims
+func (s *JWTService) parse(tokenString string) (*Claims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		// 验证签名方法
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -88,7 +171,8 @@ func (s *JWTService) ParseTokenIgnoreExpiry(tokenString string) (*Claims, error)
 		return nil, ErrInvalidToken
 	}
 
-	if claims, ok := token.Claims.(*Claims); ok {
+	// 员工 token 和代查看 token 不允许走供应商刷新流程
+	if claims, ok := token.Claims.(*Claims); ok && !claims.IsAdmin() && !claims.IsImpersonation() {
 		return claims, nil
 	}
 
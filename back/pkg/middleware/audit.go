@@ -39,18 +39,22 @@ func Audit(auditService audit.Service) gin.HandlerFunc {
 		}
 
 		entry := &audit.AuditLog{
-			RequestID:  rc.RequestID,
-			Username:   rc.Username,
-			Action:     action,
-			Resource:   rc.Resource,
-			ResourceID: rc.ResourceID,
-			Method:     c.Request.Method,
-			Path:       c.Request.URL.Path,
-			StatusCode: c.Writer.Status(),
-			ClientIP:   rc.ClientIP,
-			UserAgent:  rc.UserAgent,
-			Duration:   time.Since(rc.StartTime).Milliseconds(),
-			Detail:     detailJSON,
+			RequestID:     rc.RequestID,
+			Username:      rc.Username,
+			CustomerCode:  rc.CustomerCode,
+			StaffUsername: rc.StaffUsername,
+			Impersonation: rc.Impersonation,
+			APIKeyID:      rc.APIKeyID,
+			Action:        action,
+			Resource:      rc.Resource,
+			ResourceID:    rc.ResourceID,
+			Method:        c.Request.Method,
+			Path:          c.Request.URL.Path,
+			StatusCode:    c.Writer.Status(),
+			ClientIP:      rc.ClientIP,
+			UserAgent:     rc.UserAgent,
+			Duration:      time.Since(rc.StartTime).Milliseconds(),
+			Detail:        detailJSON,
 		}
 
 		auditService.Log(entry)
@@ -2,15 +2,24 @@ package middleware
 
 import (
 	"back/pkg/jwt"
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
-// JWTAuth JWT认证中间件
-func JWTAuth(jwtService *jwt.JWTService) gin.HandlerFunc {
+// ErrSessionRevoked 用户已停用/删除，或 token 签发于会话作废之前
+var ErrSessionRevoked = errors.New("session revoked")
+
+// SessionChecker 校验 token 对应的会话是否仍然有效
+type SessionChecker func(ctx context.Context, username string, issuedAt time.Time) error
+
+// JWTAuth JWT认证中间件，checkSession 不为空时额外校验会话是否已被作废
+func JWTAuth(jwtService *jwt.JWTService, checkSession SessionChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		path := c.Request.URL.Path
 		log.Printf("[JWT-AUTH] 收到请求: %s %s", c.Request.Method, path)
@@ -60,14 +69,135 @@ func JWTAuth(jwtService *jwt.JWTService) gin.HandlerFunc {
 			return
 		}
 
+		if checkSession != nil {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			if err := checkSession(c.Request.Context(), claims.Username, issuedAt); err != nil {
+				log.Printf("[JWT-AUTH] 会话校验失败: %v, 用户: %s, 路径: %s", err, claims.Username, path)
+				status, message := http.StatusServiceUnavailable, "session check failed"
+				if errors.Is(err, ErrSessionRevoked) {
+					status, message = http.StatusUnauthorized, "session revoked"
+				}
+				c.JSON(status, gin.H{
+					"code":    status,
+					"message": message,
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		log.Printf("[JWT-AUTH] Token验证成功, 用户: %s, 路径: %s", claims.Username, path)
 
 		// 将用户信息填入 RequestContext
 		rc := GetRequestContext(c)
 		if rc != nil {
 			rc.Username = claims.Username
+			rc.CustomerCode = claims.CustomerCode
+			if claims.IsImpersonation() {
+				rc.StaffUsername = claims.ImpersonatedBy
+				rc.Impersonation = true
+			}
 		}
 
 		c.Next()
 	}
 }
+
+// StaffAuth 员工认证中间件，只接受 admin audience 的 token；
+// checkSession 非空时校验员工账号仍可用且 token 未因改密/停用而作废
+func StaffAuth(jwtService *jwt.JWTService, checkSession SessionChecker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authHeader := c.GetHeader("Authorization")
+		parts := strings.SplitN(authHeader, " ", 2)
+		if len(parts) != 2 || parts[0] != "Bearer" {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "missing or invalid authorization header",
+			})
+			c.Abort()
+			return
+		}
+
+		claims, err := jwtService.ValidateAdminToken(parts[1])
+		if err != nil {
+			log.Printf("[STAFF-AUTH] Token验证失败: %v, 路径: %s", err, c.Request.URL.Path)
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    http.StatusUnauthorized,
+				"message": "invalid or expired token",
+			})
+			c.Abort()
+			return
+		}
+
+		if checkSession != nil {
+			var issuedAt time.Time
+			if claims.IssuedAt != nil {
+				issuedAt = claims.IssuedAt.Time
+			}
+			if err := checkSession(c.Request.Context(), claims.Username, issuedAt); err != nil {
+				log.Printf("[STAFF-AUTH] 会话校验失败: %v, 员工: %s, 路径: %s", err, claims.Username, c.Request.URL.Path)
+				status, message := http.StatusServiceUnavailable, "session check failed"
+				if errors.Is(err, ErrSessionRevoked) {
+					status, message = http.StatusUnauthorized, "session revoked"
+				}
+				c.JSON(status, gin.H{
+					"code":    status,
+					"message": message,
+				})
+				c.Abort()
+				return
+			}
+		}
+
+		if rc := GetRequestContext(c); rc != nil {
+			rc.StaffUsername = claims.Username
+			rc.StaffRole = claims.Role
+		}
+
+		c.Next()
+	}
+}
+
+// ImpersonationReadOnly 代查看 token 只读：除 allowedPaths（按路由模板匹配的只读 POST 接口）外，
+// 只放行 GET/HEAD 请求。需在 JWTAuth 之后使用
+func ImpersonationReadOnly(allowedPaths ...string) gin.HandlerFunc {
+	allowed := make(map[string]bool, len(allowedPaths))
+	for _, p := range allowedPaths {
+		allowed[p] = true
+	}
+	return func(c *gin.Context) {
+		rc := GetRequestContext(c)
+		if rc == nil || !rc.Impersonation {
+			c.Next()
+			return
+		}
+		method := c.Request.Method
+		if method == http.MethodGet || method == http.MethodHead || allowed[c.FullPath()] {
+			c.Next()
+			return
+		}
+
+		log.Printf("[JWT-AUTH] 代查看模式拒绝写操作: staff=%s user=%s %s %s", rc.StaffUsername, rc.Username, method, c.Request.URL.Path)
+		rc.Action = "impersonation.write_blocked"
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    http.StatusForbidden,
+			"message": "read-only impersonation session",
+		})
+		c.Abort()
+	}
+}
+
+// TokenFromQuery 未携带 Authorization 请求头时从查询参数读取 token（EventSource 无法设置请求头），需在 JWTAuth 之前使用
+func TokenFromQuery(param string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query(param); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
+}
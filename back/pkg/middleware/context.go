@@ -10,15 +10,20 @@ const requestContextKey = "request_context"
 
 // RequestContext holds unified request metadata populated by middleware and handlers.
 type RequestContext struct {
-	RequestID  string
-	ClientIP   string
-	UserAgent  string
-	StartTime  time.Time
-	Username   string         // filled by Auth middleware
-	Action     string         // set by handler (optional)
-	Resource   string         // set by handler (optional)
-	ResourceID string         // set by handler (optional)
-	Detail     map[string]any // set by handler (optional)
+	RequestID     string
+	ClientIP      string
+	UserAgent     string
+	StartTime     time.Time
+	Username      string         // filled by Auth middleware
+	CustomerCode  string         // filled by Auth middleware; handlers acting for a supplier override it
+	StaffUsername string         // filled by StaffAuth middleware
+	StaffRole     string         // filled by StaffAuth middleware
+	Impersonation bool           // filled by Auth middleware: staff viewing as Username, StaffUsername is the staff
+	APIKeyID      string         // filled by API key middleware for signed machine requests
+	Action        string         // set by handler (optional)
+	Resource      string         // set by handler (optional)
+	ResourceID    string         // set by handler (optional)
+	Detail        map[string]any // set by handler (optional)
 }
 
 // SetRequestContext stores the RequestContext in the gin context.
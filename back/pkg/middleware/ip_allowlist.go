@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseCIDRs 解析逗号分隔的 CIDR 列表，单个 IP 视为 /32（IPv6 为 /128）
+func ParseCIDRs(list string) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, item := range strings.Split(list, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if !strings.Contains(item, "/") {
+			ip := net.ParseIP(item)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid IP %q", item)
+			}
+			if ip.To4() != nil {
+				item += "/32"
+			} else {
+				item += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", item, err)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// IPAllowlist 只允许来源 IP 落在 nets 内的请求通过，nets 为空时不做限制。
+// 来源 IP 取 c.ClientIP()，需配合 router.SetTrustedProxies 防止伪造 X-Forwarded-For。
+func IPAllowlist(nets []*net.IPNet) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if len(nets) == 0 {
+			c.Next()
+			return
+		}
+
+		ip := net.ParseIP(c.ClientIP())
+		if ip != nil {
+			for _, n := range nets {
+				if n.Contains(ip) {
+					c.Next()
+					return
+				}
+			}
+		}
+
+		log.Printf("[IP-ALLOWLIST] 拒绝来源 %s, 路径: %s", c.ClientIP(), c.Request.URL.Path)
+		if rc := GetRequestContext(c); rc != nil {
+			rc.Action = "admin.ip_denied"
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"code":    http.StatusForbidden,
+			"message": "access denied from this address",
+		})
+		c.Abort()
+	}
+}
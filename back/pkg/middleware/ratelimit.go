@@ -0,0 +1,81 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateWindow 单个来源在当前固定窗口内的计数
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// rateLimiter 按来源 IP 的固定窗口计数器（进程内，多实例部署时各自计数）
+type rateLimiter struct {
+	mu        sync.Mutex
+	limit     int
+	window    time.Duration
+	windows   map[string]*rateWindow
+	lastSweep time.Time
+}
+
+// allow 记录一次请求，返回是否放行以及被拒绝时需要等待的时长
+func (l *rateLimiter) allow(key string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// 每个窗口周期顺带清理一次过期计数，避免 map 无限增长
+	if now.Sub(l.lastSweep) > l.window {
+		for k, w := range l.windows {
+			if now.Sub(w.start) >= l.window {
+				delete(l.windows, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	w, ok := l.windows[key]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.windows[key] = &rateWindow{start: now, count: 1}
+		return true, 0
+	}
+	if w.count >= l.limit {
+		return false, w.start.Add(l.window).Sub(now)
+	}
+	w.count++
+	return true, 0
+}
+
+// RateLimit 限制每个来源 IP 在 window 内最多 limit 次请求，limit <= 0 时不限制
+func RateLimit(limit int, window time.Duration) gin.HandlerFunc {
+	if limit <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+	l := &rateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+	}
+	return func(c *gin.Context) {
+		ok, retryAfter := l.allow(c.ClientIP(), time.Now())
+		if ok {
+			c.Next()
+			return
+		}
+
+		if rc := GetRequestContext(c); rc != nil && rc.Action == "" {
+			rc.Action = "rate_limited"
+		}
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())+1))
+		c.JSON(http.StatusTooManyRequests, gin.H{
+			"code":    http.StatusTooManyRequests,
+			"message": "too many requests",
+		})
+		c.Abort()
+	}
+}
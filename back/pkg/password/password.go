@@ -0,0 +1,95 @@
+// Package password 密码哈希
+// 新密码使用 argon2id，兼容校验 bcrypt 哈希（便于从其他系统导入账号）
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// argon2id 参数（OWASP 推荐值）
+const (
+	argonMemory  = 64 * 1024 // KiB
+	argonTime    = 3
+	argonThreads = 2
+	argonKeyLen  = 32
+	argonSaltLen = 16
+)
+
+var (
+	ErrMismatch      = errors.New("password: 密码不匹配")
+	ErrUnknownFormat = errors.New("password: 无法识别的哈希格式")
+)
+
+// Hash 使用 argon2id 计算密码哈希，返回 PHC 格式字符串
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+func Hash(plain string) (string, error) {
+	salt := make([]byte, argonSaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(plain), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, argonMemory, argonTime, argonThreads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify 校验密码，支持 argon2id 和 bcrypt 哈希
+func Verify(hash, plain string) error {
+	switch {
+	case strings.HasPrefix(hash, "$argon2id$"):
+		return verifyArgon2id(hash, plain)
+	case strings.HasPrefix(hash, "$2a$"), strings.HasPrefix(hash, "$2b$"), strings.HasPrefix(hash, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(plain)); err != nil {
+			return ErrMismatch
+		}
+		return nil
+	default:
+		return ErrUnknownFormat
+	}
+}
+
+// NeedsRehash 哈希不是当前参数的 argon2id 时返回 true，登录成功后应重新计算
+func NeedsRehash(hash string) bool {
+	prefix := fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$", argon2.Version, argonMemory, argonTime, argonThreads)
+	return !strings.HasPrefix(hash, prefix)
+}
+
+func verifyArgon2id(hash, plain string) error {
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return ErrUnknownFormat
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return ErrUnknownFormat
+	}
+	var memory, iterations uint32
+	var threads uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &threads); err != nil {
+		return ErrUnknownFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return ErrUnknownFormat
+	}
+	expected, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return ErrUnknownFormat
+	}
+
+	key := argon2.IDKey([]byte(plain), salt, iterations, memory, threads, uint32(len(expected)))
+	if subtle.ConstantTimeCompare(key, expected) != 1 {
+		return ErrMismatch
+	}
+	return nil
+}
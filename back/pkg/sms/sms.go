@@ -0,0 +1,91 @@
+// Package sms 短信发送
+// ConsoleSender 只打印到控制台，用于本地开发；生产环境通过 HTTPSender 调用短信网关
+package sms
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Sender 短信发送接口
+type Sender interface {
+	Send(ctx context.Context, phone, content string) error
+}
+
+// ConsoleSender 将短信打印到控制台（模拟短信发送）
+type ConsoleSender struct{}
+
+// NewConsoleSender 创建控制台短信发送器
+func NewConsoleSender() *ConsoleSender {
+	return &ConsoleSender{}
+}
+
+// Send 打印短信内容
+func (s *ConsoleSender) Send(ctx context.Context, phone, content string) error {
+	fmt.Println("========================================")
+	fmt.Printf("短信接收号码: %s\n", phone)
+	fmt.Printf("短信内容: %s\n", content)
+	fmt.Println("========================================")
+	return nil
+}
+
+// HTTPSender 通过短信网关发送：POST {"phone": ..., "content": ...}，
+// 配置了 token 时带 Authorization: Bearer 头，2xx 视为发送成功
+type HTTPSender struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// NewHTTPSender 创建短信网关发送器
+func NewHTTPSender(url, token string, timeout time.Duration) (*HTTPSender, error) {
+	if url == "" {
+		return nil, errors.New("短信网关地址不能为空")
+	}
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	return &HTTPSender{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// Send 调用短信网关发送短信
+func (s *HTTPSender) Send(ctx context.Context, phone, content string) error {
+	body, err := json.Marshal(map[string]string{
+		"phone":   phone,
+		"content": content,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if s.token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("短信网关返回 %d: %s", resp.StatusCode, bytes.TrimSpace(snippet))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
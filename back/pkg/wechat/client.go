@@ -0,0 +1,184 @@
+package wechat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultBaseURL 微信开放接口地址
+const DefaultBaseURL = "https://api.weixin.qq.com"
+
+// accessTokenMargin access_token 提前刷新的时间
+const accessTokenMargin = 5 * time.Minute
+
+// Config 微信客户端配置
+type Config struct {
+	BaseURL     string        // 接口基础地址，默认 DefaultBaseURL，可指向 wechattest 假服务
+	OpenBaseURL string        // 网页授权地址，默认 DefaultOpenBaseURL（仅公众号使用）
+	AppID       string        // 小程序/公众号 appid
+	Secret      string        // 小程序/公众号 secret
+	Timeout     time.Duration // 单次请求超时，默认 5 秒
+}
+
+// Session jscode2session 结果
+type Session struct {
+	OpenID     string `json:"openid"`
+	SessionKey string `json:"session_key"`
+	UnionID    string `json:"unionid"`
+}
+
+// Client 微信服务端接口客户端
+// access_token 在内存中缓存，到期前 accessTokenMargin 自动刷新
+type Client struct {
+	baseURL     string
+	openBaseURL string
+	appID       string
+	secret      string
+	httpClient  *http.Client
+
+	mu             sync.Mutex
+	accessToken    string
+	tokenExpiresAt time.Time
+}
+
+// NewClient 创建微信客户端
+func NewClient(cfg Config) *Client {
+	baseURL := cfg.BaseURL
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	openBaseURL := cfg.OpenBaseURL
+	if openBaseURL == "" {
+		openBaseURL = DefaultOpenBaseURL
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+	return &Client{
+		baseURL:     strings.TrimRight(baseURL, "/"),
+		openBaseURL: strings.TrimRight(openBaseURL, "/"),
+		appID:       cfg.AppID,
+		secret:      cfg.Secret,
+		httpClient:  &http.Client{Timeout: timeout},
+	}
+}
+
+// AppID 返回客户端对应的 appid
+func (c *Client) AppID() string {
+	return c.appID
+}
+
+// Configured appid/secret 是否已配置
+func (c *Client) Configured() bool {
+	return c.appID != "" && c.secret != ""
+}
+
+// Code2Session 用小程序 wx.login 的 code 换取 openid 和 session_key
+func (c *Client) Code2Session(ctx context.Context, code string) (*Session, error) {
+	query := url.Values{}
+	query.Set("appid", c.appID)
+	query.Set("secret", c.secret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	var result struct {
+		Session
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := c.getJSON(ctx, "/sns/jscode2session", query, &result); err != nil {
+		return nil, err
+	}
+	if err := newError(result.ErrCode, result.ErrMsg); err != nil {
+		return nil, err
+	}
+	return &result.Session, nil
+}
+
+// AccessToken 返回缓存的接口调用凭证，过期前自动刷新
+func (c *Client) AccessToken(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.accessToken != "" && time.Now().Before(c.tokenExpiresAt) {
+		return c.accessToken, nil
+	}
+
+	query := url.Values{}
+	query.Set("grant_type", "client_credential")
+	query.Set("appid", c.appID)
+	query.Set("secret", c.secret)
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+		ErrCode     int    `json:"errcode"`
+		ErrMsg      string `json:"errmsg"`
+	}
+	if err := c.getJSON(ctx, "/cgi-bin/token", query, &result); err != nil {
+		return "", err
+	}
+	if err := newError(result.ErrCode, result.ErrMsg); err != nil {
+		return "", err
+	}
+
+	lifetime := time.Duration(result.ExpiresIn) * time.Second
+	if lifetime > 2*accessTokenMargin {
+		lifetime -= accessTokenMargin
+	}
+	c.accessToken = result.AccessToken
+	c.tokenExpiresAt = time.Now().Add(lifetime)
+	log.Printf("[WECHAT] access_token 已刷新，有效期 %v", lifetime)
+	return c.accessToken, nil
+}
+
+// InvalidateAccessToken 丢弃缓存的 access_token，下次调用时重新获取
+func (c *Client) InvalidateAccessToken() {
+	c.mu.Lock()
+	c.accessToken = ""
+	c.tokenExpiresAt = time.Time{}
+	c.mu.Unlock()
+}
+
+// getJSON 发起 GET 请求并解析 JSON 响应
+func (c *Client) getJSON(ctx context.Context, path string, query url.Values, out interface{}) error {
+	reqURL := c.baseURL + path
+	if len(query) > 0 {
+		reqURL += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return fmt.Errorf("wechat: 创建请求失败: %w", err)
+	}
+	return c.do(req, out)
+}
+
+// do 执行请求并解析 JSON
+func (c *Client) do(req *http.Request, out interface{}) error {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("wechat: 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("wechat: 读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("wechat: HTTP %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("wechat: 解析响应失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,129 @@
+package wechat_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"back/pkg/wechat"
+	"back/pkg/wechat/wechattest"
+)
+
+func newFakeClient(t *testing.T, secret string) (*wechattest.Server, *wechat.Client) {
+	t.Helper()
+	fake := wechattest.NewServer("wx-test", "secret")
+	t.Cleanup(fake.Close)
+	return fake, wechat.NewClient(wechat.Config{BaseURL: fake.URL, AppID: "wx-test", Secret: secret})
+}
+
+func TestCode2Session(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		preset  *wechattest.Session
+		reuse   bool
+		wantErr error
+	}{
+		{name: "登记的 code", secret: "secret", preset: &wechattest.Session{OpenID: "o-preset", UnionID: "u-preset", SessionKey: "a2V5a2V5a2V5a2V5a2V5aw=="}},
+		{name: "未登记的 code 按 code 派生", secret: "secret"},
+		{name: "code 重复使用", secret: "secret", reuse: true, wantErr: wechat.ErrCodeUsed},
+		{name: "secret 错误", secret: "wrong", wantErr: wechat.ErrInvalidSecret},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, client := newFakeClient(t, tt.secret)
+			const code = "js-code"
+			if tt.preset != nil {
+				fake.AddCode(code, *tt.preset)
+			}
+			if tt.reuse {
+				if _, err := client.Code2Session(context.Background(), code); err != nil {
+					t.Fatalf("第一次换取失败: %v", err)
+				}
+			}
+
+			session, err := client.Code2Session(context.Background(), code)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Code2Session: %v", err)
+			}
+			want := fake.SessionFor(code)
+			if session.OpenID != want.OpenID || session.UnionID != want.UnionID || session.SessionKey != want.SessionKey {
+				t.Fatalf("session = %+v, want %+v", *session, want)
+			}
+		})
+	}
+}
+
+func TestAccessTokenCacheAndRefresh(t *testing.T) {
+	fake, client := newFakeClient(t, "secret")
+	ctx := context.Background()
+
+	first, err := client.AccessToken(ctx)
+	if err != nil {
+		t.Fatalf("AccessToken: %v", err)
+	}
+	if second, _ := client.AccessToken(ctx); second != first {
+		t.Fatalf("缓存期内 access_token 变化: %s -> %s", first, second)
+	}
+
+	// 服务端作废 token 后，发送订阅消息会刷新 token 重试一次
+	fake.ExpireAccessTokens()
+	err = client.SendSubscribeMessage(ctx, wechat.SubscribeMessageRequest{
+		ToUser:     "o-user",
+		TemplateID: "tmpl",
+		Data:       map[string]wechat.SubscribeDataItem{"thing1": {Value: "hello"}},
+	})
+	if err != nil {
+		t.Fatalf("SendSubscribeMessage: %v", err)
+	}
+	if refreshed, _ := client.AccessToken(ctx); refreshed == first || !fake.ValidAccessToken(refreshed) {
+		t.Fatalf("access_token 未刷新: %s", refreshed)
+	}
+	if msgs := fake.Messages(); len(msgs) != 1 || msgs[0].Data["thing1"] != "hello" {
+		t.Fatalf("messages = %+v", msgs)
+	}
+}
+
+func TestSendSubscribeMessageRefused(t *testing.T) {
+	_, client := newFakeClient(t, "secret")
+	err := client.SendSubscribeMessage(context.Background(), wechat.SubscribeMessageRequest{ToUser: "refused-user", TemplateID: "tmpl"})
+	if !errors.Is(err, wechat.ErrSubscribeRefused) {
+		t.Fatalf("err = %v, want ErrSubscribeRefused", err)
+	}
+}
+
+func TestDecryptPhoneData(t *testing.T) {
+	fake, client := newFakeClient(t, "secret")
+	session, err := client.Code2Session(context.Background(), "phone-code")
+	if err != nil {
+		t.Fatalf("Code2Session: %v", err)
+	}
+	encrypted, iv, err := wechattest.EncryptData(session.SessionKey, fake.PhoneData("13800000000"))
+	if err != nil {
+		t.Fatalf("EncryptData: %v", err)
+	}
+
+	plaintext, err := wechat.DecryptData(session.SessionKey, encrypted, iv)
+	if err != nil {
+		t.Fatalf("DecryptData: %v", err)
+	}
+	var phone struct {
+		PhoneNumber string `json:"phoneNumber"`
+		Watermark   struct {
+			AppID string `json:"appid"`
+		} `json:"watermark"`
+	}
+	if err := json.Unmarshal(plaintext, &phone); err != nil {
+		t.Fatalf("解析明文: %v", err)
+	}
+	if phone.PhoneNumber != "13800000000" || phone.Watermark.AppID != "wx-test" {
+		t.Fatalf("phone = %+v", phone)
+	}
+}
@@ -0,0 +1,62 @@
+package wechat
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+)
+
+// DecryptData 解密小程序 encryptedData（AES-128-CBC + PKCS#7）
+// sessionKey、encryptedData、iv 均为微信下发的 base64 字符串
+func DecryptData(sessionKey, encryptedData, iv string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil {
+		return nil, errors.New("session_key 格式错误")
+	}
+	ivBytes, err := base64.StdEncoding.DecodeString(iv)
+	if err != nil {
+		return nil, errors.New("iv 格式错误")
+	}
+	data, err := base64.StdEncoding.DecodeString(encryptedData)
+	if err != nil {
+		return nil, errors.New("encryptedData 格式错误")
+	}
+
+	if len(key) != 16 {
+		return nil, errors.New("session_key 长度错误")
+	}
+	if len(ivBytes) != aes.BlockSize {
+		return nil, errors.New("iv 长度错误")
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, errors.New("encryptedData 长度错误")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(data))
+	cipher.NewCBCDecrypter(block, ivBytes).CryptBlocks(plaintext, data)
+
+	return pkcs7Unpad(plaintext, aes.BlockSize)
+}
+
+// pkcs7Unpad 去除 PKCS#7 填充
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	n := len(data)
+	if n == 0 {
+		return nil, errors.New("解密数据为空")
+	}
+	pad := int(data[n-1])
+	if pad == 0 || pad > blockSize || pad > n {
+		return nil, errors.New("解密失败，session_key 可能已失效")
+	}
+	if !bytes.Equal(data[n-pad:], bytes.Repeat([]byte{byte(pad)}, pad)) {
+		return nil, errors.New("解密失败，session_key 可能已失效")
+	}
+	return data[:n-pad], nil
+}
@@ -0,0 +1,41 @@
+package wechat
+
+import "fmt"
+
+// Error 微信接口返回的 errcode/errmsg
+type Error struct {
+	Code int
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("wechat: errcode=%d errmsg=%s", e.Code, e.Msg)
+}
+
+// Is 按 errcode 匹配，便于使用 errors.Is(err, wechat.ErrInvalidCode)
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code
+}
+
+// 常见 errcode
+var (
+	ErrSystemBusy         = &Error{Code: -1, Msg: "system busy"}
+	ErrInvalidCredential  = &Error{Code: 40001, Msg: "invalid credential"}
+	ErrInvalidAppID       = &Error{Code: 40013, Msg: "invalid appid"}
+	ErrInvalidCode        = &Error{Code: 40029, Msg: "invalid code"}
+	ErrInvalidSecret      = &Error{Code: 40125, Msg: "invalid appsecret"}
+	ErrCodeUsed           = &Error{Code: 40163, Msg: "code been used"}
+	ErrHighRiskUser       = &Error{Code: 40226, Msg: "high risk user"}
+	ErrAccessTokenExpired = &Error{Code: 42001, Msg: "access_token expired"}
+	ErrRateLimited        = &Error{Code: 45011, Msg: "api minute-quota reach limit"}
+	ErrAPIUnauthorized    = &Error{Code: 48001, Msg: "api unauthorized"}
+)
+
+// newError 根据 errcode 构造错误，errcode=0 返回 nil
+func newError(code int, msg string) error {
+	if code == 0 {
+		return nil
+	}
+	return &Error{Code: code, Msg: msg}
+}
@@ -0,0 +1,61 @@
+package wechat
+
+import (
+	"context"
+	"net/url"
+)
+
+// DefaultOpenBaseURL 公众号网页授权地址
+const DefaultOpenBaseURL = "https://open.weixin.qq.com"
+
+// 网页授权 scope
+const (
+	ScopeBase     = "snsapi_base"     // 静默授权，仅获取 openid
+	ScopeUserInfo = "snsapi_userinfo" // 需用户确认，可获取用户信息
+)
+
+// OAuthToken 公众号网页授权 access_token 结果
+type OAuthToken struct {
+	AccessToken  string `json:"access_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token"`
+	OpenID       string `json:"openid"`
+	Scope        string `json:"scope"`
+	UnionID      string `json:"unionid"`
+}
+
+// AuthorizeURL 构造公众号网页授权跳转地址
+func (c *Client) AuthorizeURL(redirectURI, state, scope string) string {
+	if scope == "" {
+		scope = ScopeBase
+	}
+	query := url.Values{}
+	query.Set("appid", c.appID)
+	query.Set("redirect_uri", redirectURI)
+	query.Set("response_type", "code")
+	query.Set("scope", scope)
+	query.Set("state", state)
+	return c.openBaseURL + "/connect/oauth2/authorize?" + query.Encode() + "#wechat_redirect"
+}
+
+// OAuthAccessToken 用网页授权回调的 code 换取 openid
+func (c *Client) OAuthAccessToken(ctx context.Context, code string) (*OAuthToken, error) {
+	query := url.Values{}
+	query.Set("appid", c.appID)
+	query.Set("secret", c.secret)
+	query.Set("code", code)
+	query.Set("grant_type", "authorization_code")
+
+	var result struct {
+		OAuthToken
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := c.getJSON(ctx, "/sns/oauth2/access_token", query, &result); err != nil {
+		return nil, err
+	}
+	if err := newError(result.ErrCode, result.ErrMsg); err != nil {
+		return nil, err
+	}
+	return &result.OAuthToken, nil
+}
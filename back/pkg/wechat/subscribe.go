@@ -0,0 +1,75 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// 订阅消息相关 errcode
+var (
+	ErrSubscribeRefused  = &Error{Code: 43101, Msg: "user refuse to accept the msg"}
+	ErrTemplateParam     = &Error{Code: 47003, Msg: "argument invalid"}
+	ErrTemplateNotExists = &Error{Code: 40037, Msg: "invalid template_id"}
+)
+
+// SubscribeMessageRequest 发送小程序订阅消息参数
+type SubscribeMessageRequest struct {
+	ToUser           string                       `json:"touser"`
+	TemplateID       string                       `json:"template_id"`
+	Page             string                       `json:"page,omitempty"`              // 点击消息打开的页面
+	MiniprogramState string                       `json:"miniprogram_state,omitempty"` // developer / trial / formal
+	Lang             string                       `json:"lang,omitempty"`
+	Data             map[string]SubscribeDataItem `json:"data"`
+}
+
+// SubscribeDataItem 模板字段值
+type SubscribeDataItem struct {
+	Value string `json:"value"`
+}
+
+// SendSubscribeMessage 调用 message.subscribe.send 发送订阅消息
+// 用户未订阅或已用完次数时返回 ErrSubscribeRefused；access_token 失效时自动刷新重试一次
+func (c *Client) SendSubscribeMessage(ctx context.Context, req SubscribeMessageRequest) error {
+	if req.ToUser == "" || req.TemplateID == "" {
+		return errors.New("wechat: touser 和 template_id 不能为空")
+	}
+
+	err := c.sendSubscribeMessage(ctx, req)
+	if errors.Is(err, ErrAccessTokenExpired) || errors.Is(err, ErrInvalidCredential) {
+		c.InvalidateAccessToken()
+		err = c.sendSubscribeMessage(ctx, req)
+	}
+	return err
+}
+
+func (c *Client) sendSubscribeMessage(ctx context.Context, req SubscribeMessageRequest) error {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("wechat: 序列化请求失败: %w", err)
+	}
+	reqURL := c.baseURL + "/cgi-bin/message/subscribe/send?access_token=" + url.QueryEscape(token)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("wechat: 创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	var result struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := c.do(httpReq, &result); err != nil {
+		return err
+	}
+	return newError(result.ErrCode, result.ErrMsg)
+}
@@ -0,0 +1,358 @@
+// Package wechattest 提供进程内的微信接口假服务，供测试和本地开发使用
+// 启动后将 wechat.Config.BaseURL 指向 Server.URL 即可离线走通登录流程
+package wechattest
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server 微信接口假服务
+// 未登记的 code 会按 code 派生固定 openid，同一 code 只能使用一次
+type Server struct {
+	*httptest.Server
+
+	AppID  string
+	Secret string
+
+	mu           sync.Mutex
+	sessions     map[string]Session // code -> 预设会话
+	usedCodes    map[string]bool
+	accessTokens map[string]time.Time // access_token -> 过期时间
+	messages     []SubscribeMessage   // 已发送的订阅消息
+}
+
+// SubscribeMessage 假服务收到的订阅消息
+type SubscribeMessage struct {
+	ToUser     string
+	TemplateID string
+	Page       string
+	Data       map[string]string
+}
+
+// Session 预设的 jscode2session 结果
+type Session struct {
+	OpenID     string
+	UnionID    string
+	SessionKey string
+}
+
+// NewServer 启动假服务，监听本地随机端口
+func NewServer(appID, secret string) *Server {
+	s := &Server{
+		AppID:        appID,
+		Secret:       secret,
+		sessions:     make(map[string]Session),
+		usedCodes:    make(map[string]bool),
+		accessTokens: make(map[string]time.Time),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/sns/jscode2session", s.handleCode2Session)
+	mux.HandleFunc("/cgi-bin/token", s.handleToken)
+	mux.HandleFunc("/connect/oauth2/authorize", s.handleAuthorize)
+	mux.HandleFunc("/sns/oauth2/access_token", s.handleOAuthAccessToken)
+	mux.HandleFunc("/wxa/getwxacodeunlimit", s.handleUnlimitedCode)
+	mux.HandleFunc("/cgi-bin/message/subscribe/send", s.handleSubscribeSend)
+	s.Server = httptest.NewServer(mux)
+	return s
+}
+
+// AddCode 登记一个 code 对应的会话
+func (s *Server) AddCode(code string, session Session) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[code] = session
+}
+
+// SessionFor 返回 code 对应的会话（未登记时按 code 派生）
+func (s *Server) SessionFor(code string) Session {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sessionFor(code)
+}
+
+func (s *Server) sessionFor(code string) Session {
+	if session, ok := s.sessions[code]; ok {
+		return session
+	}
+	sum := sha256.Sum256([]byte(s.AppID + ":" + code))
+	return Session{
+		OpenID:     "fake-openid-" + hex.EncodeToString(sum[:8]),
+		SessionKey: base64.StdEncoding.EncodeToString(sum[16:32]),
+	}
+}
+
+func (s *Server) handleCode2Session(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !s.checkCredential(w, q.Get("appid"), q.Get("secret")) {
+		return
+	}
+
+	code := q.Get("js_code")
+	if code == "" {
+		writeError(w, 40029, "invalid code")
+		return
+	}
+
+	s.mu.Lock()
+	if s.usedCodes[code] {
+		s.mu.Unlock()
+		writeError(w, 40163, "code been used")
+		return
+	}
+	s.usedCodes[code] = true
+	session := s.sessionFor(code)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"openid":      session.OpenID,
+		"session_key": session.SessionKey,
+		"unionid":     session.UnionID,
+	})
+}
+
+// handleAuthorize 模拟用户同意网页授权，直接带 code 跳回 redirect_uri
+func (s *Server) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("appid") != s.AppID {
+		writeError(w, 40013, "invalid appid")
+		return
+	}
+	redirectURI, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || redirectURI.Scheme == "" {
+		writeError(w, 40029, "invalid redirect_uri")
+		return
+	}
+
+	values := redirectURI.Query()
+	values.Set("code", "oa-"+randomHex(8))
+	values.Set("state", q.Get("state"))
+	redirectURI.RawQuery = values.Encode()
+	http.Redirect(w, r, redirectURI.String(), http.StatusFound)
+}
+
+func (s *Server) handleOAuthAccessToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !s.checkCredential(w, q.Get("appid"), q.Get("secret")) {
+		return
+	}
+
+	code := q.Get("code")
+	if code == "" {
+		writeError(w, 40029, "invalid code")
+		return
+	}
+
+	s.mu.Lock()
+	if s.usedCodes[code] {
+		s.mu.Unlock()
+		writeError(w, 40163, "code been used")
+		return
+	}
+	s.usedCodes[code] = true
+	session := s.sessionFor(code)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"access_token":  randomHex(16),
+		"expires_in":    7200,
+		"refresh_token": randomHex(16),
+		"openid":        session.OpenID,
+		"scope":         q.Get("scope"),
+		"unionid":       session.UnionID,
+	})
+}
+
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if !s.checkCredential(w, q.Get("appid"), q.Get("secret")) {
+		return
+	}
+
+	token := randomHex(16)
+	s.mu.Lock()
+	s.accessTokens[token] = time.Now().Add(2 * time.Hour)
+	s.mu.Unlock()
+
+	writeJSON(w, map[string]interface{}{
+		"access_token": token,
+		"expires_in":   7200,
+	})
+}
+
+// handleUnlimitedCode 返回按 scene 生成的占位 PNG，代替真实小程序码
+func (s *Server) handleUnlimitedCode(w http.ResponseWriter, r *http.Request) {
+	if !s.ValidAccessToken(r.URL.Query().Get("access_token")) {
+		writeError(w, 42001, "access_token expired")
+		return
+	}
+
+	var req struct {
+		Scene string `json:"scene"`
+		Width int    `json:"width"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Scene == "" || len(req.Scene) > 32 {
+		writeError(w, 40169, "invalid scene")
+		return
+	}
+
+	// 16x16 的格子图案，每格由 scene 哈希决定黑白
+	const cells, cell = 16, 12
+	sum := sha256.Sum256([]byte(req.Scene))
+	img := image.NewGray(image.Rect(0, 0, cells*cell, cells*cell))
+	for y := 0; y < cells*cell; y++ {
+		for x := 0; x < cells*cell; x++ {
+			bit := (y/cell)*cells + x/cell
+			if sum[bit/8%len(sum)]>>(bit%8)&1 == 1 {
+				img.SetGray(x, y, color.Gray{Y: 0})
+			} else {
+				img.SetGray(x, y, color.Gray{Y: 255})
+			}
+		}
+	}
+	w.Header().Set("Content-Type", "image/png")
+	_ = png.Encode(w, img)
+}
+
+// handleSubscribeSend 记录订阅消息，openid 以 "refused" 开头时模拟用户未订阅
+func (s *Server) handleSubscribeSend(w http.ResponseWriter, r *http.Request) {
+	if !s.ValidAccessToken(r.URL.Query().Get("access_token")) {
+		writeError(w, 42001, "access_token expired")
+		return
+	}
+
+	var req struct {
+		ToUser     string `json:"touser"`
+		TemplateID string `json:"template_id"`
+		Page       string `json:"page"`
+		Data       map[string]struct {
+			Value string `json:"value"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.ToUser == "" || req.TemplateID == "" {
+		writeError(w, 47003, "argument invalid")
+		return
+	}
+	if strings.HasPrefix(req.ToUser, "refused") {
+		writeError(w, 43101, "user refuse to accept the msg")
+		return
+	}
+
+	msg := SubscribeMessage{ToUser: req.ToUser, TemplateID: req.TemplateID, Page: req.Page, Data: make(map[string]string, len(req.Data))}
+	for k, v := range req.Data {
+		msg.Data[k] = v.Value
+	}
+	s.mu.Lock()
+	s.messages = append(s.messages, msg)
+	s.mu.Unlock()
+	writeJSON(w, map[string]interface{}{"errcode": 0, "errmsg": "ok"})
+}
+
+// Messages 返回已收到的订阅消息
+func (s *Server) Messages() []SubscribeMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SubscribeMessage(nil), s.messages...)
+}
+
+// ValidAccessToken 校验 access_token 是否由本服务签发且未过期
+func (s *Server) ValidAccessToken(token string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.accessTokens[token]
+	return ok && time.Now().Before(expiresAt)
+}
+
+// ExpireAccessTokens 使已签发的 access_token 全部失效
+func (s *Server) ExpireAccessTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accessTokens = make(map[string]time.Time)
+}
+
+func (s *Server) checkCredential(w http.ResponseWriter, appID, secret string) bool {
+	if appID != s.AppID {
+		writeError(w, 40013, "invalid appid")
+		return false
+	}
+	if secret != s.Secret {
+		writeError(w, 40125, "invalid appsecret")
+		return false
+	}
+	return true
+}
+
+// EncryptData 按微信 AES-128-CBC 方案加密数据，用于构造 encryptedData/iv
+func EncryptData(sessionKey string, plaintext []byte) (encryptedData, iv string, err error) {
+	key, err := base64.StdEncoding.DecodeString(sessionKey)
+	if err != nil || len(key) != 16 {
+		return "", "", errors.New("session_key 格式错误")
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+
+	pad := aes.BlockSize - len(plaintext)%aes.BlockSize
+	data := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(pad)}, pad)...)
+
+	ivBytes := make([]byte, aes.BlockSize)
+	if _, err := rand.Read(ivBytes); err != nil {
+		return "", "", err
+	}
+	out := make([]byte, len(data))
+	cipher.NewCBCEncrypter(block, ivBytes).CryptBlocks(out, data)
+
+	return base64.StdEncoding.EncodeToString(out), base64.StdEncoding.EncodeToString(ivBytes), nil
+}
+
+// PhoneData 构造 getPhoneNumber 解密后的明文
+func (s *Server) PhoneData(phone string) []byte {
+	b, _ := json.Marshal(map[string]interface{}{
+		"phoneNumber":     phone,
+		"purePhoneNumber": phone,
+		"countryCode":     "86",
+		"watermark": map[string]interface{}{
+			"appid":     s.AppID,
+			"timestamp": time.Now().Unix(),
+		},
+	})
+	return b
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, map[string]interface{}{
+		"errcode": code,
+		"errmsg":  fmt.Sprintf("%s, rid: fake", msg),
+	})
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
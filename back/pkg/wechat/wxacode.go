@@ -0,0 +1,86 @@
+package wechat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// UnlimitedCodeRequest 生成小程序码（数量不限）参数
+type UnlimitedCodeRequest struct {
+	Scene      string `json:"scene"`                 // 场景值，最长 32 个可见字符
+	Page       string `json:"page,omitempty"`        // 扫码后打开的页面，不填默认主页
+	CheckPath  bool   `json:"check_path"`            // 是否校验页面已发布
+	EnvVersion string `json:"env_version,omitempty"` // release / trial / develop
+	Width      int    `json:"width,omitempty"`       // 二维码宽度（像素）
+}
+
+// GetUnlimitedQRCode 调用 wxacode.getUnlimited 生成小程序码，返回图片内容
+// access_token 失效时自动刷新重试一次
+func (c *Client) GetUnlimitedQRCode(ctx context.Context, req UnlimitedCodeRequest) ([]byte, string, error) {
+	if len(req.Scene) == 0 || len(req.Scene) > 32 {
+		return nil, "", errors.New("wechat: scene 长度须为 1-32")
+	}
+
+	img, contentType, err := c.getUnlimitedQRCode(ctx, req)
+	if errors.Is(err, ErrAccessTokenExpired) || errors.Is(err, ErrInvalidCredential) {
+		c.InvalidateAccessToken()
+		img, contentType, err = c.getUnlimitedQRCode(ctx, req)
+	}
+	return img, contentType, err
+}
+
+func (c *Client) getUnlimitedQRCode(ctx context.Context, req UnlimitedCodeRequest) ([]byte, string, error) {
+	token, err := c.AccessToken(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("wechat: 序列化请求失败: %w", err)
+	}
+	reqURL := c.baseURL + "/wxa/getwxacodeunlimit?access_token=" + url.QueryEscape(token)
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, reqURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("wechat: 创建请求失败: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, "", fmt.Errorf("wechat: 请求失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, "", fmt.Errorf("wechat: 读取响应失败: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("wechat: HTTP %d: %s", resp.StatusCode, string(data))
+	}
+
+	// 成功时返回图片，失败时返回 JSON 错误
+	contentType := resp.Header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "application/json") || strings.HasPrefix(contentType, "text/plain") {
+		var result struct {
+			ErrCode int    `json:"errcode"`
+			ErrMsg  string `json:"errmsg"`
+		}
+		if err := json.Unmarshal(data, &result); err != nil {
+			return nil, "", fmt.Errorf("wechat: 解析响应失败: %w", err)
+		}
+		if err := newError(result.ErrCode, result.ErrMsg); err != nil {
+			return nil, "", err
+		}
+		return nil, "", errors.New("wechat: 未返回小程序码图片")
+	}
+	return data, contentType, nil
+}
@@ -88,6 +88,83 @@ export const loginWithCode = async (phone, code) => {
   return result
 }
 
+/**
+ * 是否在微信内置浏览器中（公众号网页授权仅在微信内可用）
+ */
+export const isWechatBrowser = () => /MicroMessenger/i.test(navigator.userAgent)
+
+/**
+ * 公众号网页授权登录：跳转到后端授权入口，授权完成后带 token 跳回当前页面
+ * @param {string} redirect - 登录完成后跳回的站内路径
+ */
+export const loginWithWechatOA = (redirect = window.location.pathname + window.location.search) => {
+  window.location.href = `${API_BASE}/auth/wechat-oa/authorize?redirect=${encodeURIComponent(redirect)}`
+}
+
+/**
+ * 处理网页授权回跳：后端把 token 放在 URL fragment 中
+ * @returns {{error?: string, bound?: boolean} | null} 非授权回跳时返回 null
+ */
+export const consumeOAuthRedirect = () => {
+  const hash = window.location.hash.slice(1)
+  if (!hash) return null
+
+  const params = new URLSearchParams(hash)
+  if (!params.has('token') && !params.has('error')) return null
+
+  // 清除 fragment，避免 token 残留在地址栏和浏览历史中
+  window.history.replaceState(null, '', window.location.pathname + window.location.search)
+
+  if (params.has('error')) {
+    return { error: params.get('error') }
+  }
+
+  setToken(params.get('token'))
+  if (params.get('username')) setUsername(params.get('username'))
+  return { bound: params.get('bound') === 'true' }
+}
+
+// ========== 小程序扫码登录 ==========
+
+/**
+ * 申请扫码登录票据
+ * @returns {Promise<{ticket: string, pollToken: string, imageUrl: string, expiresIn: number}>}
+ */
+export const requestQRLogin = async () => {
+  const response = await fetch(`${API_BASE}/auth/qr/ticket`, { method: 'POST' })
+  const result = await response.json()
+  if (!response.ok) {
+    throw new Error(result.message || '获取登录二维码失败')
+  }
+  return result
+}
+
+/**
+ * 长轮询扫码结果，状态与 status 不同或等待超时后返回
+ * 状态为 confirmed 时保存 token 和用户名
+ * @param {{ticket: string, pollToken: string}} qr - requestQRLogin 的返回
+ * @param {string} status - 当前已知状态
+ * @param {AbortSignal} signal - 取消轮询
+ * @returns {Promise<{status: string, token?: string, username?: string}>}
+ */
+export const pollQRLogin = async (qr, status = 'pending', signal) => {
+  const params = new URLSearchParams({ ticket: qr.ticket, pollToken: qr.pollToken, status })
+  const response = await fetch(`${API_BASE}/auth/qr/poll?${params}`, { signal })
+  const result = await response.json()
+  if (response.status === 404) {
+    return { status: 'expired' }
+  }
+  if (!response.ok) {
+    throw new Error(result.message || '查询扫码结果失败')
+  }
+
+  if (result.status === 'confirmed' && result.token) {
+    setToken(result.token)
+    if (result.username) setUsername(result.username)
+  }
+  return result
+}
+
 /**
  * 用户登出
  */
@@ -97,6 +174,25 @@ export const logout = () => {
   removeUsername()
 }
 
+/**
+ * 获取客服/运营人员访问本账号的记录（含代查看）
+ * @param {number} limit - 条数
+ * @returns {Promise<Array<{time: string, staff: string, impersonation: boolean, action: string, method: string, path: string}>>}
+ */
+export const getStaffAccessLog = async (limit = 50) => {
+  const response = await authFetch(`${API_BASE}/account/staff-access?limit=${limit}`, {
+    method: 'GET',
+  })
+
+  const result = await response.json()
+
+  if (!response.ok) {
+    throw new Error(result.message || '获取访问记录失败')
+  }
+
+  return result.data || []
+}
+
 // ========== 企业认证相关 API ==========
 
 /**
@@ -76,3 +76,28 @@ export const fetchBountyDetail = async (id) => {
 
   return result.data
 }
+
+/**
+ * 询价单本地标记（关注 / 已读 / 私人备注）
+ * 列表和详情返回的每条数据已带 mark: { watched, read, note }，这里只负责修改
+ */
+const updateInquiryMark = async (id, path, method, body) => {
+  const response = await authFetch(
+    `${API_BASE}/inquiries/${encodeURIComponent(id)}/${path}`,
+    { method, body: body ? JSON.stringify(body) : undefined }
+  )
+  const result = await response.json()
+  if (!response.ok) {
+    throw new Error(result.message || '保存失败')
+  }
+  return result.data
+}
+
+export const watchInquiry = (id, watched) =>
+  updateInquiryMark(id, 'watch', watched ? 'PUT' : 'DELETE')
+
+export const markInquiryRead = (id, read) =>
+  updateInquiryMark(id, 'read', read ? 'PUT' : 'DELETE')
+
+export const saveInquiryNote = (id, note) =>
+  updateInquiryMark(id, 'note', 'PUT', { note })
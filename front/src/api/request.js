@@ -33,7 +33,7 @@ const refreshToken = async () => {
 }
 
 // 强制登出
-const forceLogout = () => {
+export const forceLogout = () => {
   removeToken()
   removePhone()
   removeUsername()
@@ -46,6 +46,7 @@ const transformBountyItem = (item) => {
   const status = item.status
   const sampleType = item.sample_type || item.sampleType
   const expectedDeliveryDate = item.expected_delivery_date || item.expectedDeliveryDate
+  const mark = item.mark || {}
 
   let wovenSpec = item.wovenSpec
   let knittedSpec = item.knittedSpec
@@ -86,7 +87,10 @@ const transformBountyItem = (item) => {
     status,
     wovenSpec,
     knittedSpec,
-    expectedDeliveryDate
+    expectedDeliveryDate,
+    watched: !!mark.watched,
+    read: item.mark ? !!mark.read : true, // 未带标记（预览列表）时不显示未读
+    note: mark.note || ''
   }
 }
 
@@ -0,0 +1,92 @@
+import { ref, onUnmounted } from 'vue'
+import { ElNotification } from 'element-plus'
+import { getToken } from '@/api/token'
+import { forceLogout } from '@/api/request'
+
+const API_BASE = '/api/v1'
+const RECONNECT_DELAY = 5000
+
+// 交给调用方处理的事件类型
+const FORWARDED_EVENTS = ['quote.status', 'inquiry.new', 'stream.reset']
+
+/**
+ * 订阅服务端实时事件（SSE），替代轮询
+ * 系统公告统一弹出通知，会话被作废时自动退出登录；其余事件交给 handlers 处理
+ * @param {Object} handlers - { 'quote.status': fn, 'inquiry.new': fn, 'stream.reset': fn }，参数为事件数据
+ */
+export function useEventStream(handlers = {}) {
+  const connected = ref(false)
+  let source = null
+  let retryTimer = null
+  let lastEventId = ''
+
+  const remember = (e) => {
+    if (e.lastEventId) lastEventId = e.lastEventId
+  }
+
+  const parse = (e) => {
+    try {
+      return JSON.parse(e.data)
+    } catch {
+      return {}
+    }
+  }
+
+  const close = () => {
+    clearTimeout(retryTimer)
+    retryTimer = null
+    if (source) {
+      source.close()
+      source = null
+    }
+    connected.value = false
+  }
+
+  const connect = () => {
+    close()
+    const token = getToken()
+    if (!token) return
+
+    // EventSource 不能设置请求头，token 走查询参数；每次重连都用最新 token 和断点
+    const params = new URLSearchParams({ access_token: token })
+    if (lastEventId) params.set('lastEventId', lastEventId)
+    source = new EventSource(`${API_BASE}/events/stream?${params}`)
+
+    source.onopen = () => {
+      connected.value = true
+    }
+    // 服务端定期断开、连接数超限或 token 过期时都会触发，稍后重连
+    source.onerror = () => {
+      close()
+      retryTimer = setTimeout(connect, RECONNECT_DELAY)
+    }
+
+    FORWARDED_EVENTS.forEach((type) => {
+      source.addEventListener(type, (e) => {
+        remember(e)
+        handlers[type]?.(parse(e))
+      })
+    })
+
+    source.addEventListener('announcement', (e) => {
+      remember(e)
+      const { title, content, level } = parse(e)
+      ElNotification({
+        title: title || '系统公告',
+        message: content,
+        type: level === 'warning' ? 'warning' : 'info',
+        duration: 0
+      })
+    })
+
+    source.addEventListener('session.revoked', () => {
+      close()
+      ElNotification({ title: '登录已失效', message: '您的账号已在后台下线，请重新登录', type: 'warning' })
+      forceLogout()
+    })
+  }
+
+  onUnmounted(close)
+
+  return { connected, connect, close }
+}